@@ -30,6 +30,11 @@ func (bw BidetWriter) Close() error {
 	return nil
 }
 
+// Flushes any data still buffered in the [BidetWriter.Writer].
+func (bw BidetWriter) Flush() error {
+	return bw.FlushFunc()
+}
+
 func (bw BidetWriter) Write(b []byte) (n int, err error) {
 	n, err = bw.Writer.Write(b)
 	flushErr := bw.FlushFunc()
@@ -71,3 +71,23 @@ func TestBidetWriter_DoesntCloseWithStdout(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, len(data), n)
 }
+
+// Ensure that data written directly to the buffered writer is written to the file once flushed
+func TestBidetWriter_Flush(t *testing.T) {
+	data := "TestBidetWriter_Flush"
+
+	testutil.WithTempFile(t, func(filepath string) {
+		file, err := os.OpenFile(filepath, os.O_APPEND|os.O_WRONLY, os.ModeType)
+		require.NoError(t, err)
+		defer file.Close()
+
+		writer := NewBidetWriter(file)
+		_, err = writer.Writer.Write([]byte(data))
+		require.NoError(t, err)
+
+		require.NoError(t, writer.Flush())
+		pos, err := file.Seek(0, io.SeekEnd)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(data)), pos)
+	})
+}
@@ -0,0 +1,77 @@
+// Package capture records the data read from readers along with when it was read, so that it can be replayed later
+// with its original timing. Captures are stored as JSON lines, one [Record] per line.
+package capture
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// A single chunk of captured data.
+type Record struct {
+	// The time since the capture started that the data was read, measured with the monotonic clock
+	Offset time.Duration `json:"offset"`
+	// The ID of the reader that the data was read from
+	ReaderID string `json:"reader"`
+	// The data, which is base64 encoded in the capture
+	Data []byte `json:"data"`
+}
+
+// Records each chunk of data written to it as a [Record] in the capture file.
+type Writer struct {
+	file    *os.File
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+	start   time.Time
+}
+
+// NewWriter creates (or truncates) the capture file at the provided path. The capture starts once this is called.
+func NewWriter(path string) (*Writer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := bufio.NewWriter(file)
+	return &Writer{
+		file:    file,
+		buffer:  buffer,
+		encoder: json.NewEncoder(buffer),
+		start:   time.Now(),
+	}, nil
+}
+
+// [io.Writer.Write], records the data without a reader ID. See [Writer.WriteFrom].
+func (w *Writer) Write(b []byte) (int, error) {
+	return w.WriteFrom("", b)
+}
+
+// Records the data as read from the reader with the provided ID.
+func (w *Writer) WriteFrom(readerID string, b []byte) (int, error) {
+	err := w.encoder.Encode(Record{
+		Offset:   time.Since(w.start),
+		ReaderID: readerID,
+		Data:     b,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(b), w.buffer.Flush()
+}
+
+// Flushes any buffered records to the capture file.
+func (w *Writer) Flush() error {
+	return w.buffer.Flush()
+}
+
+// [io.Closer.Close], flushes and closes the capture file.
+func (w *Writer) Close() error {
+	err := w.buffer.Flush()
+	e := w.file.Close()
+	if err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,146 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Writes a capture file containing the provided records.
+func writeCapture(t *testing.T, records ...Record) string {
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, record := range records {
+		require.NoError(t, encoder.Encode(record))
+	}
+	return path
+}
+
+// Ensure that each write is recorded with its reader ID and an increasing offset.
+func TestWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	writer, err := NewWriter(path)
+	require.NoError(t, err)
+
+	_, err = writer.WriteFrom("first", []byte("hello"))
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	n, err := writer.Write([]byte{0x00, 0xFF})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.NoError(t, writer.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var first, second Record
+	require.NoError(t, json.Unmarshal(lines[0], &first))
+	require.NoError(t, json.Unmarshal(lines[1], &second))
+	require.Equal(t, "first", first.ReaderID)
+	require.Equal(t, []byte("hello"), first.Data)
+	require.Equal(t, "", second.ReaderID)
+	require.Equal(t, []byte{0x00, 0xFF}, second.Data)
+	require.GreaterOrEqual(t, second.Offset-first.Offset, 20*time.Millisecond)
+}
+
+// Ensure that the records are only replayed once they are due, using the speed multiplier.
+func TestReplayReader_Timing(t *testing.T) {
+	path := writeCapture(t,
+		Record{Offset: 0, Data: []byte("first")},
+		Record{Offset: 200 * time.Millisecond, Data: []byte("second")},
+	)
+	reader, err := NewReplayReader(path)
+	require.NoError(t, err)
+	defer reader.Close()
+	reader.Speed = 2
+
+	var output bytes.Buffer
+	n, err := reader.WriteTo(&output)
+	require.NoError(t, err)
+	require.Equal(t, int64(len("first")), n)
+	require.Equal(t, "first", output.String())
+
+	// The second record is due after 100ms at double speed
+	start := time.Now()
+	for output.Len() == len("first") {
+		_, err = reader.WriteTo(&output)
+		require.NoError(t, err)
+	}
+	require.InDelta(t, 100*time.Millisecond, time.Since(start), float64(50*time.Millisecond))
+	require.Equal(t, "firstsecond", output.String())
+	// The offset is the original offset of the record, regardless of the speed
+	require.Equal(t, 200*time.Millisecond, reader.Offset())
+
+	n, err = reader.WriteTo(&output)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+}
+
+// Ensure that a record larger than the read buffer is returned over multiple reads, and only the records of the
+// configured reader are replayed.
+func TestReplayReader_ReadAndFilter(t *testing.T) {
+	path := writeCapture(t,
+		Record{ReaderID: "a", Data: []byte("abcdef")},
+		Record{ReaderID: "b", Data: []byte("skipped")},
+		Record{ReaderID: "a", Data: []byte("gh")},
+	)
+	reader, err := NewReplayReader(path)
+	require.NoError(t, err)
+	defer reader.Close()
+	reader.ReaderID = "a"
+
+	read, err := io.ReadAll(io.LimitReader(reader, 8))
+	require.NoError(t, err)
+	require.Equal(t, "abcdefgh", string(read))
+	require.False(t, reader.Ended())
+
+	b := make([]byte, 4)
+	n, err := reader.Read(b)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 0, n)
+	require.True(t, reader.Ended())
+}
+
+// Ensure that a looping replay starts again once all records are replayed, returning from each write at the end of
+// the capture.
+func TestReplayReader_Loop(t *testing.T) {
+	path := writeCapture(t, Record{Data: []byte("a")}, Record{Data: []byte("b")})
+	reader, err := NewReplayReader(path)
+	require.NoError(t, err)
+	defer reader.Close()
+	reader.Loop = true
+
+	var output bytes.Buffer
+	for range 3 {
+		_, err = reader.WriteTo(&output)
+		require.NoError(t, err)
+	}
+	require.Equal(t, "abababa", output.String()[:7])
+	require.False(t, reader.Ended())
+}
+
+// Ensure that a looping replay with no records to replay does not loop forever.
+func TestReplayReader_LoopWithNoRecords(t *testing.T) {
+	path := writeCapture(t, Record{ReaderID: "a", Data: []byte("a")})
+	reader, err := NewReplayReader(path)
+	require.NoError(t, err)
+	defer reader.Close()
+	reader.Loop = true
+	reader.ReaderID = "b"
+
+	n, err := reader.WriteTo(io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+}
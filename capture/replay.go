@@ -0,0 +1,184 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// The longest a [ReplayReader] waits for the next record to become due before returning [io.EOF]
+const ReplayReadDeadline = 10 * time.Millisecond
+
+// Plays back the records of a capture file, each record becomes available once the same time has passed since the
+// replay started as had passed since the capture started.
+type ReplayReader struct {
+	// Multiplies the replay speed, e.g. 2 replays twice as fast as it was captured. The original speed is used when 0
+	Speed float64
+	// Starts the replay again from the beginning once all records are replayed
+	Loop bool
+	// Only replays the records read from the reader with this ID, all records are replayed when empty
+	ReaderID string
+
+	file    *os.File
+	decoder *json.Decoder
+	start   time.Time
+	next    *Record
+	// The data of the current record that did not fit in the buffer of the last read
+	pending []byte
+	// The offset of the current record
+	offset time.Duration
+	done   bool
+	// The number of times the replay has started again from the beginning
+	loops int
+}
+
+// NewReplayReader opens the capture file at the provided path. The replay starts once this is called.
+func NewReplayReader(path string) (*ReplayReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReplayReader{
+		file:    file,
+		decoder: json.NewDecoder(bufio.NewReader(file)),
+		start:   time.Now(),
+	}, nil
+}
+
+// Loads the next record to be replayed into [ReplayReader.next], starting again from the beginning of the capture if
+// [ReplayReader.Loop] is set.
+func (r *ReplayReader) loadNext() error {
+	rewound := false
+	for r.next == nil && !r.done {
+		var record Record
+		err := r.decoder.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			// A capture without any records to replay is only read once
+			if !r.Loop || rewound {
+				r.done = true
+				return nil
+			}
+			_, err = r.file.Seek(0, io.SeekStart)
+			if err != nil {
+				return err
+			}
+			r.decoder = json.NewDecoder(bufio.NewReader(r.file))
+			r.start = time.Now()
+			r.loops++
+			rewound = true
+			continue
+		}
+		if err != nil {
+			r.done = true
+			return fmt.Errorf("failed to decode capture record with error: [%s]", err.Error())
+		}
+
+		if r.ReaderID == "" || r.ReaderID == record.ReaderID {
+			r.next = &record
+			rewound = false
+		}
+	}
+	return nil
+}
+
+// Returns how long until the next record is due to be replayed.
+func (r *ReplayReader) untilDue() time.Duration {
+	speed := r.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+	due := r.start.Add(time.Duration(float64(r.next.Offset) / speed))
+	return time.Until(due)
+}
+
+// Returns the data of the next record once it is due, waiting up to [ReplayReadDeadline] for it.
+// [io.EOF] is returned when no record is due yet or all records have been replayed.
+func (r *ReplayReader) nextData() ([]byte, error) {
+	err := r.loadNext()
+	if err != nil {
+		return nil, err
+	}
+	if r.next == nil {
+		return nil, io.EOF
+	}
+
+	wait := r.untilDue()
+	if wait > ReplayReadDeadline {
+		time.Sleep(ReplayReadDeadline)
+		return nil, io.EOF
+	}
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+
+	data := r.next.Data
+	r.offset = r.next.Offset
+	r.next = nil
+	return data, nil
+}
+
+// Returns true once all records have been replayed and read, a looping replay never ends.
+func (r *ReplayReader) Ended() bool {
+	return r.done && r.next == nil && len(r.pending) == 0
+}
+
+// Returns the offset of the record that was last read, which is the time since the capture started that its data was
+// originally read. The offsets start again from 0 each time the replay loops.
+func (r *ReplayReader) Offset() time.Duration {
+	return r.offset
+}
+
+// [io.Reader.Read]
+func (r *ReplayReader) Read(b []byte) (int, error) {
+	if len(r.pending) == 0 {
+		data, err := r.nextData()
+		if err != nil {
+			return 0, err
+		}
+		r.pending = data
+	}
+
+	n := copy(b, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// [io.WriterTo.WriteTo], writes each due record with a single write so the boundaries of the captured chunks are kept.
+// This returns once the replay starts again from the beginning, so a looping capture does not write forever.
+func (r *ReplayReader) WriteTo(w io.Writer) (n int64, err error) {
+	loops := r.loops
+	if len(r.pending) > 0 {
+		written, err := w.Write(r.pending)
+		n += int64(written)
+		r.pending = nil
+		if err != nil {
+			return n, err
+		}
+	}
+
+	for {
+		data, err := r.nextData()
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+
+		written, err := w.Write(data)
+		n += int64(written)
+		if err != nil || r.loops != loops {
+			return n, err
+		}
+	}
+}
+
+// [io.Closer.Close]
+func (r *ReplayReader) Close() error {
+	return r.file.Close()
+}
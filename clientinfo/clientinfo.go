@@ -0,0 +1,91 @@
+package clientinfo
+
+import (
+	"bytes"
+	"io"
+	"text/template"
+	"time"
+)
+
+// Info describes a single client connection accepted by a listening reader, e.g. a TCP or IPC reader.
+type Info struct {
+	// An incrementing number identifying the client within its reader, starting at 1
+	ID int
+	// The remote address of the client, this may be empty for IPC and unix socket clients
+	RemoteAddr string
+	// The time the client connection was accepted
+	AcceptTime time.Time
+}
+
+// Writer is implemented by writers that want to know which client the data written to them was received from.
+// Readers that accept multiple clients will call [Writer.WriteClient] instead of [io.Writer.Write] when
+// their destination implements this.
+type Writer interface {
+	io.Writer
+	// WriteClient writes data that was received from the provided client
+	WriteClient(info Info, b []byte) (int, error)
+	// CloseClient is called once the client has disconnected and no more data will be received from it
+	CloseClient(info Info) error
+}
+
+// Write the provided client data to the [io.Writer], using [Writer.WriteClient] if it is supported.
+func Write(w io.Writer, info Info, b []byte) (int, error) {
+	if cw, ok := w.(Writer); ok {
+		return cw.WriteClient(info, b)
+	}
+	return w.Write(b)
+}
+
+// Notify the [io.Writer] that the provided clients have disconnected, if it is a [Writer].
+// Only the first occurring error will be returned.
+func Close(w io.Writer, infos []Info) error {
+	cw, ok := w.(Writer)
+	if !ok {
+		return nil
+	}
+
+	var err error
+	for _, info := range infos {
+		e := cw.CloseClient(info)
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// HeaderWriter prefixes each chunk of client data with a header rendered from the [template.Template] for the
+// client the data came from, e.g. "[{{.RemoteAddr}}] ".
+type HeaderWriter struct {
+	Writer io.Writer
+	Header *template.Template
+}
+
+// [io.Writer.Write], data with no client information is written without a header.
+func (w HeaderWriter) Write(b []byte) (int, error) {
+	return w.Writer.Write(b)
+}
+
+// [Writer.WriteClient], the header and data are written in a single write, with the client if the writer supports
+// it.
+func (w HeaderWriter) WriteClient(info Info, b []byte) (int, error) {
+	var buf bytes.Buffer
+	err := w.Header.Execute(&buf, info)
+	if err != nil {
+		return 0, err
+	}
+	buf.Write(b)
+
+	n, err := Write(w.Writer, info, buf.Bytes())
+	// Only report the bytes of the provided data that were written
+	n -= buf.Len() - len(b)
+	if n < 0 {
+		n = 0
+	}
+	return n, err
+}
+
+// [Writer.CloseClient]
+func (w HeaderWriter) CloseClient(info Info) error {
+	return Close(w.Writer, []Info{info})
+}
@@ -0,0 +1,91 @@
+package clientinfo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that the header is rendered for the client and written in the same Write call as the data.
+func TestHeaderWriter_WriteClient(t *testing.T) {
+	var buf bytes.Buffer
+	w := HeaderWriter{Writer: &buf, Header: template.Must(template.New("").Parse("[{{.ID}} {{.RemoteAddr}}] "))}
+
+	content := "TestHeaderWriter_WriteClient"
+	n, err := Write(w, Info{ID: 3, RemoteAddr: "127.0.0.1:5000"}, []byte(content))
+	require.NoError(t, err)
+	require.Equal(t, len(content), n)
+	require.Equal(t, "[3 127.0.0.1:5000] "+content, buf.String())
+
+	buf.Reset()
+	n, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.Equal(t, len(content), n)
+	require.Equal(t, content, buf.String())
+}
+
+// Ensure that the data of each client is written to its own file.
+func TestFileWriter_WriteClient(t *testing.T) {
+	dir := t.TempDir()
+	w := NewFileWriter(template.Must(template.New("").Parse(filepath.Join(dir, "client-{{.ID}}.log"))), os.O_CREATE|os.O_WRONLY|os.O_APPEND)
+	defer w.Close()
+
+	client1 := Info{ID: 1, AcceptTime: time.Now()}
+	client2 := Info{ID: 2, AcceptTime: time.Now()}
+	for _, data := range []struct {
+		info    Info
+		content string
+	}{{client1, "first "}, {client2, "second"}, {client1, "again"}} {
+		n, err := Write(w, data.info, []byte(data.content))
+		require.NoError(t, err)
+		require.Equal(t, len(data.content), n)
+	}
+	require.NoError(t, Close(w, []Info{client1}))
+	require.Len(t, w.files, 1)
+
+	b, err := os.ReadFile(filepath.Join(dir, "client-1.log"))
+	require.NoError(t, err)
+	require.Equal(t, "first again", string(b))
+	b, err = os.ReadFile(filepath.Join(dir, "client-2.log"))
+	require.NoError(t, err)
+	require.Equal(t, "second", string(b))
+}
+
+// Ensure that the clients of different readers with the same ID are written to their own files, and closing the client
+// of one reader does not close the other's.
+func TestFileWriter_WriteClientFrom(t *testing.T) {
+	dir := t.TempDir()
+	w := NewFileWriter(template.Must(template.New("").Parse(filepath.Join(dir, "client-{{.RemoteAddr}}.log"))), os.O_CREATE|os.O_WRONLY|os.O_APPEND)
+	defer w.Close()
+
+	tcp := Info{ID: 1, RemoteAddr: "tcp"}
+	ipc := Info{ID: 1, RemoteAddr: "ipc"}
+	_, err := w.WriteClientFrom("tcp-reader", tcp, []byte("first "))
+	require.NoError(t, err)
+	_, err = w.WriteClientFrom("ipc-reader", ipc, []byte("other"))
+	require.NoError(t, err)
+	require.Len(t, w.files, 2)
+
+	require.NoError(t, w.CloseClientFrom("ipc-reader", ipc))
+	_, err = w.WriteClientFrom("tcp-reader", tcp, []byte("again"))
+	require.NoError(t, err)
+	require.Len(t, w.files, 1)
+
+	b, err := os.ReadFile(filepath.Join(dir, "client-tcp.log"))
+	require.NoError(t, err)
+	require.Equal(t, "first again", string(b))
+	b, err = os.ReadFile(filepath.Join(dir, "client-ipc.log"))
+	require.NoError(t, err)
+	require.Equal(t, "other", string(b))
+}
+
+// Ensure that closing clients of a writer that does not implement Writer is a no-op.
+func TestClose_NotClientWriter(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Close(&buf, []Info{{ID: 1}}))
+}
@@ -0,0 +1,104 @@
+package clientinfo
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"text/template"
+)
+
+// FileWriter writes the data of each client to its own file, the path of which is rendered from the
+// [template.Template] for the client, e.g. "client-{{.ID}}-{{.AcceptTime.Unix}}.log".
+type FileWriter struct {
+	path  *template.Template
+	flags int
+	files map[fileKey]*os.File
+}
+
+// Identifies the file of a client. Client IDs start from 1 in each reader, so the ID of the reader that accepted the
+// client is part of the key. The data without a client is written to a single file whichever reader it is from.
+type fileKey struct {
+	readerID string
+	clientID int
+}
+
+// NewFileWriter creates a [FileWriter], the provided flags are used to open each file, see [os.OpenFile].
+func NewFileWriter(path *template.Template, flags int) *FileWriter {
+	return &FileWriter{
+		path:  path,
+		flags: flags,
+		files: make(map[fileKey]*os.File),
+	}
+}
+
+// Returns the key of the file of the client of the reader.
+func newFileKey(readerID string, info Info) fileKey {
+	if info.ID == 0 {
+		return fileKey{}
+	}
+	return fileKey{readerID: readerID, clientID: info.ID}
+}
+
+// [io.Writer.Write], data with no client information is written to the path rendered with an empty [Info].
+func (w *FileWriter) Write(b []byte) (int, error) {
+	return w.WriteClientFrom("", Info{}, b)
+}
+
+// Writes the data read from the reader with the provided ID, which has no client information. See [FileWriter.Write].
+func (w *FileWriter) WriteFrom(readerID string, b []byte) (int, error) {
+	return w.WriteClientFrom(readerID, Info{}, b)
+}
+
+// [Writer.WriteClient], see [FileWriter.WriteClientFrom].
+func (w *FileWriter) WriteClient(info Info, b []byte) (int, error) {
+	return w.WriteClientFrom("", info, b)
+}
+
+// Writes the data of the client of the reader with the provided ID, the client's file is created on its first write.
+func (w *FileWriter) WriteClientFrom(readerID string, info Info, b []byte) (int, error) {
+	key := newFileKey(readerID, info)
+	file, exists := w.files[key]
+	if !exists {
+		var buf bytes.Buffer
+		err := w.path.Execute(&buf, info)
+		if err != nil {
+			return 0, err
+		}
+
+		file, err = os.OpenFile(buf.String(), w.flags, 0666)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open file [%s] for client [%d] with error: [%s]", buf.String(), info.ID, err.Error())
+		}
+		w.files[key] = file
+	}
+	return file.Write(b)
+}
+
+// [Writer.CloseClient], see [FileWriter.CloseClientFrom].
+func (w *FileWriter) CloseClient(info Info) error {
+	return w.CloseClientFrom("", info)
+}
+
+// Closes the file of the client of the reader with the provided ID.
+func (w *FileWriter) CloseClientFrom(readerID string, info Info) error {
+	key := newFileKey(readerID, info)
+	file, exists := w.files[key]
+	if !exists {
+		return nil
+	}
+	delete(w.files, key)
+	return file.Close()
+}
+
+// [io.Closer.Close], closes all open files. Only the first occurring error will be returned.
+func (w *FileWriter) Close() error {
+	var err error
+	for key, file := range w.files {
+		e := file.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+		delete(w.files, key)
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+// Package codec provides streaming compression and decompression of the data flowing through a connection.
+package codec
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// The supported compression algorithms
+const (
+	Gzip   = "gzip"
+	Zstd   = "zstd"
+	Snappy = "snappy"
+	LZ4    = "lz4"
+)
+
+// A compressor that can flush the data it has buffered, so the output can be decompressed without waiting for more
+// data.
+type Encoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// Returns an error if the algorithm is not supported.
+func Validate(algorithm string) error {
+	switch strings.ToLower(algorithm) {
+	case Gzip, Zstd, Snappy, LZ4:
+		return nil
+	default:
+		return fmt.Errorf("unsupported compression algorithm [%s], expected one of [%s, %s, %s, %s]", algorithm, Gzip, Zstd, Snappy, LZ4)
+	}
+}
+
+// NewEncoder returns a compressor for the algorithm that writes the compressed data to the provided writer. Closing
+// the compressor writes the end of the compressed stream, but does not close the provided writer.
+func NewEncoder(algorithm string, w io.Writer) (Encoder, error) {
+	switch strings.ToLower(algorithm) {
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		return zstd.NewWriter(w)
+	case Snappy:
+		return s2.NewWriter(w, s2.WriterSnappyCompat(), s2.WriterConcurrency(1)), nil
+	case LZ4:
+		return lz4.NewWriter(w), nil
+	default:
+		return nil, Validate(algorithm)
+	}
+}
+
+// NewDecoder returns a decompressor for the algorithm that reads the compressed data from the provided reader.
+func NewDecoder(algorithm string, r io.Reader) (io.Reader, error) {
+	switch strings.ToLower(algorithm) {
+	case Gzip:
+		reader, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		// Each stream is decompressed by its own reader
+		reader.Multistream(false)
+		return reader, nil
+	case Zstd:
+		// The decoder is not closed, so it must not use any background goroutines
+		return zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true))
+	case Snappy:
+		return s2.NewReader(r), nil
+	case LZ4:
+		return lz4.NewReader(r), nil
+	default:
+		return nil, Validate(algorithm)
+	}
+}
@@ -0,0 +1,126 @@
+package codec
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var algorithms = []string{Gzip, Zstd, Snappy, LZ4}
+
+// Ensure that the data flushed by the encoder can be decompressed as it is written, before the stream is closed.
+func TestEncodeAndDecodeWriter_Streaming(t *testing.T) {
+	for _, algorithm := range algorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			var compressed, decompressed bytes.Buffer
+			encoder, err := NewEncodeWriter(algorithm, &compressed)
+			require.NoError(t, err)
+			decoder, err := NewDecodeWriter(algorithm, &decompressed)
+			require.NoError(t, err)
+
+			for _, chunk := range []string{"first chunk ", strings.Repeat("second chunk ", 1000)} {
+				_, err = encoder.Write([]byte(chunk))
+				require.NoError(t, err)
+				require.NoError(t, encoder.Flush())
+				// Nothing is written when flushing without any new data
+				length := compressed.Len()
+				require.NoError(t, encoder.Flush())
+				require.Equal(t, length, compressed.Len())
+
+				n, err := decoder.Write(compressed.Bytes())
+				require.NoError(t, err)
+				require.Equal(t, compressed.Len(), n)
+				compressed.Reset()
+				require.Equal(t, chunk, decompressed.String())
+				decompressed.Reset()
+			}
+
+			require.NoError(t, encoder.Close())
+			require.NoError(t, encoder.Close())
+			_, err = decoder.Write(compressed.Bytes())
+			require.NoError(t, err)
+			require.NoError(t, decoder.Close())
+			require.NoError(t, decoder.Close())
+			require.Equal(t, 0, decompressed.Len())
+		})
+	}
+}
+
+// Ensure that the compressed data can be written one byte at a time.
+func TestDecodeWriter_SplitWrites(t *testing.T) {
+	content := strings.Repeat("TestDecodeWriter_SplitWrites", 100)
+	for _, algorithm := range algorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			var compressed, decompressed bytes.Buffer
+			encoder, err := NewEncoder(algorithm, &compressed)
+			require.NoError(t, err)
+			_, err = encoder.Write([]byte(content))
+			require.NoError(t, err)
+			require.NoError(t, encoder.Close())
+
+			decoder, err := NewDecodeWriter(algorithm, &decompressed)
+			require.NoError(t, err)
+			for _, b := range compressed.Bytes() {
+				_, err = decoder.Write([]byte{b})
+				require.NoError(t, err)
+			}
+			require.NoError(t, decoder.Close())
+			require.Equal(t, content, decompressed.String())
+		})
+	}
+}
+
+// Ensure that the streams following the end of a compressed stream are decompressed, even when written together.
+func TestDecodeWriter_ConcatenatedStreams(t *testing.T) {
+	for _, algorithm := range algorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			var compressed, decompressed bytes.Buffer
+			for _, content := range []string{"first stream ", "second stream"} {
+				encoder, err := NewEncoder(algorithm, &compressed)
+				require.NoError(t, err)
+				_, err = encoder.Write([]byte(content))
+				require.NoError(t, err)
+				require.NoError(t, encoder.Close())
+			}
+
+			decoder, err := NewDecodeWriter(algorithm, &decompressed)
+			require.NoError(t, err)
+			_, err = decoder.Write(compressed.Bytes())
+			require.NoError(t, err)
+			require.NoError(t, decoder.Close())
+			require.Equal(t, "first stream second stream", decompressed.String())
+		})
+	}
+}
+
+// Ensure that invalid data returns an error from the write and the close.
+func TestDecodeWriter_InvalidData(t *testing.T) {
+	decoder, err := NewDecodeWriter(Gzip, io.Discard)
+	require.NoError(t, err)
+	_, err = decoder.Write([]byte("this is not compressed"))
+	require.Error(t, err)
+	_, err = decoder.Write([]byte("more"))
+	require.Error(t, err)
+	require.Error(t, decoder.Close())
+}
+
+// Ensure that a decoder with no data written to it can be closed.
+func TestDecodeWriter_CloseWithoutData(t *testing.T) {
+	decoder, err := NewDecodeWriter(Zstd, io.Discard)
+	require.NoError(t, err)
+	require.NoError(t, decoder.Close())
+}
+
+func TestValidate(t *testing.T) {
+	for _, algorithm := range append(algorithms, "GZIP") {
+		require.NoError(t, Validate(algorithm))
+	}
+	require.Error(t, Validate("brotli"))
+	_, err := NewEncodeWriter("brotli", io.Discard)
+	require.Error(t, err)
+	_, err = NewDecodeWriter("brotli", io.Discard)
+	require.Error(t, err)
+}
@@ -0,0 +1,228 @@
+package codec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Compresses the data written to it and writes the compressed data to [EncodeWriter.Writer]. The compressor is only
+// flushed by [EncodeWriter.Flush], so that the data is compressed in larger blocks while it is flowing.
+type EncodeWriter struct {
+	Writer  io.Writer
+	encoder Encoder
+	// Whether data has been written since the last flush
+	dirty  bool
+	closed bool
+}
+
+// NewEncodeWriter returns a writer that compresses the data written to it with the algorithm.
+func NewEncodeWriter(algorithm string, w io.Writer) (*EncodeWriter, error) {
+	encoder, err := NewEncoder(algorithm, w)
+	if err != nil {
+		return nil, err
+	}
+	return &EncodeWriter{Writer: w, encoder: encoder}, nil
+}
+
+// [io.Writer.Write]
+func (w *EncodeWriter) Write(b []byte) (int, error) {
+	w.dirty = true
+	return w.encoder.Write(b)
+}
+
+// Writes the compressed data of everything written so far, this does nothing if nothing has been written since the
+// last flush.
+func (w *EncodeWriter) Flush() error {
+	if !w.dirty {
+		return nil
+	}
+	w.dirty = false
+	return w.encoder.Flush()
+}
+
+// [io.Closer.Close], writes the end of the compressed stream. [EncodeWriter.Writer] is not closed.
+func (w *EncodeWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.encoder.Close()
+}
+
+// Decompresses the data written to it and writes the decompressed data to [DecodeWriter.Writer]. Each write returns
+// once all the data that can be decompressed from it has been written, the rest is decompressed once more data is
+// written. Once a compressed stream ends, any following data is decompressed as a new stream.
+type DecodeWriter struct {
+	Writer    io.Writer
+	algorithm string
+	// The compressed data sent to the decompressor
+	input chan []byte
+	// Signalled once the decompressor is waiting for more data
+	ready   chan struct{}
+	waiting bool
+	// Closed once the decompressor fails or [DecodeWriter.Close] is called
+	done   chan struct{}
+	err    error
+	closed bool
+	output bytes.Buffer
+}
+
+// NewDecodeWriter returns a writer that decompresses the data written to it with the algorithm.
+func NewDecodeWriter(algorithm string, w io.Writer) (*DecodeWriter, error) {
+	err := Validate(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &DecodeWriter{
+		Writer:    w,
+		algorithm: algorithm,
+		input:     make(chan []byte),
+		ready:     make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go d.decode()
+	return d, nil
+}
+
+// Decompresses the input into the output until the input is closed or the data cannot be decompressed. The output is
+// only accessed while [DecodeWriter.Write] is waiting for the decompressor to be ready.
+func (d *DecodeWriter) decode() {
+	defer close(d.done)
+
+	source := &feedReader{input: d.input, ready: d.ready}
+	for {
+		decoder, err := NewDecoder(d.algorithm, source)
+		if err == nil {
+			err = d.decodeStream(decoder)
+		}
+		if source.closed {
+			return
+		}
+		if err != nil {
+			d.err = fmt.Errorf("failed to decompress [%s] data with error: [%s]", d.algorithm, err.Error())
+			return
+		}
+	}
+}
+
+// Reads the decompressed data into the output until the end of the compressed stream. This does not use
+// [bytes.Buffer.ReadFrom], since the output is reset while the decompressor is waiting for more data. Decompressors
+// that implement [io.WriterTo] are preferred, since some only return from a read once its buffer is full.
+func (d *DecodeWriter) decodeStream(decoder io.Reader) error {
+	if writerTo, ok := decoder.(io.WriterTo); ok {
+		_, err := writerTo.WriteTo(&d.output)
+		return err
+	}
+
+	buffer := make([]byte, 32*1024)
+	for {
+		n, err := decoder.Read(buffer)
+		d.output.Write(buffer[:n])
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Waits for the decompressor to be ready for more data, returning false if it has stopped.
+func (d *DecodeWriter) waitReady() bool {
+	if d.waiting {
+		return true
+	}
+	select {
+	case <-d.ready:
+		d.waiting = true
+		return true
+	case <-d.done:
+		return false
+	}
+}
+
+// [io.Writer.Write]
+func (d *DecodeWriter) Write(b []byte) (int, error) {
+	if !d.waitReady() {
+		return 0, d.err
+	}
+	d.waiting = false
+	d.input <- append([]byte(nil), b...)
+
+	if !d.waitReady() && d.err != nil {
+		return 0, d.err
+	}
+	err := d.writeOutput()
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Writes the decompressed data to [DecodeWriter.Writer].
+func (d *DecodeWriter) writeOutput() error {
+	if d.output.Len() == 0 {
+		return nil
+	}
+	_, err := d.Writer.Write(d.output.Bytes())
+	d.output.Reset()
+	return err
+}
+
+// The decompressed data is always written by [DecodeWriter.Write], so there is nothing to flush.
+func (d *DecodeWriter) Flush() error {
+	return nil
+}
+
+// [io.Closer.Close], stops the decompressor and writes any data it was still holding. [DecodeWriter.Writer] is not
+// closed.
+func (d *DecodeWriter) Close() error {
+	if !d.closed && d.waitReady() {
+		d.closed = true
+		close(d.input)
+		<-d.done
+		err := d.writeOutput()
+		if err != nil && d.err == nil {
+			d.err = err
+		}
+	}
+	return d.err
+}
+
+// Provides the data written to a [DecodeWriter] to its decompressor, signalling that it is ready before it waits for
+// more data.
+type feedReader struct {
+	input   chan []byte
+	ready   chan struct{}
+	pending []byte
+	closed  bool
+}
+
+// [io.ByteReader.ReadByte], which stops the gzip decompressor from reading past the end of its stream.
+func (f *feedReader) ReadByte() (byte, error) {
+	var b [1]byte
+	_, err := f.Read(b[:])
+	return b[0], err
+}
+
+// [io.Reader.Read]
+func (f *feedReader) Read(b []byte) (int, error) {
+	for len(f.pending) == 0 {
+		if f.closed {
+			return 0, io.EOF
+		}
+		f.ready <- struct{}{}
+		data, ok := <-f.input
+		if !ok {
+			f.closed = true
+			return 0, io.EOF
+		}
+		f.pending = data
+	}
+
+	n := copy(b, f.pending)
+	f.pending = f.pending[n:]
+	return n, nil
+}
@@ -3,7 +3,6 @@ package config
 import (
 	"context"
 	"fmt"
-	"io"
 	"os"
 	"os/signal"
 	"syscall"
@@ -12,28 +11,64 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// Entry point to read in the provided file, resolve the connections, readers and writers and apply the configuration
-func ApplyConfigurationFromFile(filepath string) {
+// Entry point to read in the provided file, resolve the connections, readers and writers and apply the configuration.
+// An [ExitError] describing why the flow failed is returned, see [ExitCode] for the process exit code.
+func ApplyConfigurationFromFile(filepath string) error {
 	config, err := readConfig(filepath)
 	if err != nil {
-		fmt.Printf("Failed to apply configuration from filepath [%s]. Err: [%s].\n", filepath, err.Error())
-		return
+		return newExitError(ExitCodeConfigError, fmt.Errorf("failed to apply configuration from filepath [%s] with error: [%s]", filepath, err.Error()))
 	}
 
 	err = config.Initialise()
 	if err != nil {
-		fmt.Println(err.Error())
-		return
+		config.Close()
+		return err
 	}
-	defer config.Close()
 
-	signalCtx, signalStopFunc := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
-	defer signalStopFunc()
-	ctx, cancelFunc := context.WithCancel(signalCtx)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(signals)
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
 
 	// TODO: If we have stdin configured, we need to start another go routine that is grabbing content from stdin
-	go applyConfig(ctx, cancelFunc, config.Conns, config.Settings)
-	<-ctx.Done()
+	result := make(chan error, 1)
+	go func() {
+		result <- applyConfig(ctx, cancelFunc, config.Conns, config.Settings)
+	}()
+
+	select {
+	case sig := <-signals:
+		fmt.Printf("Received signal [%s], stopping. Send the signal again to exit immediately.\n", sig)
+		cancelFunc()
+		select {
+		case <-result:
+		case sig := <-signals:
+			// The nodes are left open, since the process is exiting
+			fmt.Printf("Received signal [%s] while stopping, exiting immediately.\n", sig)
+			return newSignalExitError(sig)
+		}
+		err = newSignalExitError(sig)
+	case err = <-result:
+	}
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- config.Shutdown(config.Settings.drainTimeout())
+	}()
+
+	select {
+	case shutdownErr := <-shutdown:
+		// Failing to write the remaining data or close a writer is only reported if the flow otherwise succeeded
+		if shutdownErr != nil && err == nil {
+			return newExitError(ExitCodeIOFailure, fmt.Errorf("failed to shut down the flow with error: [%s]", shutdownErr.Error()))
+		}
+		return err
+	case sig := <-signals:
+		// The nodes are left open, since the process is exiting
+		fmt.Printf("Received signal [%s] while shutting down, exiting immediately.\n", sig)
+		return newSignalExitError(sig)
+	}
 }
 
 // Read and return a Config from the provided filepath
@@ -49,33 +84,124 @@ func readConfig(filePath string) (Config, error) {
 	return config, err
 }
 
-func applyConfig(ctx context.Context, cancelFunc context.CancelFunc, connections []Connection, settings ConfigSettings) {
+// Copies data from each connection's reader to its writer until the context is done, the idle timeout or maximum
+// runtime is reached, an exit condition is met or every script has finished. An [ExitError] is returned if the flow did not end successfully.
+func applyConfig(ctx context.Context, cancelFunc context.CancelFunc, connections []Connection, settings ConfigSettings) error {
 	// TODO: This needs to be smarter and understand the "flow" of information and call the correct reader and writers in the correct order
+	runStartTime := time.Now()
 	startTime := time.Now()
+	backoff := pollBackoff{max: settings.maxPollInterval()}
+	var copyErr error
 	for {
+		idle := true
+		scripts, finishedScripts := 0, 0
 		for _, connection := range connections {
-			written, err := io.Copy(connection.Writer, connection.Reader)
+			written, read, err := connection.copy()
 			if err != nil {
 				// TODO: Add a debug flag to enable this
 				fmt.Printf("Error occurred when copying content from reader [%s] to writer(s) [%s]. Error: [%s]\n", connection.ReaderId, connection.WriterIds, err.Error())
+				copyErr = fmt.Errorf("failed to copy content from reader [%s] to writer(s) [%s] with error: [%s]", connection.ReaderId, connection.WriterIds, err.Error())
 			}
 
 			if written > 0 {
 				fmt.Printf("Wrote [%d] bytes from reader [%s] to writer(s) [%s].\n", written, connection.ReaderId, connection.WriterIds)
 				startTime = time.Now()
+				idle = false
 			}
+			// Compressors hold back data until they are flushed, which is done once the reader has no more data
+			if written == 0 {
+				err = connection.flush()
+				if err != nil {
+					fmt.Printf("Error occurred when flushing compressed content from reader [%s] to writer(s) [%s]. Error: [%s]\n", connection.ReaderId, connection.WriterIds, err.Error())
+					copyErr = fmt.Errorf("failed to flush compressed content from reader [%s] to writer(s) [%s] with error: [%s]", connection.ReaderId, connection.WriterIds, err.Error())
+				}
+			}
+
+			_, pending, err := connection.release()
+			if err != nil {
+				fmt.Printf("Error occurred when writing queued content from reader [%s] to writer(s) [%s]. Error: [%s]\n", connection.ReaderId, connection.WriterIds, err.Error())
+				copyErr = fmt.Errorf("failed to write queued content from reader [%s] to writer(s) [%s] with error: [%s]", connection.ReaderId, connection.WriterIds, err.Error())
+			}
+			// The flow does not time out while data is still queued to be written
+			if pending {
+				startTime = time.Now()
+			}
+
+			for _, condition := range connection.exitConditions {
+				// The reader was not read from, so nothing is known about whether it has more data
+				if !read {
+					break
+				}
+				condition.polled(connection.Reader, written)
+				if condition.met {
+					fmt.Printf("Exit condition met, %s. Exiting with code [%d].\n", condition.condition, condition.condition.ExitCode)
+					cancelFunc()
+					if condition.condition.ExitCode == ExitCodeSuccess {
+						return withCopyError(nil, copyErr)
+					}
+					return withCopyError(newExitError(condition.condition.ExitCode, fmt.Errorf("exit condition met, %s", condition.condition)), copyErr)
+				}
+			}
+
+			if script, ok := connection.Reader.(finisher); ok {
+				scripts++
+				finished, err := script.Finished()
+				if finished && err != nil {
+					fmt.Printf("Script [%s] failed. Exiting with code [%d]. Error: [%s].\n", connection.ReaderId, ExitCodeScriptFailure, err.Error())
+					cancelFunc()
+					return newExitError(ExitCodeScriptFailure, fmt.Errorf("script [%s] failed with error: [%s]", connection.ReaderId, err.Error()))
+				}
+				if finished {
+					finishedScripts++
+				} else {
+					// The flow does not time out while a script is waiting for a reply
+					startTime = time.Now()
+				}
+			}
+		}
+
+		if scripts > 0 && finishedScripts == scripts {
+			fmt.Printf("All [%d] script(s) passed, exiting.\n", scripts)
+			cancelFunc()
+			return withCopyError(nil, copyErr)
+		}
+
+		// Only wait between polls while no data is flowing
+		wait := time.Duration(0)
+		if idle {
+			wait = backoff.next()
+		} else {
+			backoff.reset()
 		}
 
 		select {
 		// This will only be detected if a OS signal is received
 		case <-ctx.Done():
-			return
-		default:
-			timeDifference := time.Since(startTime)
-			if settings.Timeout > 0 && timeDifference.Seconds() >= float64(settings.Timeout) {
-				cancelFunc()
-				return
+			return nil
+		case <-time.After(wait):
+		}
+
+		timeDifference := time.Since(startTime)
+		if settings.Timeout > 0 && timeDifference.Seconds() >= float64(settings.Timeout) {
+			cancelFunc()
+			if len(settings.ExitConditions) > 0 {
+				return withCopyError(newExitError(ExitCodeIdleTimeout, fmt.Errorf("idle timeout of [%d] seconds reached before any exit condition was met", settings.Timeout)), copyErr)
 			}
+			return withCopyError(nil, copyErr)
+		}
+
+		if settings.MaxRuntime > 0 && time.Since(runStartTime).Seconds() >= float64(settings.MaxRuntime) {
+			cancelFunc()
+			return withCopyError(newExitError(ExitCodeMaxRuntime, fmt.Errorf("maximum runtime of [%d] seconds reached", settings.MaxRuntime)), copyErr)
 		}
 	}
 }
+
+// Returns the provided result of the flow, unless copying data failed at least once during the flow. An
+// [ExitCodeIOFailure] error is then returned instead, so the failure is not hidden by the reason the flow ended.
+func withCopyError(result error, copyErr error) error {
+	if copyErr != nil {
+		return newExitError(ExitCodeIOFailure, copyErr)
+	}
+	return result
+}
@@ -0,0 +1,44 @@
+//go:build unix
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that the flow shuts down once a signal is received and returns the exit code of the signal.
+func TestApplyConfigurationFromFile_Signal(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	output := filepath.Join(dir, "output.txt")
+	require.NoError(t, os.WriteFile(input, []byte("TestApplyConfigurationFromFile_Signal"), 0666))
+
+	configPath := filepath.Join(dir, "config.yaml")
+	config := Config{
+		Connections: []ConfigConnection{{ReaderID: "in", WriterID: "out"}},
+		Nodes: ConfigNodes{
+			Files: []ConfigFile{
+				{ID: "in", Path: input},
+				{ID: "out", Path: output},
+			},
+		},
+	}
+	require.NoError(t, config.writeConfig(configPath))
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		syscall.Kill(os.Getpid(), syscall.SIGINT)
+	}()
+	err := ApplyConfigurationFromFile(configPath)
+	require.Equal(t, ExitCodeSignal+int(syscall.SIGINT), ExitCode(err))
+
+	read, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "TestApplyConfigurationFromFile_Signal", string(read))
+}
@@ -39,7 +39,8 @@ func TestApplyConfigurationFromFile_invalidFile(t *testing.T) {
 	_, err := os.Stat(filepath)
 	require.Error(t, err)
 
-	ApplyConfigurationFromFile(filepath)
+	err = ApplyConfigurationFromFile(filepath)
+	require.Equal(t, ExitCodeConfigError, ExitCode(err))
 }
 
 func TestApplyConfigurationFromFile(t *testing.T) {
@@ -52,7 +53,7 @@ func TestApplyConfigurationFromFile(t *testing.T) {
 	defer os.Remove(inputFile)
 	defer os.Remove(outputFile)
 
-	ApplyConfigurationFromFile(filepath)
+	require.NoError(t, ApplyConfigurationFromFile(filepath))
 
 	read, err := os.ReadFile(outputFile)
 	require.NoError(t, err)
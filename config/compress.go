@@ -0,0 +1,258 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/Kilemonn/flow/clientinfo"
+	"github.com/Kilemonn/flow/codec"
+	"github.com/Kilemonn/flow/socket"
+)
+
+// A compression, encryption or timestamp stage of a connection, see [codec.EncodeWriter], [codec.DecodeWriter],
+// [encryption.EncryptWriter], [encryption.DecryptWriter] and [linePrefixWriter].
+type codecStage interface {
+	io.WriteCloser
+	Flush() error
+}
+
+func validateCodecs(connection ConfigConnection) error {
+	for _, algorithm := range []string{connection.Decompress, connection.Compress} {
+		if algorithm == "" {
+			continue
+		}
+		err := codec.Validate(algorithm)
+		if err != nil {
+			return fmt.Errorf("connection from [%s] to [%s] has an invalid compression with error: [%s]", connection.ReaderID, connection.WriterID, err.Error())
+		}
+	}
+	for _, crypt := range []*ConfigEncryption{connection.Decrypt, connection.Encrypt} {
+		if crypt == nil {
+			continue
+		}
+		err := crypt.validate(connection)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Wraps the writer in the compression, encryption and timestamp stages of the connection, see [clientStages]. The
+// writer is returned as is if the connection has no stages.
+func newCodecStages(writer io.Writer, connection ConfigConnection) (io.Writer, []codecStage, error) {
+	shared, err := newStageChain(writer, connection, dataSource{})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(shared.stages) == 0 {
+		return writer, nil, nil
+	}
+	stages := &clientStages{writer: writer, connection: connection, shared: shared, clients: make(map[int]*stageChain)}
+	return stages, []codecStage{stages}, nil
+}
+
+// Wraps the writer in the compression, encryption and timestamp stages of the connection, the data is decrypted,
+// decompressed, timestamped, compressed and then encrypted. The stages are returned in the order that the data flows
+// through them.
+func chainCodecStages(writer io.Writer, connection ConfigConnection) (io.Writer, []codecStage, error) {
+	stages := []codecStage{}
+	if connection.Encrypt != nil {
+		encrypter, err := connection.Encrypt.encryptWriter(writer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create [%s] encryption for connection from [%s] to [%s] with error: [%s]", connection.Encrypt.Algorithm, connection.ReaderID, connection.WriterID, err.Error())
+		}
+		writer = encrypter
+		stages = append(stages, encrypter)
+	}
+	if connection.Compress != "" {
+		encoder, err := codec.NewEncodeWriter(connection.Compress, writer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create [%s] compressor for connection from [%s] to [%s] with error: [%s]", connection.Compress, connection.ReaderID, connection.WriterID, err.Error())
+		}
+		writer = encoder
+		stages = append(stages, encoder)
+	}
+	if connection.Timestamp != nil {
+		prefixer := newLinePrefixWriter(*connection.Timestamp, connection.ReaderID, writer)
+		writer = prefixer
+		stages = append([]codecStage{prefixer}, stages...)
+	}
+	if connection.Decompress != "" {
+		decoder, err := codec.NewDecodeWriter(connection.Decompress, writer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create [%s] decompressor for connection from [%s] to [%s] with error: [%s]", connection.Decompress, connection.ReaderID, connection.WriterID, err.Error())
+		}
+		writer = decoder
+		stages = append([]codecStage{decoder}, stages...)
+	}
+	if connection.Decrypt != nil {
+		decrypter, err := connection.Decrypt.decryptWriter(writer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create [%s] decryption for connection from [%s] to [%s] with error: [%s]", connection.Decrypt.Algorithm, connection.ReaderID, connection.WriterID, err.Error())
+		}
+		writer = decrypter
+		stages = append([]codecStage{decrypter}, stages...)
+	}
+	return writer, stages, nil
+}
+
+// The codec stages of the data from one source, in the order that the data flows through them.
+type stageChain struct {
+	writer io.Writer
+	stages []codecStage
+	output *sourceWriter
+}
+
+// Creates the codec stages of the connection, whose output is written to the writer with the source.
+func newStageChain(writer io.Writer, connection ConfigConnection, source dataSource) (*stageChain, error) {
+	output := &sourceWriter{writer: writer, source: source}
+	top, stages, err := chainCodecStages(output, connection)
+	if err != nil {
+		return nil, err
+	}
+	return &stageChain{writer: top, stages: stages, output: output}, nil
+}
+
+// Flushes each stage of the chain, only the first occurring error is returned.
+func (c *stageChain) flush() error {
+	var err error
+	for _, stage := range c.stages {
+		e := stage.Flush()
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Closes each stage of the chain, only the first occurring error is returned.
+func (c *stageChain) close() error {
+	var err error
+	for _, stage := range c.stages {
+		e := stage.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Keeps a separate chain of the codec stages of a connection for each client of the reader, so that the partial
+// frames, lines and compressed streams of different clients are not mixed. The output of a client's chain is written
+// with its client. Datagrams and the data written without a client share a chain, and the output of a datagram is
+// written with the datagram.
+type clientStages struct {
+	writer     io.Writer
+	connection ConfigConnection
+	shared     *stageChain
+	clients    map[int]*stageChain
+	// The IDs of the clients in the order their chains were created, so they are flushed and closed in a fixed order
+	order []int
+}
+
+// [io.Writer.Write]
+func (s *clientStages) Write(b []byte) (int, error) {
+	return s.shared.writer.Write(b)
+}
+
+// [socket.DatagramWriter.WriteDatagram], the shared chain is flushed before returning so that all of the output of
+// the datagram (e.g. its partial line or compressed data) is written with the datagram.
+func (s *clientStages) WriteDatagram(d socket.Datagram) (int, error) {
+	s.shared.output.source = dataSource{datagram: &d}
+	defer func() {
+		s.shared.output.source = dataSource{}
+	}()
+	n, err := socket.WriteDatagram(s.shared.writer, d)
+	if err != nil {
+		return n, err
+	}
+	return n, s.shared.flush()
+}
+
+// [clientinfo.Writer.WriteClient], the chain of the client is created when it first writes.
+func (s *clientStages) WriteClient(info clientinfo.Info, b []byte) (int, error) {
+	chain, exists := s.clients[info.ID]
+	if !exists {
+		var err error
+		chain, err = newStageChain(s.writer, s.connection, dataSource{client: &info})
+		if err != nil {
+			return 0, err
+		}
+		s.clients[info.ID] = chain
+		s.order = append(s.order, info.ID)
+	}
+	return chain.writer.Write(b)
+}
+
+// [clientinfo.Writer.CloseClient], closes the chain of the client before the writer is notified, so the end of its
+// compressed stream or its partial line is written first.
+func (s *clientStages) CloseClient(info clientinfo.Info) error {
+	var err error
+	if chain, exists := s.clients[info.ID]; exists {
+		err = chain.close()
+		delete(s.clients, info.ID)
+		s.order = slices.DeleteFunc(s.order, func(id int) bool {
+			return id == info.ID
+		})
+	}
+	e := clientinfo.Close(s.writer, []clientinfo.Info{info})
+	if err == nil {
+		err = e
+	}
+	return err
+}
+
+// Flushes the shared chain and then the chain of each client, only the first occurring error is returned.
+func (s *clientStages) Flush() error {
+	err := s.shared.flush()
+	for _, id := range s.order {
+		e := s.clients[id].flush()
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// [io.Closer.Close], closes the shared chain and then the chain of each client, the writer is not closed. Only the
+// first occurring error is returned.
+func (s *clientStages) Close() error {
+	err := s.shared.close()
+	for _, id := range s.order {
+		e := s.clients[id].close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	clear(s.clients)
+	s.order = nil
+	return err
+}
+
+// Flushes the compressors of the connection so the data written so far can be decompressed by the writer, this is
+// called while the reader is idle so data is not held back while nothing else is being read.
+func (c Connection) flush() error {
+	var err error
+	for _, stage := range c.codecs {
+		e := stage.Flush()
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Closes the compression and encryption stages of the connection, writing the end of each compressed stream. Only the
+// first occurring error is returned.
+func (c Connection) closeCodecs() error {
+	var err error
+	for _, stage := range c.codecs {
+		e := stage.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
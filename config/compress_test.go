@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kilemonn/flow/clientinfo"
+	"github.com/Kilemonn/flow/socket"
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that the compressed data is flushed once the reader is idle, and the compressed stream is ended on shutdown.
+func TestApplyConfig_Compress(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	output := filepath.Join(dir, "output.gz")
+	content := strings.Repeat("TestApplyConfig_Compress", 100)
+	require.NoError(t, os.WriteFile(input, []byte(content), 0666))
+
+	config := Config{
+		Connections: []ConfigConnection{{ReaderID: "in", WriterID: "out", Compress: "gzip"}},
+		Nodes: ConfigNodes{
+			Files: []ConfigFile{{ID: "in", Path: input}, {ID: "out", Path: output}},
+		},
+		Settings: ConfigSettings{
+			ExitConditions: []ConfigExitCondition{{ReaderID: "in", EOF: true}},
+		},
+	}
+	require.NoError(t, config.Initialise())
+	require.Len(t, config.Conns[0].codecs, 1)
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	require.NoError(t, applyConfig(ctx, cancelFunc, config.Conns, config.Settings))
+
+	// The flushed data can be decompressed before the end of the stream is written
+	read, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Less(t, len(read), len(content))
+	reader, err := gzip.NewReader(bytes.NewReader(read))
+	require.NoError(t, err)
+	decompressed := make([]byte, len(content))
+	_, err = io.ReadFull(reader, decompressed)
+	require.NoError(t, err)
+	require.Equal(t, content, string(decompressed))
+
+	// The error of closing the nodes is ignored, since stdin may have already been closed by another test
+	config.Shutdown(time.Second)
+	read, err = os.ReadFile(output)
+	require.NoError(t, err)
+	reader, err = gzip.NewReader(bytes.NewReader(read))
+	require.NoError(t, err)
+	decompressed, err = io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, content, string(decompressed))
+}
+
+// Ensure that a compressed file is written decompressed.
+func TestApplyConfig_Decompress(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.gz")
+	output := filepath.Join(dir, "output.txt")
+	content := strings.Repeat("TestApplyConfig_Decompress", 100)
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, os.WriteFile(input, compressed.Bytes(), 0666))
+
+	config := Config{
+		Connections: []ConfigConnection{{ReaderID: "in", WriterID: "out", Decompress: "GZIP"}},
+		Nodes: ConfigNodes{
+			Files: []ConfigFile{{ID: "in", Path: input}, {ID: "out", Path: output}},
+		},
+		Settings: ConfigSettings{
+			ExitConditions: []ConfigExitCondition{{ReaderID: "in", EOF: true}},
+		},
+	}
+	require.NoError(t, config.Initialise())
+	defer config.Close()
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	require.NoError(t, applyConfig(ctx, cancelFunc, config.Conns, config.Settings))
+
+	read, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, content, string(read))
+}
+
+// Ensure that unsupported compression algorithms are a configuration error.
+// Ensure that the reverse direction of a bridge decompresses the data that the configured direction compresses.
+func TestConfig_CompressBridge(t *testing.T) {
+	config := Config{Connections: []ConfigConnection{{ReaderID: "a", WriterID: "b", Bridge: true, Compress: "gzip"}}}
+	connections := config.allConnections()
+	require.Len(t, connections, 2)
+	require.Equal(t, "gzip", connections[0].Compress)
+	require.Empty(t, connections[0].Decompress)
+	require.Equal(t, "b", connections[1].ReaderID)
+	require.Equal(t, "gzip", connections[1].Decompress)
+	require.Empty(t, connections[1].Compress)
+}
+
+func TestConfig_CompressValidation(t *testing.T) {
+	config := Config{Connections: []ConfigConnection{{ReaderID: StdIn, WriterID: StdOut, Compress: "brotli"}}}
+	require.Equal(t, ExitCodeConfigError, ExitCode(config.Initialise()))
+
+	config = Config{Connections: []ConfigConnection{{ReaderID: StdIn, WriterID: StdOut, Decompress: "brotli"}}}
+	require.Equal(t, ExitCodeConfigError, ExitCode(config.Initialise()))
+}
+
+// Records the data written for each client and in each datagram, along with the closed clients.
+type clientBuffers struct {
+	clients   map[int]*bytes.Buffer
+	datagrams []string
+	events    []string
+}
+
+func (r *clientBuffers) Write(b []byte) (int, error) {
+	r.events = append(r.events, "write")
+	return len(b), nil
+}
+
+func (r *clientBuffers) WriteClient(info clientinfo.Info, b []byte) (int, error) {
+	if r.clients[info.ID] == nil {
+		r.clients[info.ID] = &bytes.Buffer{}
+	}
+	return r.clients[info.ID].Write(b)
+}
+
+func (r *clientBuffers) CloseClient(info clientinfo.Info) error {
+	r.events = append(r.events, fmt.Sprintf("close %d", info.ID))
+	return nil
+}
+
+func (r *clientBuffers) WriteDatagram(d socket.Datagram) (int, error) {
+	r.datagrams = append(r.datagrams, string(d.Data))
+	return len(d.Data), nil
+}
+
+// Decompresses the gzip stream written for the client.
+func decompressClient(t *testing.T, output *clientBuffers, id int) string {
+	reader, err := gzip.NewReader(bytes.NewReader(output.clients[id].Bytes()))
+	require.NoError(t, err)
+	decompressed, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(decompressed)
+}
+
+// Ensure that each client is compressed into its own stream, which is ended before the client is closed, and that the
+// output of a datagram is written as a datagram.
+func TestCodecStages_Clients(t *testing.T) {
+	output := &clientBuffers{clients: make(map[int]*bytes.Buffer)}
+	writer, stages, err := newCodecStages(output, ConfigConnection{Compress: "gzip"})
+	require.NoError(t, err)
+	require.Len(t, stages, 1)
+
+	first, second := clientinfo.Info{ID: 1}, clientinfo.Info{ID: 2}
+	for _, write := range []struct {
+		info clientinfo.Info
+		data string
+	}{{first, "first "}, {second, "second "}, {first, "client"}, {second, "client"}} {
+		_, err = clientinfo.Write(writer, write.info, []byte(write.data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, clientinfo.Close(writer, []clientinfo.Info{first}))
+	require.Equal(t, "first client", decompressClient(t, output, 1))
+	require.Equal(t, []string{"close 1"}, output.events)
+
+	require.NoError(t, stages[0].Close())
+	require.Equal(t, "second client", decompressClient(t, output, 2))
+
+	output.events = nil
+	writer, _, err = newCodecStages(output, ConfigConnection{Timestamp: &ConfigTimestamp{Format: TimestampFormatRelative}})
+	require.NoError(t, err)
+	_, err = socket.WriteDatagram(writer, socket.Datagram{Data: []byte("line\n")})
+	require.NoError(t, err)
+	require.Len(t, output.datagrams, 1)
+	require.True(t, strings.HasSuffix(output.datagrams[0], " line\n"))
+
+	// The partial line of a datagram is written with the datagram rather than when the stages are next flushed
+	_, err = socket.WriteDatagram(writer, socket.Datagram{Data: []byte("prompt> ")})
+	require.NoError(t, err)
+	require.Len(t, output.datagrams, 2)
+	require.True(t, strings.HasSuffix(output.datagrams[1], " prompt> "))
+	require.Empty(t, output.events)
+
+	output.datagrams = nil
+	writer, _, err = newCodecStages(output, ConfigConnection{Compress: "gzip"})
+	require.NoError(t, err)
+	_, err = socket.WriteDatagram(writer, socket.Datagram{Data: []byte("datagram")})
+	require.NoError(t, err)
+	require.NotEmpty(t, output.datagrams)
+	require.Empty(t, output.events)
+}
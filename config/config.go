@@ -3,8 +3,13 @@ package config
 import (
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"slices"
+	"text/template"
 
+	"github.com/Kilemonn/flow/clientinfo"
+	"github.com/Kilemonn/flow/sink"
 	"github.com/Kilemonn/flow/stdio"
 	"gopkg.in/yaml.v3"
 )
@@ -12,8 +17,15 @@ import (
 const (
 	StdIn  string = "stdin"
 	StdOut string = "stdout"
+	// Discards the data written to it, see [sink.NullWriter]
+	Null string = "null"
+	// Counts the data written to it from each reader, see [sink.CounterWriter]
+	Counter string = "counter"
 )
 
+// The IDs that are always accessible without defining any nodes, used in errors
+var reservedIDs = fmt.Sprintf("\"%s\", \"%s\", \"%s\" or \"%s\"", StdIn, StdOut, Null, Counter)
+
 type Config struct {
 	Connections []ConfigConnection
 	Nodes       ConfigNodes
@@ -26,10 +38,16 @@ type Config struct {
 }
 
 type ConfigNodes struct {
-	Ports   []ConfigPort
-	Files   []ConfigFile
-	Sockets []ConfigSocket
-	Ipcs    []ConfigIPC
+	Ports      []ConfigPort
+	Files      []ConfigFile
+	Sockets    []ConfigSocket
+	Ipcs       []ConfigIPC
+	Captures   []ConfigCapture   `yaml:",omitempty"`
+	Replays    []ConfigReplay    `yaml:",omitempty"`
+	Monitors   []ConfigMonitor   `yaml:",omitempty"`
+	Merges     []ConfigMerge     `yaml:",omitempty"`
+	Scripts    []ConfigScript    `yaml:",omitempty"`
+	Generators []ConfigGenerator `yaml:",omitempty"`
 }
 
 type Connection struct {
@@ -37,11 +55,58 @@ type Connection struct {
 	ReaderId  string
 	Writer    io.Writer
 	WriterIds []string
+	// The exit conditions on the data read from the reader, these are also included in the Writer
+	exitConditions []*exitConditionWriter
+	// The writers of the connections with a rate limit or delay, these are also included in the Writer
+	shapers []*shapedWriter
+	// The compression and encryption stages of the connections, these are also included in the Writer
+	codecs []codecStage
 }
 
 type ConfigConnection struct {
 	ReaderID string
 	WriterID string
+	// When true the connection is full-duplex, data read from the writer node is also written back to the reader node.
+	// If a bridged node accepts clients (e.g. a TCP socket reader) the data is written back to its accepted clients.
+	Bridge bool
+	// Limits the rate that data is written to the writer, this applies to both directions of a bridge
+	RateLimit *ConfigRateLimit
+	// The milliseconds each chunk of data is delayed before it is written to the writer
+	Delay int
+	// Impairs the data written to the writer to emulate an unreliable network, this applies to both directions of a
+	// bridge
+	Impair *ConfigImpairment
+	// The compression algorithm used to compress the data before it is written to the writer, one of "gzip", "zstd",
+	// "snappy" or "lz4". The reverse direction of a bridge decompresses instead.
+	Compress string
+	// The compression algorithm used to decompress the data read from the reader. The reverse direction of a bridge
+	// compresses instead.
+	Decompress string
+	// Encrypts the data before it is written to the writer, after any compression. The reverse direction of a bridge
+	// decrypts instead.
+	Encrypt *ConfigEncryption
+	// Decrypts the data read from the reader, before any decompression. The reverse direction of a bridge encrypts
+	// instead.
+	Decrypt *ConfigEncryption
+	// Adds a timestamp, and optionally the reader ID, to the start of each line after it is decrypted and decompressed.
+	// This only applies to the configured direction of a bridge.
+	Timestamp *ConfigTimestamp
+	// Routes each line or chunk of data to the writers of the rules it matches, this is used instead of the WriterID.
+	// The data is decrypted and decompressed before it is routed, the other properties of the connection apply to each
+	// of the route's writers.
+	Route *ConfigRoute
+}
+
+// Implemented by models whose readers accept client connections and can write back to them, see [ConfigConnection.Bridge].
+type clientAcceptor interface {
+	// acceptsClients returns true if the reader of this model accepts clients and implements [io.Writer] to write to them
+	acceptsClients() bool
+}
+
+// Implemented by models whose readers accept multiple clients, and can tag the data of each client with a header.
+type clientHeaderProvider interface {
+	// clientHeader returns the configured header template, or nil if none is configured
+	clientHeader() *template.Template
 }
 
 // An interface that all Config* objects will implement.
@@ -59,14 +124,19 @@ type ConfigModel interface {
 func (c *Config) Initialise() error {
 	err := c.validate()
 	if err != nil {
-		return err
+		return newExitError(ExitCodeConfigError, err)
 	}
 
+	c.shareBridgeSenders()
+	c.seedImpairments()
 	err = c.combineToReadersAndWriters()
 	if err != nil {
-		return err
+		return newExitError(ExitCodeNodeOpenFailure, err)
+	}
+	err = c.createConnections()
+	if err != nil {
+		return newExitError(ExitCodeNodeOpenFailure, err)
 	}
-	c.createConnections()
 
 	return nil
 }
@@ -95,6 +165,46 @@ func (c *Config) validate() error {
 		return err
 	}
 
+	for _, connection := range c.Connections {
+		if connection.Bridge && (isInvalidID(connection.ReaderID) || isInvalidID(connection.WriterID)) {
+			return fmt.Errorf("bridge between [%s] and [%s] cannot include %s", connection.ReaderID, connection.WriterID, reservedIDs)
+		}
+		if connection.ReaderID == Null || connection.ReaderID == Counter {
+			return fmt.Errorf("connection to [%s] cannot read from [%s], it can only be used as a writer", connection.WriterID, connection.ReaderID)
+		}
+		if connection.Delay < 0 {
+			return fmt.Errorf("connection from [%s] to [%s] has a negative delay [%d]", connection.ReaderID, connection.WriterID, connection.Delay)
+		}
+		if connection.RateLimit != nil {
+			err = connection.RateLimit.validate(connection)
+			if err != nil {
+				return err
+			}
+		}
+		if connection.Impair != nil {
+			err = connection.Impair.validate(connection)
+			if err != nil {
+				return err
+			}
+		}
+		if connection.Timestamp != nil {
+			err = connection.Timestamp.validate(connection)
+			if err != nil {
+				return err
+			}
+		}
+		err = validateCodecs(connection)
+		if err != nil {
+			return err
+		}
+		if connection.Route != nil {
+			err = connection.Route.validate(connection)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	for _, model := range c.models {
 		err = model.Validate()
 		if err != nil {
@@ -102,29 +212,42 @@ func (c *Config) validate() error {
 		}
 	}
 
-	return nil
+	err = c.validateScripts()
+	if err != nil {
+		return err
+	}
+	return c.validateExitConditions()
 }
 
+// Returns true for the IDs that are always accessible, which cannot be used by configured nodes.
 func isInvalidID(id string) bool {
-	return id == StdIn || id == StdOut
+	return id == StdIn || id == StdOut || id == Null || id == Counter
 }
 
-// Check that the IDs of files and ports are unique and also do not clash with stdin or stdout
+// Check that the IDs of files and ports are unique and also do not clash with the reserved IDs, e.g. stdin or stdout
 func (c *Config) componentIDsAreUnique() error {
 	c.models = make(map[string]ConfigModel)
 	nodes := c.Nodes
 
 	for _, port := range nodes.Ports {
 		if _, exists := c.models[port.GetID()]; isInvalidID(port.GetID()) || exists {
-			return fmt.Errorf("found port with a duplicate ID [%s] defined or is overriding \"%s\" or \"%s\"", port.GetID(), StdIn, StdOut)
+			return fmt.Errorf("found port with a duplicate ID [%s] defined or is overriding %s", port.GetID(), reservedIDs)
 		} else {
 			c.models[port.GetID()] = &port
 		}
+
+		if port.RFC2217 != nil {
+			server := rfc2217Node{port: &port}
+			if _, exists := c.models[server.GetID()]; exists {
+				return fmt.Errorf("found RFC 2217 server with a duplicate ID [%s] defined", server.GetID())
+			}
+			c.models[server.GetID()] = server
+		}
 	}
 
 	for _, file := range nodes.Files {
 		if _, exists := c.models[file.GetID()]; isInvalidID(file.GetID()) || exists {
-			return fmt.Errorf("found file with a duplicate ID [%s] defined or is overriding \"%s\" or \"%s\"", file.GetID(), StdIn, StdOut)
+			return fmt.Errorf("found file with a duplicate ID [%s] defined or is overriding %s", file.GetID(), reservedIDs)
 		} else {
 			c.models[file.GetID()] = file
 		}
@@ -132,7 +255,7 @@ func (c *Config) componentIDsAreUnique() error {
 
 	for _, socket := range nodes.Sockets {
 		if _, exists := c.models[socket.GetID()]; isInvalidID(socket.GetID()) || exists {
-			return fmt.Errorf("found socket with a duplicate ID [%s] defined or is overriding \"%s\" or \"%s\"", socket.GetID(), StdIn, StdOut)
+			return fmt.Errorf("found socket with a duplicate ID [%s] defined or is overriding %s", socket.GetID(), reservedIDs)
 		} else {
 			c.models[socket.GetID()] = socket
 		}
@@ -140,17 +263,67 @@ func (c *Config) componentIDsAreUnique() error {
 
 	for _, ipc := range nodes.Ipcs {
 		if _, exists := c.models[ipc.GetID()]; isInvalidID(ipc.GetID()) || exists {
-			return fmt.Errorf("found ipc with a duplicate ID [%s] defined or is overriding \"%s\" or \"%s\"", ipc.GetID(), StdIn, StdOut)
+			return fmt.Errorf("found ipc with a duplicate ID [%s] defined or is overriding %s", ipc.GetID(), reservedIDs)
 		} else {
 			c.models[ipc.GetID()] = ipc
 		}
 	}
 
+	for _, node := range nodes.Captures {
+		if _, exists := c.models[node.GetID()]; isInvalidID(node.GetID()) || exists {
+			return fmt.Errorf("found capture with a duplicate ID [%s] defined or is overriding %s", node.GetID(), reservedIDs)
+		} else {
+			c.models[node.GetID()] = node
+		}
+	}
+
+	for _, monitor := range nodes.Monitors {
+		if _, exists := c.models[monitor.GetID()]; isInvalidID(monitor.GetID()) || exists {
+			return fmt.Errorf("found monitor with a duplicate ID [%s] defined or is overriding %s", monitor.GetID(), reservedIDs)
+		} else {
+			c.models[monitor.GetID()] = monitor
+		}
+	}
+
+	for _, node := range nodes.Merges {
+		if _, exists := c.models[node.GetID()]; isInvalidID(node.GetID()) || exists {
+			return fmt.Errorf("found merge with a duplicate ID [%s] defined or is overriding %s", node.GetID(), reservedIDs)
+		} else {
+			// The merge is both a reader and a writer, which share the same merger
+			c.models[node.GetID()] = &node
+		}
+	}
+
+	for _, node := range nodes.Scripts {
+		if _, exists := c.models[node.GetID()]; isInvalidID(node.GetID()) || exists {
+			return fmt.Errorf("found script with a duplicate ID [%s] defined or is overriding %s", node.GetID(), reservedIDs)
+		} else {
+			// The script is both a reader and a writer, which share the same runner
+			c.models[node.GetID()] = &node
+		}
+	}
+
+	for _, node := range nodes.Generators {
+		if _, exists := c.models[node.GetID()]; isInvalidID(node.GetID()) || exists {
+			return fmt.Errorf("found generator with a duplicate ID [%s] defined or is overriding %s", node.GetID(), reservedIDs)
+		} else {
+			c.models[node.GetID()] = node
+		}
+	}
+
+	for _, replay := range nodes.Replays {
+		if _, exists := c.models[replay.GetID()]; isInvalidID(replay.GetID()) || exists {
+			return fmt.Errorf("found replay with a duplicate ID [%s] defined or is overriding %s", replay.GetID(), reservedIDs)
+		} else {
+			c.models[replay.GetID()] = replay
+		}
+	}
+
 	return nil
 }
 
 // Load all configured readers and writers and load them into the returned map with "id" -> [io.ReadCloser] / [io.WriteCloser] as appropriate.
-// StdIn and StdOut are also initialised and returned in these maps.
+// StdIn and StdOut are also initialised and returned in these maps, along with Null and Counter when they are written to.
 func (c *Config) combineToReadersAndWriters() error {
 	c.readers = make(map[string]io.ReadCloser)
 	stdIn, _ := stdio.CreateStdInReader()
@@ -163,7 +336,7 @@ func (c *Config) combineToReadersAndWriters() error {
 	// Firstly iterate over and ONLY initialise the READER (listening) sockets, since if we connect to ourself we need to make sure
 	// the reader is listening first before the writer connects to us (for TCP). See below for the second loop.
 	// This is the same for IPC channels.
-	for _, connection := range c.Connections {
+	for _, connection := range c.allConnections() {
 		rID := connection.ReaderID
 
 		if _, exists := c.readers[rID]; !exists {
@@ -178,10 +351,28 @@ func (c *Config) combineToReadersAndWriters() error {
 	}
 
 	// Move the socket writer init and IPC init to a second loop:
-	for _, connection := range c.Connections {
+	for _, connection := range c.allConnections() {
 		wID := connection.WriterID
 
 		if _, exists := c.writers[wID]; !exists {
+			// The totals of the null and counter writers are reported when they are closed
+			switch wID {
+			case Null:
+				c.writers[wID] = sink.NewNullWriter(os.Stdout)
+				continue
+			case Counter:
+				c.writers[wID] = sink.NewCounterWriter(os.Stdout)
+				continue
+			}
+
+			// Bridged nodes that accept clients write back to those clients rather than creating a new connection
+			if acceptor, ok := c.models[wID].(clientAcceptor); ok && c.isBridged(wID) && acceptor.acceptsClients() {
+				if w, ok := c.readers[wID].(io.WriteCloser); ok {
+					c.writers[wID] = newReplyWriter(w)
+					continue
+				}
+			}
+
 			if model, ok := c.models[wID]; ok {
 				w, err := model.Writer()
 				if err != nil {
@@ -197,64 +388,206 @@ func (c *Config) combineToReadersAndWriters() error {
 	return nil
 }
 
+// Get the configured connections, along with the bridge between each port and its RFC 2217 server.
+func (c Config) configuredConnections() []ConfigConnection {
+	connections := slices.Clone(c.Connections)
+	for _, port := range c.Nodes.Ports {
+		if port.RFC2217 != nil {
+			connections = append(connections, ConfigConnection{ReaderID: port.GetID(), WriterID: port.GetID() + RFC2217IDSuffix, Bridge: true})
+		}
+	}
+	return connections
+}
+
+// Get all the configured connections, bridged connections are expanded into an additional connection in the reverse
+// direction. The reverse direction undoes the compression and encryption of the configured direction, is not
+// timestamped and is impaired with its own seed. Routed connections are expanded into a connection to each of the route's writers.
+func (c Config) allConnections() []ConfigConnection {
+	connections := []ConfigConnection{}
+	for _, connection := range c.configuredConnections() {
+		if connection.Route != nil {
+			for _, writerID := range connection.Route.writerIDs() {
+				connection.WriterID = writerID
+				connections = append(connections, connection)
+			}
+			continue
+		}
+
+		bridge := connection.Bridge
+		connection.Bridge = false
+		connections = append(connections, connection)
+		if bridge {
+			connection.ReaderID, connection.WriterID = connection.WriterID, connection.ReaderID
+			connection.Compress, connection.Decompress = connection.Decompress, connection.Compress
+			connection.Encrypt, connection.Decrypt = connection.Decrypt, connection.Encrypt
+			connection.Timestamp = nil
+			if connection.Impair != nil {
+				impair := *connection.Impair
+				impair.reverse = true
+				connection.Impair = &impair
+			}
+			connections = append(connections, connection)
+		}
+	}
+	return connections
+}
+
+// Whether the node with the provided ID is part of a bridged connection.
+func (c Config) isBridged(id string) bool {
+	for _, connection := range c.configuredConnections() {
+		if connection.Bridge && (connection.ReaderID == id || connection.WriterID == id) {
+			return true
+		}
+	}
+	return false
+}
+
 // Create the connection objects which contains the [io.ReadCloser] and its [io.WriteCloser].
-// This will look up and resolve multiple writers per reader, and bundle them in a [io.MultiWriter].
-func (c *Config) createConnections() {
+// This will look up and resolve multiple writers per reader, and bundle them in a [multiWriter].
+func (c *Config) createConnections() error {
 	convertedReaders := make(map[string]bool)
 	c.Conns = make([]Connection, 0)
-	for _, conf := range c.Connections {
+	for _, conf := range c.allConnections() {
 		if _, exists := convertedReaders[conf.ReaderID]; !exists {
-			writer, writerIds := c.getWritersForReaderId(conf.ReaderID)
+			connection, err := c.getWritersForReaderId(conf.ReaderID)
+			if err != nil {
+				return err
+			}
 			convertedReaders[conf.ReaderID] = true
 
-			if writer != nil {
-				c.Conns = append(c.Conns, Connection{
-					Reader:    c.readers[conf.ReaderID],
-					ReaderId:  conf.ReaderID,
-					Writer:    writer,
-					WriterIds: writerIds,
-				})
+			if writer := connection.Writer; writer != nil {
+				// The clients of a bridged reader are recorded so that the replies are written back to them
+				if reply, ok := c.writers[conf.ReaderID].(*replyWriter); ok {
+					writer = requestWriter{writer: writer, reply: reply}
+				}
+
+				conditions := []*exitConditionWriter{}
+				for _, condition := range c.Settings.ExitConditions {
+					if condition.ReaderID == conf.ReaderID {
+						conditions = append(conditions, newExitConditionWriter(condition))
+					}
+				}
+				if len(conditions) > 0 {
+					writers := []io.Writer{writer}
+					for _, condition := range conditions {
+						writers = append(writers, condition)
+					}
+					writer = newMultiWriter(writers...)
+				}
+
+				connection.Reader = c.readers[conf.ReaderID]
+				connection.Writer = writer
+				connection.exitConditions = conditions
+				c.Conns = append(c.Conns, connection)
 			} else {
 				fmt.Printf("Resolved no matching writers for reader with id [%s]", conf.ReaderID)
 			}
 		}
 	}
+	return nil
 }
 
-// Get all the [io.WriteCloser] that has the provided [string] as its registered [io.ReadCloser]. If only a single [io.WriteCloser] is resolved it will
-// be returned, otherwise if there are multiple they will be wrapped in an [io.MultiWriter].
-func (c Config) getWritersForReaderId(readerId string) (io.Writer, []string) {
+// Get all the [io.WriteCloser] that has the provided [string] as its registered [io.ReadCloser], returned as the Writer
+// of a [Connection] without its Reader. If only a single [io.WriteCloser] is resolved it will be used, otherwise if
+// there are multiple they will be wrapped in a [multiWriter].
+// If the reader is configured with a client header, each writer is wrapped in a [clientinfo.HeaderWriter]. Each writer
+// is then wrapped in its connection's [shapedWriter] and compression stages. The writers of a route are written to by a
+// single [routeWriter], which is written to through the decryption and decompression of the route's connection.
+func (c Config) getWritersForReaderId(readerId string) (Connection, error) {
+	var header *template.Template
+	if provider, ok := c.models[readerId].(clientHeaderProvider); ok {
+		header = provider.clientHeader()
+	}
+
 	w := []io.Writer{}
 	writerNames := []string{}
-	for _, conf := range c.Connections {
+	shapers := []*shapedWriter{}
+	codecs := []codecStage{}
+	routes := []ConfigConnection{}
+	routeWriters := make(map[*ConfigRoute]map[string]io.Writer)
+	for _, conf := range c.allConnections() {
 		if conf.ReaderID == readerId {
-			w = append(w, c.writers[conf.WriterID])
+			writer := io.Writer(c.writers[conf.WriterID])
+			_, isClientWriter := writer.(clientinfo.Writer)
+			if sourceWriter, ok := writer.(readerIDWriter); ok {
+				writer = newFromReaderWriter(sourceWriter, readerId, c.readers[readerId])
+			}
+			if header != nil && !isClientWriter {
+				writer = clientinfo.HeaderWriter{Writer: writer, Header: header}
+			}
+			if shaper := newShapedWriter(writer, conf); shaper != nil {
+				writer = shaper
+				shapers = append(shapers, shaper)
+			}
+			writerConf := conf
+			if conf.Route != nil {
+				// The data of a route is decrypted and decompressed once before it is routed, see below
+				writerConf.Decrypt, writerConf.Decompress = nil, ""
+			}
+			writer, stages, err := newCodecStages(writer, writerConf)
+			if err != nil {
+				return Connection{}, err
+			}
+			codecs = append(codecs, stages...)
 			writerNames = append(writerNames, conf.WriterID)
+
+			if conf.Route != nil {
+				if _, exists := routeWriters[conf.Route]; !exists {
+					routes = append(routes, conf)
+					routeWriters[conf.Route] = make(map[string]io.Writer)
+				}
+				routeWriters[conf.Route][conf.WriterID] = writer
+				continue
+			}
+			w = append(w, writer)
 		}
 	}
 
-	if len(w) == 0 {
-		return nil, writerNames
-	} else if len(w) == 1 {
-		return w[0], writerNames
-	} else {
-		return io.MultiWriter(w...), writerNames
+	for _, conf := range routes {
+		router := newRouteWriter(*conf.Route, routeWriters[conf.Route])
+		// The rules are matched against the decrypted and decompressed data
+		writer, stages, err := newCodecStages(router, ConfigConnection{ReaderID: conf.ReaderID, Decrypt: conf.Decrypt, Decompress: conf.Decompress})
+		if err != nil {
+			return Connection{}, err
+		}
+		w = append(w, writer)
+		// The data is decrypted and decompressed, and the buffered lines are routed, before the stages of the route's
+		// writers are closed
+		codecs = append(append(stages, router), codecs...)
+	}
+
+	connection := Connection{ReaderId: readerId, WriterIds: writerNames, shapers: shapers, codecs: codecs}
+	if len(w) == 1 {
+		connection.Writer = w[0]
+	} else if len(w) > 1 {
+		connection.Writer = newMultiWriter(w...)
 	}
+	return connection, nil
 }
 
 // Close all provided reader and writers
-// Only the "first" occurring error will be returned, writers are closed first.
+// Only the "first" occurring error will be returned, readers are closed first so nothing is read that can no longer
+// be written. The readers and then the writers are closed in the order of their IDs. Writers of bridged nodes that are
+// their own reader are only closed as a reader. The compression stages
+// of each connection are closed before the writers.
 func (c Config) Close() error {
 	var err error
-	for _, w := range c.writers {
-		e := w.Close()
+	for _, connection := range c.Conns {
+		e := connection.closeCodecs()
 		if e != nil && err == nil {
 			err = e
 		}
 	}
 
-	for _, r := range c.readers {
-		e := r.Close()
+	for _, id := range slices.Sorted(maps.Keys(c.readers)) {
+		e := c.readers[id].Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(c.writers)) {
+		e := c.writers[id].Close()
 		if e != nil && err == nil {
 			err = e
 		}
@@ -262,3 +595,13 @@ func (c Config) Close() error {
 
 	return err
 }
+
+// Parse the provided text as a template used to render client headers or paths, see [clientinfo.Info] for the
+// available fields.
+func parseClientTemplate(id string, text string) (*template.Template, error) {
+	t, err := template.New(id).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("node with ID [%s] has an invalid template [%s] with error: [%s]", id, text, err.Error())
+	}
+	return t, nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Kilemonn/flow/capture"
+)
+
+// Records the data of each reader it is connected to with the time it was read, see [capture.Writer].
+// This can only be used as a writer.
+type ConfigCapture struct {
+	ID   string
+	Path string
+}
+
+// [ConfigModel.GetID]
+func (c ConfigCapture) GetID() string {
+	return c.ID
+}
+
+// [ConfigModel.Validate]
+func (c ConfigCapture) Validate() error {
+	if c.Path == "" {
+		return fmt.Errorf("capture with ID [%s] has no path", c.GetID())
+	}
+	return nil
+}
+
+// [ConfigModel.Reader]
+func (c ConfigCapture) Reader() (io.ReadCloser, error) {
+	return nil, fmt.Errorf("capture with ID [%s] can only be used as a writer, use a replay to read it", c.GetID())
+}
+
+// [ConfigModel.Writer]
+func (c ConfigCapture) Writer() (io.WriteCloser, error) {
+	return capture.NewWriter(c.Path)
+}
+
+// Plays back a capture with its original timing, see [capture.ReplayReader]. This can only be used as a reader.
+type ConfigReplay struct {
+	ID   string
+	Path string
+	// Multiplies the replay speed, e.g. 2 replays twice as fast as it was captured. The original speed is used when 0
+	Speed float64
+	// Starts the replay again from the beginning once all of the capture is replayed
+	Loop bool
+	// Only replays the data captured from the reader with this ID, all data is replayed when empty
+	ReaderID string
+}
+
+// [ConfigModel.GetID]
+func (c ConfigReplay) GetID() string {
+	return c.ID
+}
+
+// [ConfigModel.Validate]
+func (c ConfigReplay) Validate() error {
+	if c.Path == "" {
+		return fmt.Errorf("replay with ID [%s] has no path", c.GetID())
+	}
+	if c.Speed < 0 {
+		return fmt.Errorf("replay with ID [%s] has a negative speed [%f]", c.GetID(), c.Speed)
+	}
+	return nil
+}
+
+// [ConfigModel.Reader]
+func (c ConfigReplay) Reader() (io.ReadCloser, error) {
+	reader, err := capture.NewReplayReader(c.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture [%s] for replay with ID [%s] with error: [%s]", c.Path, c.GetID(), err.Error())
+	}
+	reader.Speed = c.Speed
+	reader.Loop = c.Loop
+	reader.ReaderID = c.ReaderID
+	return reader, nil
+}
+
+// [ConfigModel.Writer]
+func (c ConfigReplay) Writer() (io.WriteCloser, error) {
+	return nil, fmt.Errorf("replay with ID [%s] can only be used as a reader, use a capture to write it", c.GetID())
+}
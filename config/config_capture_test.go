@@ -0,0 +1,71 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that a capture records the data of each of its readers, and that a replay plays back the data captured from
+// a single reader.
+func TestCaptureAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	capturePath := filepath.Join(dir, "capture.jsonl")
+	output := filepath.Join(dir, "output.txt")
+	require.NoError(t, os.WriteFile(first, []byte("TestCaptureAndReplay"), 0666))
+	require.NoError(t, os.WriteFile(second, []byte("other"), 0666))
+
+	config := Config{
+		Connections: []ConfigConnection{
+			{ReaderID: "first", WriterID: "capture"},
+			{ReaderID: "second", WriterID: "capture"},
+		},
+		Nodes: ConfigNodes{
+			Files:    []ConfigFile{{ID: "first", Path: first}, {ID: "second", Path: second}},
+			Captures: []ConfigCapture{{ID: "capture", Path: capturePath}},
+		},
+		Settings: ConfigSettings{Timeout: 1},
+	}
+	require.NoError(t, config.Initialise())
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	require.NoError(t, applyConfig(ctx, cancelFunc, config.Conns, config.Settings))
+	config.Close()
+
+	config = Config{
+		Connections: []ConfigConnection{{ReaderID: "replay", WriterID: "output"}},
+		Nodes: ConfigNodes{
+			Files:   []ConfigFile{{ID: "output", Path: output}},
+			Replays: []ConfigReplay{{ID: "replay", Path: capturePath, ReaderID: "first"}},
+		},
+		Settings: ConfigSettings{
+			ExitConditions: []ConfigExitCondition{{ReaderID: "replay", EOF: true}},
+		},
+	}
+	require.NoError(t, config.Initialise())
+	defer config.Close()
+	ctx, cancelFunc = context.WithCancel(context.Background())
+	require.NoError(t, applyConfig(ctx, cancelFunc, config.Conns, config.Settings))
+
+	read, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "TestCaptureAndReplay", string(read))
+}
+
+// Ensure that captures can only be written and replays can only be read.
+func TestCaptureAndReplay_Direction(t *testing.T) {
+	_, err := ConfigCapture{ID: "capture", Path: "capture.jsonl"}.Reader()
+	require.Error(t, err)
+	_, err = ConfigReplay{ID: "replay", Path: "capture.jsonl"}.Writer()
+	require.Error(t, err)
+
+	require.Error(t, ConfigCapture{ID: "capture"}.Validate())
+	require.Error(t, ConfigReplay{ID: "replay", Path: "capture.jsonl", Speed: -1}.Validate())
+
+	_, err = ConfigReplay{ID: "replay", Path: filepath.Join(t.TempDir(), "missing.jsonl")}.Reader()
+	require.Error(t, err)
+}
@@ -5,13 +5,17 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/Kilemonn/flow/bidetwriter"
+	"github.com/Kilemonn/flow/clientinfo"
 	"github.com/Kilemonn/flow/sync_file_read_writer"
 )
 
 type ConfigFile struct {
-	ID   string
+	ID string
+	// The file path, when this contains a template (e.g. "client-{{.ID}}.log") and is used as a writer, the data of
+	// each client of the reader is written to its own file. See [clientinfo.Info] for the available fields.
 	Path string
 	// Determines whether this file is in truncate mode or append mode. By default this is false
 	// meaning it is in append mode.
@@ -27,6 +31,11 @@ func (c ConfigFile) GetID() string {
 
 // [ConfigModel.Validate]
 func (c ConfigFile) Validate() error {
+	if c.isPerClient() {
+		_, err := parseClientTemplate(c.GetID(), c.Path)
+		return err
+	}
+
 	// TODO: Should we fail on input files that don't exist?
 	if _, err := os.Stat(c.Path); errors.Is(err, os.ErrNotExist) {
 		file, err := os.Create(c.Path)
@@ -40,12 +49,28 @@ func (c ConfigFile) Validate() error {
 
 // [ConfigModel.Reader]
 func (c ConfigFile) Reader() (io.ReadCloser, error) {
+	if c.isPerClient() {
+		return nil, fmt.Errorf("file with ID [%s] has a templated path [%s] and can only be used as a writer", c.GetID(), c.Path)
+	}
+
 	err := c.initialiseFile()
 	return c.file, err
 }
 
 // [ConfigModel.Writer]
 func (c ConfigFile) Writer() (io.WriteCloser, error) {
+	if c.isPerClient() {
+		path, err := parseClientTemplate(c.GetID(), c.Path)
+		if err != nil {
+			return nil, err
+		}
+		mode := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		if c.Trunc {
+			mode |= os.O_TRUNC
+		}
+		return clientinfo.NewFileWriter(path, mode), nil
+	}
+
 	err := c.initialiseFile()
 	if err != nil {
 		return nil, err
@@ -53,6 +78,11 @@ func (c ConfigFile) Writer() (io.WriteCloser, error) {
 	return bidetwriter.NewBidetWriter(c.file), nil
 }
 
+// Whether the path is a template, resolved per client of the reader
+func (c ConfigFile) isPerClient() bool {
+	return strings.Contains(c.Path, "{{")
+}
+
 func (c *ConfigFile) initialiseFile() error {
 	if c.file == nil {
 		mode := os.O_CREATE | os.O_RDWR
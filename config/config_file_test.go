@@ -3,6 +3,7 @@ package config
 import (
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/Kilemonn/flow/testutil"
@@ -191,3 +192,18 @@ func TestFileAsReaderAndWriter_NoTruncate_WriteThenRead(t *testing.T) {
 		require.Equal(t, initialContent+content, string(read))
 	})
 }
+
+// Ensure that a templated path is validated as a template and can only be used as a writer.
+func TestFileWithTemplatedPath(t *testing.T) {
+	fileConfig := ConfigFile{ID: "templated", Path: filepath.Join(t.TempDir(), "client-{{.ID}}.txt")}
+	require.NoError(t, fileConfig.Validate())
+
+	_, err := fileConfig.Reader()
+	require.Error(t, err)
+
+	writer, err := fileConfig.Writer()
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	require.Error(t, ConfigFile{ID: "invalid", Path: "client-{{.ID"}.Validate())
+}
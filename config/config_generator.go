@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/Kilemonn/flow/generator"
+)
+
+// The default [ConfigGenerator.Size] of random messages
+const defaultGeneratorSize = 64
+
+// Generates messages of test data at a configured rate, see [generator.Generator]. This can only be used as a reader.
+type ConfigGenerator struct {
+	ID string
+	// Either "repeat", "counter", "random" or "template", see [generator.ModeRepeat], [generator.ModeCounter],
+	// [generator.ModeRandom] and [generator.ModeTemplate]
+	Mode string
+	// The data of each message in "repeat" mode, or the text/template of each message in "template" mode
+	Data string
+	// The bytes of each message in "random" mode, defaults to 64 when 0
+	Size int
+	// The seed of the random messages
+	Seed int64
+	// The messages generated per second, unlimited when 0
+	Rate float64
+	// The amount of messages generated, unlimited when 0
+	Count int
+}
+
+// [ConfigModel.GetID]
+func (c ConfigGenerator) GetID() string {
+	return c.ID
+}
+
+// [ConfigModel.Validate]
+func (c ConfigGenerator) Validate() error {
+	switch c.Mode {
+	case generator.ModeRepeat:
+		if c.Data == "" {
+			return fmt.Errorf("generator with ID [%s] has no data to repeat", c.GetID())
+		}
+	case generator.ModeTemplate:
+		_, err := c.template()
+		if err != nil {
+			return fmt.Errorf("generator with ID [%s] has an invalid template with error: [%s]", c.GetID(), err.Error())
+		}
+	case generator.ModeCounter, generator.ModeRandom:
+	default:
+		return fmt.Errorf("generator with ID [%s] has an invalid mode [%s], expected one of [%s, %s, %s, %s]", c.GetID(), c.Mode, generator.ModeRepeat, generator.ModeCounter, generator.ModeRandom, generator.ModeTemplate)
+	}
+
+	if c.Size < 0 || c.Rate < 0 || c.Count < 0 {
+		return fmt.Errorf("generator with ID [%s] has a negative size, rate or count", c.GetID())
+	}
+	return nil
+}
+
+// Parses the template and evaluates it once, so that fields that do not exist are reported before the flow starts.
+func (c ConfigGenerator) template() (*template.Template, error) {
+	tmpl, err := template.New(c.GetID()).Parse(c.Data)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, tmpl.Execute(io.Discard, generator.TemplateData{})
+}
+
+// [ConfigModel.Reader]
+func (c ConfigGenerator) Reader() (io.ReadCloser, error) {
+	var g *generator.Generator
+	switch c.Mode {
+	case generator.ModeRepeat:
+		g = generator.NewRepeat([]byte(c.Data))
+	case generator.ModeCounter:
+		g = generator.NewCounter()
+	case generator.ModeRandom:
+		size := c.Size
+		if size == 0 {
+			size = defaultGeneratorSize
+		}
+		g = generator.NewRandom(size, c.Seed)
+	default:
+		// The template is checked when the config is validated
+		tmpl, _ := c.template()
+		g = generator.NewTemplate(tmpl)
+	}
+	g.Rate = c.Rate
+	g.Count = c.Count
+	return g, nil
+}
+
+// [ConfigModel.Writer]
+func (c ConfigGenerator) Writer() (io.WriteCloser, error) {
+	return nil, fmt.Errorf("generator with ID [%s] can only be used as a reader", c.GetID())
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that the messages generated from a template are written, up to the count.
+func TestApplyConfig_Generator(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output.txt")
+	config := Config{
+		Connections: []ConfigConnection{{ReaderID: "generator", WriterID: "output"}},
+		Nodes: ConfigNodes{
+			Files:      []ConfigFile{{ID: "output", Path: output}},
+			Generators: []ConfigGenerator{{ID: "generator", Mode: "template", Data: "message {{.Sequence}}\n", Rate: 100, Count: 3}},
+		},
+		Settings: ConfigSettings{
+			ExitConditions: []ConfigExitCondition{{ReaderID: "generator", Bytes: 30}},
+		},
+	}
+	require.NoError(t, config.Initialise())
+	defer config.Close()
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	require.NoError(t, applyConfig(ctx, cancelFunc, config.Conns, config.Settings))
+
+	read, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "message 1\nmessage 2\nmessage 3\n", string(read))
+}
+
+func TestConfigGenerator_Validate(t *testing.T) {
+	require.NoError(t, ConfigGenerator{ID: "generator", Mode: "repeat", Data: "ping"}.Validate())
+	require.NoError(t, ConfigGenerator{ID: "generator", Mode: "counter", Rate: 10, Count: 100}.Validate())
+	require.NoError(t, ConfigGenerator{ID: "generator", Mode: "random", Size: 128, Seed: 7}.Validate())
+	require.NoError(t, ConfigGenerator{ID: "generator", Mode: "template", Data: "{{.Sequence}} {{.Time.Unix}}"}.Validate())
+	require.Error(t, ConfigGenerator{ID: "generator"}.Validate())
+	require.Error(t, ConfigGenerator{ID: "generator", Mode: "repeat"}.Validate())
+	require.Error(t, ConfigGenerator{ID: "generator", Mode: "template", Data: "{{.Sequence"}.Validate())
+	require.Error(t, ConfigGenerator{ID: "generator", Mode: "template", Data: "{{.Missing}}"}.Validate())
+	require.Error(t, ConfigGenerator{ID: "generator", Mode: "counter", Rate: -1}.Validate())
+
+	_, err := ConfigGenerator{ID: "generator"}.Writer()
+	require.Error(t, err)
+}
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"io"
+	"text/template"
+	"time"
 
 	"github.com/Kilemonn/flow/ipc"
 )
@@ -9,6 +12,11 @@ import (
 type ConfigIPC struct {
 	ID      string
 	Channel string
+	// A template prefixed to each chunk of data received from a client, e.g. "[client {{.ID}}] ".
+	// See [clientinfo.Info] for the available fields.
+	ClientHeader string
+	// The milliseconds each read (and accept) attempt of a reader waits for data, [ipc.IPCReadDeadline] is used when 0
+	ReadDeadline int
 }
 
 // [ConfigModel.GetID]
@@ -18,12 +26,33 @@ func (c ConfigIPC) GetID() string {
 
 // [ConfigModel.Validate]
 func (c ConfigIPC) Validate() error {
+	if c.ReadDeadline < 0 {
+		return fmt.Errorf("ipc with ID [%s] has a negative read deadline [%d]", c.GetID(), c.ReadDeadline)
+	}
+	if c.ClientHeader != "" {
+		_, err := parseClientTemplate(c.GetID(), c.ClientHeader)
+		return err
+	}
 	return nil
 }
 
+// [clientHeaderProvider.clientHeader]
+func (c ConfigIPC) clientHeader() *template.Template {
+	if c.ClientHeader == "" {
+		return nil
+	}
+	header, _ := parseClientTemplate(c.GetID(), c.ClientHeader)
+	return header
+}
+
+// [clientAcceptor.acceptsClients]
+func (c ConfigIPC) acceptsClients() bool {
+	return true
+}
+
 // [ConfigModel.Reader]
 func (c ConfigIPC) Reader() (io.ReadCloser, error) {
-	return ipc.NewIPCReader(c.Channel)
+	return ipc.NewIPCReaderWithDeadline(c.Channel, time.Duration(c.ReadDeadline)*time.Millisecond)
 }
 
 // [ConfigModel.Writer]
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Kilemonn/flow/merge"
+)
+
+// The default [ConfigMerge.Window] in milliseconds
+const defaultMergeWindow = 100
+
+// Merges the data of each reader it is connected to without interleaving their partial lines or frames, see
+// [merge.Merger]. The merged data is read from the same ID.
+type ConfigMerge struct {
+	ID string
+	// Either "line" (the default) or "chunk"
+	Framing string
+	// Either "arrival" (the default) or "timestamp", which orders the data by when it was originally read. The data
+	// of a replay is ordered by when it was captured
+	Order string
+	// Prefixes each line or chunk with the ID of the reader it was read from, e.g. "[Serial1] "
+	Tag bool
+	// The milliseconds a partial line is held waiting for the rest of the line, and a frame is held waiting for the
+	// frames of other readers with an earlier timestamp. Defaults to 100 when 0
+	Window int
+
+	merger *merge.Merger
+}
+
+// [ConfigModel.GetID]
+func (c *ConfigMerge) GetID() string {
+	return c.ID
+}
+
+// [ConfigModel.Validate]
+func (c *ConfigMerge) Validate() error {
+	if c.Framing != "" && c.Framing != merge.FramingLine && c.Framing != merge.FramingChunk {
+		return fmt.Errorf("merge with ID [%s] has an invalid framing [%s], expected one of [%s, %s]", c.GetID(), c.Framing, merge.FramingLine, merge.FramingChunk)
+	}
+	if c.Order != "" && c.Order != merge.OrderArrival && c.Order != merge.OrderTimestamp {
+		return fmt.Errorf("merge with ID [%s] has an invalid order [%s], expected one of [%s, %s]", c.GetID(), c.Order, merge.OrderArrival, merge.OrderTimestamp)
+	}
+	if c.Window < 0 {
+		return fmt.Errorf("merge with ID [%s] has a negative window [%d]", c.GetID(), c.Window)
+	}
+	return nil
+}
+
+// Returns the merger shared by the reader and writer, creating it the first time this is called.
+func (c *ConfigMerge) open() *merge.Merger {
+	if c.merger == nil {
+		framing := c.Framing
+		if framing == "" {
+			framing = merge.FramingLine
+		}
+		window := c.Window
+		if window == 0 {
+			window = defaultMergeWindow
+		}
+		c.merger = merge.NewMerger(framing, c.Order, time.Duration(window)*time.Millisecond)
+		c.merger.Tag = c.Tag
+	}
+	return c.merger
+}
+
+// [ConfigModel.Reader]
+func (c *ConfigMerge) Reader() (io.ReadCloser, error) {
+	return c.open(), nil
+}
+
+// [ConfigModel.Writer]
+func (c *ConfigMerge) Writer() (io.WriteCloser, error) {
+	return c.open(), nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Kilemonn/flow/capture"
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that the lines of multiple files are merged whole and tagged with the file they were read from.
+func TestApplyConfig_Merge(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	output := filepath.Join(dir, "output.txt")
+	require.NoError(t, os.WriteFile(first, []byte("first line\nfirst partial"), 0666))
+	require.NoError(t, os.WriteFile(second, []byte("second line\n"), 0666))
+
+	config := Config{
+		Connections: []ConfigConnection{
+			{ReaderID: "first", WriterID: "merged"},
+			{ReaderID: "second", WriterID: "merged"},
+			{ReaderID: "merged", WriterID: "output"},
+		},
+		Nodes: ConfigNodes{
+			Files:  []ConfigFile{{ID: "first", Path: first}, {ID: "second", Path: second}, {ID: "output", Path: output}},
+			Merges: []ConfigMerge{{ID: "merged", Tag: true, Window: 10000}},
+		},
+		Settings: ConfigSettings{Timeout: 1},
+	}
+	require.NoError(t, config.Initialise())
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	require.NoError(t, applyConfig(ctx, cancelFunc, config.Conns, config.Settings))
+
+	read, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "[first] first line\n[second] second line\n", string(read))
+
+	// The partial line is merged on shutdown, the error of closing the nodes is ignored since stdin may have already
+	// been closed by another test
+	config.Shutdown(time.Second)
+	read, err = os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "[first] first line\n[second] second line\n[first] first partial", string(read))
+}
+
+// Ensure that the data of multiple replays is merged in the order that it was captured, rather than the order that
+// it is replayed in.
+func TestApplyConfig_MergeReplaysByTimestamp(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output.txt")
+	captures := map[string][]capture.Record{
+		"first.jsonl":  {{Offset: 0, Data: []byte("1\n")}, {Offset: 60 * time.Millisecond, Data: []byte("2\n")}},
+		"second.jsonl": {{Offset: 80 * time.Millisecond, Data: []byte("3\n")}},
+	}
+	for name, records := range captures {
+		file, err := os.Create(filepath.Join(dir, name))
+		require.NoError(t, err)
+		encoder := json.NewEncoder(file)
+		for _, record := range records {
+			require.NoError(t, encoder.Encode(record))
+		}
+		require.NoError(t, file.Close())
+	}
+
+	config := Config{
+		Connections: []ConfigConnection{
+			{ReaderID: "second", WriterID: "merged"},
+			{ReaderID: "first", WriterID: "merged"},
+			{ReaderID: "merged", WriterID: "output"},
+		},
+		Nodes: ConfigNodes{
+			Files: []ConfigFile{{ID: "output", Path: output}},
+			Replays: []ConfigReplay{
+				{ID: "first", Path: filepath.Join(dir, "first.jsonl")},
+				// The data captured at 80ms is replayed at 20ms
+				{ID: "second", Path: filepath.Join(dir, "second.jsonl"), Speed: 4},
+			},
+			Merges: []ConfigMerge{{ID: "merged", Order: "timestamp"}},
+		},
+		Settings: ConfigSettings{Timeout: 1},
+	}
+	require.NoError(t, config.Initialise())
+	defer config.Close()
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	require.NoError(t, applyConfig(ctx, cancelFunc, config.Conns, config.Settings))
+
+	read, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "1\n2\n3\n", string(read))
+}
+
+func TestConfigMerge_Validate(t *testing.T) {
+	require.NoError(t, (&ConfigMerge{ID: "merge"}).Validate())
+	require.NoError(t, (&ConfigMerge{ID: "merge", Framing: "chunk", Order: "timestamp", Window: 10}).Validate())
+	require.Error(t, (&ConfigMerge{ID: "merge", Framing: "frame"}).Validate())
+	require.Error(t, (&ConfigMerge{ID: "merge", Order: "random"}).Validate())
+	require.Error(t, (&ConfigMerge{ID: "merge", Window: -1}).Validate())
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Kilemonn/flow/monitor"
+)
+
+// Renders the data of each reader it is connected to as an annotated hex dump, see [monitor.Writer].
+// This can only be used as a writer.
+type ConfigMonitor struct {
+	ID string
+	// The file the dump is appended to, the dump is written to stderr when empty
+	Path string
+}
+
+// [ConfigModel.GetID]
+func (c ConfigMonitor) GetID() string {
+	return c.ID
+}
+
+// [ConfigModel.Validate]
+func (c ConfigMonitor) Validate() error {
+	return nil
+}
+
+// [ConfigModel.Reader]
+func (c ConfigMonitor) Reader() (io.ReadCloser, error) {
+	return nil, fmt.Errorf("monitor with ID [%s] can only be used as a writer", c.GetID())
+}
+
+// [ConfigModel.Writer]
+func (c ConfigMonitor) Writer() (io.WriteCloser, error) {
+	if c.Path == "" {
+		return monitor.NewStderrWriter(), nil
+	}
+
+	file, err := os.OpenFile(c.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file [%s] for monitor with ID [%s] with error: [%s]", c.Path, c.GetID(), err.Error())
+	}
+	return monitor.NewWriter(file), nil
+}
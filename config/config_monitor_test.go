@@ -0,0 +1,45 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that the data of a reader is dumped to the monitor's file with the ID of the reader.
+func TestMonitor(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.bin")
+	output := filepath.Join(dir, "monitor.txt")
+	require.NoError(t, os.WriteFile(input, []byte{0x00, 'o', 'k', 0xFF}, 0666))
+
+	config := Config{
+		Connections: []ConfigConnection{{ReaderID: "device", WriterID: "monitor"}},
+		Nodes: ConfigNodes{
+			Files:    []ConfigFile{{ID: "device", Path: input}},
+			Monitors: []ConfigMonitor{{ID: "monitor", Path: output}},
+		},
+		Settings: ConfigSettings{
+			ExitConditions: []ConfigExitCondition{{ReaderID: "device", EOF: true}},
+		},
+	}
+	require.NoError(t, config.Initialise())
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	require.NoError(t, applyConfig(ctx, cancelFunc, config.Conns, config.Settings))
+	config.Close()
+
+	read, err := os.ReadFile(output)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(read)), "\n")
+	require.Len(t, lines, 3)
+	require.True(t, strings.HasSuffix(lines[0], "[device] 4 bytes"))
+	require.Equal(t, "00000000  00 6f 6b ff                                       |.ok.|", lines[1])
+	require.Equal(t, "00000004", lines[2])
+
+	_, err = ConfigMonitor{ID: "monitor"}.Reader()
+	require.Error(t, err)
+}
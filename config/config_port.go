@@ -17,6 +17,8 @@ type ConfigPort struct {
 	// The resolved and connected port, in a scenario where we call validate
 	Port        *serial.CustomPort `json:"-"`
 	ReadTimeout int
+	// When set, the port is also exposed to remote clients through an RFC 2217 server
+	RFC2217 *ConfigRFC2217
 }
 
 // [ConfigModel.GetID]
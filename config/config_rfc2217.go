@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	"github.com/Kilemonn/flow/serial"
+)
+
+// The suffix appended to the ID of a [ConfigPort] to get the ID of its RFC 2217 server node
+const RFC2217IDSuffix = ".rfc2217"
+
+// Exposes a [ConfigPort] through a TCP listener speaking RFC 2217 (Telnet COM-Port-Control).
+type ConfigRFC2217 struct {
+	// The address and port to listen on
+	Address string
+	Port    uint16
+}
+
+func (c ConfigRFC2217) validate(id string) error {
+	if c.Port == 0 {
+		return fmt.Errorf("port with ID [%s] has an RFC 2217 server configured without a port", id)
+	}
+	return nil
+}
+
+// The model of the RFC 2217 server of a [ConfigPort], this is registered with the ID of the port suffixed with
+// [RFC2217IDSuffix] and is bridged with the port.
+type rfc2217Node struct {
+	port *ConfigPort
+}
+
+// [ConfigModel.GetID]
+func (c rfc2217Node) GetID() string {
+	return c.port.GetID() + RFC2217IDSuffix
+}
+
+// [ConfigModel.Validate]
+func (c rfc2217Node) Validate() error {
+	return c.port.RFC2217.validate(c.port.GetID())
+}
+
+// [clientAcceptor.acceptsClients]
+func (c rfc2217Node) acceptsClients() bool {
+	return true
+}
+
+// [ConfigModel.Reader], opens the port if it is not already open and starts listening for RFC 2217 clients.
+func (c rfc2217Node) Reader() (io.ReadCloser, error) {
+	_, err := c.port.Reader()
+	if err != nil {
+		return nil, err
+	}
+
+	address := net.JoinHostPort(c.port.RFC2217.Address, strconv.Itoa(int(c.port.RFC2217.Port)))
+	server, err := serial.NewRFC2217Server(c.port.Port.Port, c.port.Mode, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start RFC 2217 server on [%s] for port with ID [%s] with error: [%s]", address, c.port.GetID(), err.Error())
+	}
+	return server, nil
+}
+
+// [ConfigModel.Writer], the server is written to through its reader since it is always bridged with its port.
+func (c rfc2217Node) Writer() (io.WriteCloser, error) {
+	return nil, fmt.Errorf("RFC 2217 server with ID [%s] cannot be used as a writer", c.GetID())
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that a port with an RFC 2217 server registers the server node and is bridged with it.
+func TestConfigRFC2217_RegisteredAndBridged(t *testing.T) {
+	c := Config{
+		Connections: []ConfigConnection{
+			{
+				ReaderID: "stdin",
+				WriterID: "Serial1",
+			},
+		},
+		Nodes: ConfigNodes{
+			Ports: []ConfigPort{
+				{
+					ID:      "Serial1",
+					RFC2217: &ConfigRFC2217{Address: "127.0.0.1", Port: 2217},
+				},
+				{
+					ID: "Serial2",
+				},
+			},
+		},
+	}
+	require.NoError(t, c.componentIDsAreUnique())
+
+	server, exists := c.models["Serial1"+RFC2217IDSuffix]
+	require.True(t, exists)
+	require.NoError(t, server.Validate())
+	require.Same(t, c.models["Serial1"], server.(rfc2217Node).port)
+	_, exists = c.models["Serial2"+RFC2217IDSuffix]
+	require.False(t, exists)
+
+	require.Equal(t, []ConfigConnection{
+		{ReaderID: "stdin", WriterID: "Serial1"},
+		{ReaderID: "Serial1", WriterID: "Serial1" + RFC2217IDSuffix},
+		{ReaderID: "Serial1" + RFC2217IDSuffix, WriterID: "Serial1"},
+	}, c.allConnections())
+	require.True(t, c.isBridged("Serial1"+RFC2217IDSuffix))
+}
+
+func TestConfigRFC2217_Invalid(t *testing.T) {
+	c := Config{
+		Nodes: ConfigNodes{
+			Ports: []ConfigPort{
+				{
+					ID:      "Serial1",
+					RFC2217: &ConfigRFC2217{Address: "127.0.0.1"},
+				},
+			},
+		},
+	}
+	require.NoError(t, c.componentIDsAreUnique())
+	require.Error(t, c.models["Serial1"+RFC2217IDSuffix].Validate())
+
+	// The ID of the server clashes with another node
+	c.Nodes.Files = []ConfigFile{{ID: "Serial1" + RFC2217IDSuffix}}
+	require.Error(t, c.componentIDsAreUnique())
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Kilemonn/flow/script"
+)
+
+// Runs an expect-style script against the node it is bridged with, see [script.Runner]. The data the script sends is
+// read from it, and the replies of the node are written to it. The flow ends once every script has finished, with
+// [ExitCodeScriptFailure] if a script failed.
+type ConfigScript struct {
+	ID    string
+	Steps []script.Step
+	// The file the transcript of the script is appended to, the transcript is written to stderr when empty
+	Transcript string
+
+	runner *script.Runner
+}
+
+// Implemented by readers that end the flow once they finish, see [script.Runner].
+type finisher interface {
+	// Finished returns true once the reader has finished, along with the error that it failed with
+	Finished() (bool, error)
+}
+
+// [ConfigModel.GetID]
+func (c *ConfigScript) GetID() string {
+	return c.ID
+}
+
+// [ConfigModel.Validate]
+func (c *ConfigScript) Validate() error {
+	if len(c.Steps) == 0 {
+		return fmt.Errorf("script with ID [%s] has no steps", c.GetID())
+	}
+	err := script.Validate(c.Steps)
+	if err != nil {
+		return fmt.Errorf("script with ID [%s] is invalid with error: [%s]", c.GetID(), err.Error())
+	}
+	return nil
+}
+
+// Returns the runner shared by the reader and writer, creating it the first time this is called.
+func (c *ConfigScript) open() (*script.Runner, error) {
+	if c.runner != nil {
+		return c.runner, nil
+	}
+
+	var err error
+	if c.Transcript == "" {
+		c.runner, err = script.NewStderrRunner(c.Steps)
+	} else {
+		var file *os.File
+		file, err = os.OpenFile(c.Transcript, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open transcript [%s] for script with ID [%s] with error: [%s]", c.Transcript, c.GetID(), err.Error())
+		}
+		c.runner, err = script.NewRunner(c.Steps, file)
+		if err != nil {
+			file.Close()
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create script with ID [%s] with error: [%s]", c.GetID(), err.Error())
+	}
+	return c.runner, nil
+}
+
+// [ConfigModel.Reader]
+func (c *ConfigScript) Reader() (io.ReadCloser, error) {
+	return c.open()
+}
+
+// [ConfigModel.Writer]
+func (c *ConfigScript) Writer() (io.WriteCloser, error) {
+	return c.open()
+}
+
+// Validate that each script is used as a reader, since a script only runs once it is read from.
+func (c Config) validateScripts() error {
+	for _, node := range c.Nodes.Scripts {
+		found := false
+		for _, connection := range c.allConnections() {
+			if connection.ReaderID == node.GetID() {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("script with ID [%s] is not used as a reader in any connection", node.GetID())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Kilemonn/flow/script"
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that a script bridged with a TCP socket sends its commands to the client, and the flow ends once the script
+// passes.
+func TestApplyConfig_ScriptPasses(t *testing.T) {
+	socketPort := uint16(64626)
+	transcript := filepath.Join(t.TempDir(), "transcript.txt")
+	config := Config{
+		Connections: []ConfigConnection{{ReaderID: "script", WriterID: "device", Bridge: true}},
+		Nodes: ConfigNodes{
+			Sockets: []ConfigSocket{{ID: "device", Protocol: "tcp", Address: "127.0.0.1", Port: socketPort}},
+			Scripts: []ConfigScript{{
+				ID: "script",
+				Steps: []script.Step{
+					{Send: "AT+CGSN\r\n"},
+					{Expect: `(\d+)\r\n`, Set: "imei"},
+					{Send: "AT+ID={{.imei}}\r\n"},
+					{Expect: "OK"},
+				},
+				Transcript: transcript,
+			}},
+		},
+	}
+	require.NoError(t, config.Initialise())
+	defer config.Close()
+
+	device, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", socketPort))
+	require.NoError(t, err)
+	defer device.Close()
+	// Give the socket time to accept the device
+	time.Sleep(100 * time.Millisecond)
+
+	result := make(chan error, 1)
+	go func() {
+		ctx, cancelFunc := context.WithCancel(context.Background())
+		result <- applyConfig(ctx, cancelFunc, config.Conns, config.Settings)
+	}()
+
+	require.NoError(t, device.SetReadDeadline(time.Now().Add(5*time.Second)))
+	commands := bufio.NewReader(device)
+	command, err := commands.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "AT+CGSN\r\n", command)
+	_, err = device.Write([]byte("490154203237518\r\n"))
+	require.NoError(t, err)
+
+	command, err = commands.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "AT+ID=490154203237518\r\n", command)
+	_, err = device.Write([]byte("OK\r\n"))
+	require.NoError(t, err)
+
+	select {
+	case err = <-result:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the flow did not end once the script passed")
+	}
+	read, err := os.ReadFile(transcript)
+	require.NoError(t, err)
+	require.Contains(t, string(read), `recv "490154203237518\r\n"`)
+	require.Contains(t, string(read), " pass\n")
+}
+
+// Ensure that the flow ends with the script failure exit code once an expect times out.
+func TestApplyConfig_ScriptFails(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output.txt")
+	config := Config{
+		Connections: []ConfigConnection{{ReaderID: "script", WriterID: "output"}},
+		Nodes: ConfigNodes{
+			Files:   []ConfigFile{{ID: "output", Path: output}},
+			Scripts: []ConfigScript{{ID: "script", Steps: []script.Step{{Send: "AT\r"}, {Expect: "OK", Timeout: 50}}}},
+		},
+		// The script keeps the flow from timing out while it is waiting
+		Settings: ConfigSettings{Timeout: 1},
+	}
+	require.NoError(t, config.Initialise())
+	defer config.Close()
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	err := applyConfig(ctx, cancelFunc, config.Conns, config.Settings)
+	require.Equal(t, ExitCodeScriptFailure, ExitCode(err))
+	require.ErrorContains(t, err, "timed out")
+
+	read, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "AT\r", string(read))
+}
+
+func TestConfigScript_Validate(t *testing.T) {
+	require.NoError(t, (&ConfigScript{ID: "script", Steps: []script.Step{{Send: "AT"}}}).Validate())
+	require.Error(t, (&ConfigScript{ID: "script"}).Validate())
+	require.Error(t, (&ConfigScript{ID: "script", Steps: []script.Step{{Send: "AT", Wait: 10}}}).Validate())
+
+	// The script must be read from to run
+	config := Config{
+		Connections: []ConfigConnection{{ReaderID: StdIn, WriterID: "script"}},
+		Nodes:       ConfigNodes{Scripts: []ConfigScript{{ID: "script", Steps: []script.Step{{Expect: "OK"}}}}},
+	}
+	require.ErrorContains(t, config.Initialise(), "not used as a reader")
+}
@@ -1,6 +1,66 @@
 package config
 
+import "time"
+
+const (
+	// The default maximum wait between polls of the readers while no data is flowing, see
+	// [ConfigSettings.MaxPollInterval]
+	DefaultMaxPollInterval = 50 * time.Millisecond
+	// The initial wait between polls once no data is flowing
+	minPollInterval = time.Millisecond
+	// The default maximum time spent reading the remaining data once the flow is stopped, see
+	// [ConfigSettings.DrainTimeout]
+	DefaultDrainTimeout = 5 * time.Second
+)
+
 type ConfigSettings struct {
 	// Timeout in seconds
 	Timeout int
+	// The maximum milliseconds to wait between polls of the readers while no data is flowing. The wait starts at 1ms
+	// and doubles after each poll that reads no data, up to this value. [DefaultMaxPollInterval] is used when 0.
+	MaxPollInterval int
+	// The maximum seconds the flow runs for regardless of any data flowing, no limit when 0
+	MaxRuntime int
+	// Conditions on the data read that stop the flow once any of them are met
+	ExitConditions []ConfigExitCondition `yaml:",omitempty"`
+	// The maximum seconds spent reading the data still available from the readers once the flow is stopped, before
+	// the nodes are closed. [DefaultDrainTimeout] is used when 0 and no data is drained when negative.
+	DrainTimeout int
+}
+
+// Returns the configured [ConfigSettings.MaxPollInterval], or [DefaultMaxPollInterval] if it is not set.
+func (s ConfigSettings) maxPollInterval() time.Duration {
+	if s.MaxPollInterval <= 0 {
+		return DefaultMaxPollInterval
+	}
+	return time.Duration(s.MaxPollInterval) * time.Millisecond
+}
+
+// Returns the configured [ConfigSettings.DrainTimeout], or [DefaultDrainTimeout] if it is not set.
+func (s ConfigSettings) drainTimeout() time.Duration {
+	if s.DrainTimeout < 0 {
+		return 0
+	}
+	if s.DrainTimeout == 0 {
+		return DefaultDrainTimeout
+	}
+	return time.Duration(s.DrainTimeout) * time.Second
+}
+
+// An exponential backoff used to wait between polls while no data is flowing, so an idle configuration uses little
+// CPU while data is still forwarded immediately once it is flowing.
+type pollBackoff struct {
+	max     time.Duration
+	current time.Duration
+}
+
+// Resets the wait, this should be called once data is flowing.
+func (b *pollBackoff) reset() {
+	b.current = 0
+}
+
+// Returns the next wait, doubling the previous wait up to the maximum.
+func (b *pollBackoff) next() time.Duration {
+	b.current = min(max(b.current*2, minPollInterval), b.max)
+	return b.current
 }
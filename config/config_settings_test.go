@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -17,3 +18,21 @@ func TestMapToArrayExistsOrNot(t *testing.T) {
 	require.Equal(t, []string(nil), v)
 	require.Equal(t, 0, len(v))
 }
+
+func TestConfigSettings_MaxPollInterval(t *testing.T) {
+	require.Equal(t, DefaultMaxPollInterval, ConfigSettings{}.maxPollInterval())
+	require.Equal(t, 200*time.Millisecond, ConfigSettings{MaxPollInterval: 200}.maxPollInterval())
+}
+
+// Ensure that the wait doubles up to the maximum, and starts again from the minimum once reset.
+func TestPollBackoff(t *testing.T) {
+	backoff := pollBackoff{max: 5 * time.Millisecond}
+	require.Equal(t, time.Millisecond, backoff.next())
+	require.Equal(t, 2*time.Millisecond, backoff.next())
+	require.Equal(t, 4*time.Millisecond, backoff.next())
+	require.Equal(t, 5*time.Millisecond, backoff.next())
+	require.Equal(t, 5*time.Millisecond, backoff.next())
+
+	backoff.reset()
+	require.Equal(t, time.Millisecond, backoff.next())
+}
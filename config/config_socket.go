@@ -1,16 +1,55 @@
 package config
 
 import (
+	"fmt"
 	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/Kilemonn/flow/socket"
 )
 
+var socketProtocols = []string{"tcp", "udp", "unix", "unixgram"}
+
 type ConfigSocket struct {
 	ID       string
 	Protocol string
 	Port     uint16
-	Address  string
+	// The address to listen on or send to, for "unix" and "unixgram" sockets this is the socket file path
+	Address string
+	// The network interface name used to join (reader) or send to (writer) a multicast group
+	Interface string
+	// The multicast TTL used by multicast writers, the system default is used when 0
+	TTL int
+	// Stops multicast writers from looping sent packets back to the local host
+	DisableLoopback bool
+	// The octal file permissions (e.g. "0660") of the socket file created by listening "unix" and "unixgram" sockets
+	Permissions string
+	// Optional TLS settings, only supported by the "tcp" protocol
+	TLS *ConfigTLS
+	// The local address that outgoing (writer) connections are sent from
+	BindAddress string
+	// Set SO_REUSEADDR and SO_REUSEPORT on listening (reader) sockets
+	ReuseAddr bool
+	ReusePort bool
+	// A template prefixed to each chunk of data received from a client of a listening "tcp" or "unix" socket, e.g.
+	// "[{{.RemoteAddr}}] ". See [clientinfo.Info] for the available fields.
+	ClientHeader string
+	// The maximum amount of concurrent clients of a listening "tcp" or "unix" socket, no limit when 0
+	MaxClients int
+	// The seconds a client can go without sending data, or be connected for, before it is disconnected. No limit when 0
+	ClientIdleTimeout int
+	ClientLifetime    int
+	// CIDR prefixes or IP addresses of the clients that are allowed or denied by a listening "tcp" socket
+	Allow []string `yaml:",omitempty"`
+	Deny  []string `yaml:",omitempty"`
+	// The milliseconds each read (and accept) attempt of a reader waits for data, [socket.SocketReadDeadline] is used
+	// when 0
+	ReadDeadline int
 }
 
 // [ConfigModel.GetID]
@@ -20,15 +59,129 @@ func (c ConfigSocket) GetID() string {
 
 // [ConfigModel.Validate]
 func (c ConfigSocket) Validate() error {
-	return nil
+	if !slices.Contains(socketProtocols, strings.ToLower(c.Protocol)) {
+		return fmt.Errorf("socket with ID [%s] has invalid protocol [%s], expected one of %v", c.GetID(), c.Protocol, socketProtocols)
+	}
+
+	if c.TLS != nil {
+		if strings.ToLower(c.Protocol) != "tcp" {
+			return fmt.Errorf("socket with ID [%s] has TLS configured, which is only supported by the \"tcp\" protocol", c.GetID())
+		}
+		err := c.TLS.validate(c.GetID())
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.ClientHeader != "" {
+		_, err := parseClientTemplate(c.GetID(), c.ClientHeader)
+		if err != nil {
+			return err
+		}
+	}
+
+	protocol := strings.ToLower(c.Protocol)
+	if (c.MaxClients != 0 || c.ClientIdleTimeout != 0 || c.ClientLifetime != 0) && protocol != "tcp" && protocol != "unix" {
+		return fmt.Errorf("socket with ID [%s] has client limits configured, which are only supported by the \"tcp\" and \"unix\" protocols", c.GetID())
+	}
+	if c.MaxClients < 0 || c.ClientIdleTimeout < 0 || c.ClientLifetime < 0 {
+		return fmt.Errorf("socket with ID [%s] has a negative client limit configured", c.GetID())
+	}
+	if c.ReadDeadline < 0 {
+		return fmt.Errorf("socket with ID [%s] has a negative read deadline [%d]", c.GetID(), c.ReadDeadline)
+	}
+	if (len(c.Allow) > 0 || len(c.Deny) > 0) && protocol != "tcp" {
+		return fmt.Errorf("socket with ID [%s] has an allow or deny list configured, which is only supported by the \"tcp\" protocol", c.GetID())
+	}
+
+	_, err := c.options()
+	return err
+}
+
+// [clientHeaderProvider.clientHeader]
+func (c ConfigSocket) clientHeader() *template.Template {
+	if c.ClientHeader == "" {
+		return nil
+	}
+	header, _ := parseClientTemplate(c.GetID(), c.ClientHeader)
+	return header
+}
+
+// [clientAcceptor.acceptsClients], "tcp" and "unix" socket readers accept clients.
+func (c ConfigSocket) acceptsClients() bool {
+	protocol := strings.ToLower(c.Protocol)
+	return protocol == "tcp" || protocol == "unix"
 }
 
 // [ConfigModel.Reader]
 func (c ConfigSocket) Reader() (io.ReadCloser, error) {
-	return socket.CreateSocketReader(c.Protocol, c.Address, c.Port)
+	opts, err := c.options()
+	if err != nil {
+		return nil, err
+	}
+	if c.TLS != nil {
+		opts.TLS, err = c.TLS.serverConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create TLS configuration for socket with ID [%s] with error: [%s]", c.GetID(), err.Error())
+		}
+	}
+	return socket.CreateSocketReaderWithOptions(c.Protocol, c.Address, c.Port, opts)
 }
 
 // [ConfigModel.Writer]
 func (c ConfigSocket) Writer() (io.WriteCloser, error) {
-	return socket.CreateSocketWriter(c.Protocol, c.Address, c.Port)
+	opts, err := c.options()
+	if err != nil {
+		return nil, err
+	}
+	if c.TLS != nil {
+		opts.TLS, err = c.TLS.clientConfig(c.Address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create TLS configuration for socket with ID [%s] with error: [%s]", c.GetID(), err.Error())
+		}
+	}
+	return socket.CreateSocketWriterWithOptions(c.Protocol, c.Address, c.Port, opts)
+}
+
+func (c ConfigSocket) options() (socket.Options, error) {
+	opts := socket.Options{
+		Interface:       c.Interface,
+		TTL:             c.TTL,
+		DisableLoopback: c.DisableLoopback,
+		BindAddress:     c.BindAddress,
+		ReuseAddr:       c.ReuseAddr,
+		ReusePort:       c.ReusePort,
+		ReadDeadline:    time.Duration(c.ReadDeadline) * time.Millisecond,
+		ClientLimits: socket.ClientLimits{
+			ID:          c.GetID(),
+			MaxClients:  c.MaxClients,
+			IdleTimeout: time.Duration(c.ClientIdleTimeout) * time.Second,
+			Lifetime:    time.Duration(c.ClientLifetime) * time.Second,
+		},
+	}
+
+	for _, allow := range c.Allow {
+		prefix, err := socket.ParsePrefix(allow)
+		if err != nil {
+			return opts, fmt.Errorf("socket with ID [%s] has an invalid allow entry [%s] with error: [%s]", c.GetID(), allow, err.Error())
+		}
+		opts.ClientLimits.Allow = append(opts.ClientLimits.Allow, prefix)
+	}
+	for _, deny := range c.Deny {
+		prefix, err := socket.ParsePrefix(deny)
+		if err != nil {
+			return opts, fmt.Errorf("socket with ID [%s] has an invalid deny entry [%s] with error: [%s]", c.GetID(), deny, err.Error())
+		}
+		opts.ClientLimits.Deny = append(opts.ClientLimits.Deny, prefix)
+	}
+
+	if c.Permissions != "" {
+		perm, err := strconv.ParseUint(c.Permissions, 8, 32)
+		if err != nil || perm > uint64(os.ModePerm) {
+			return opts, fmt.Errorf("socket with ID [%s] has invalid permissions [%s], expected an octal value such as \"0660\"", c.GetID(), c.Permissions)
+		}
+		opts.Permissions = os.FileMode(perm)
+	}
+
+	return opts, nil
 }
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/Kilemonn/flow/socket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigSocket_Validate(t *testing.T) {
+	valid := []ConfigSocket{
+		{ID: "tcp", Protocol: "TCP"},
+		{ID: "udp", Protocol: "udp"},
+		{ID: "unix", Protocol: "unix", Permissions: "0660"},
+		{ID: "unixgram", Protocol: "unixgram", Permissions: "600"},
+		{ID: "limits", Protocol: "tcp", MaxClients: 2, ClientIdleTimeout: 30, ClientLifetime: 3600, Allow: []string{"10.0.0.0/8", "::1"}, Deny: []string{"10.0.0.5"}},
+		{ID: "unix-limits", Protocol: "unix", MaxClients: 1},
+	}
+	for _, s := range valid {
+		require.NoError(t, s.Validate())
+	}
+
+	invalid := []ConfigSocket{
+		{ID: "protocol", Protocol: "neither"},
+		{ID: "permissions", Protocol: "unix", Permissions: "0999"},
+		{ID: "permissions-range", Protocol: "unix", Permissions: "17777"},
+		{ID: "limits-udp", Protocol: "udp", MaxClients: 1},
+		{ID: "limits-negative", Protocol: "tcp", ClientIdleTimeout: -1},
+		{ID: "allow-unix", Protocol: "unix", Allow: []string{"10.0.0.0/8"}},
+		{ID: "allow-invalid", Protocol: "tcp", Allow: []string{"10.0.0.0/33"}},
+		{ID: "deny-invalid", Protocol: "tcp", Deny: []string{"host"}},
+	}
+	for _, s := range invalid {
+		require.Error(t, s.Validate())
+	}
+}
+
+// Ensure that the client limits are converted to the socket options.
+func TestConfigSocket_ClientLimits(t *testing.T) {
+	s := ConfigSocket{ID: "limits", Protocol: "tcp", MaxClients: 2, ClientIdleTimeout: 30, ClientLifetime: 60, Allow: []string{"10.1.0.0/16"}, Deny: []string{"10.1.0.5"}}
+	opts, err := s.options()
+	require.NoError(t, err)
+	require.Equal(t, socket.ClientLimits{
+		ID:          "limits",
+		MaxClients:  2,
+		IdleTimeout: 30 * time.Second,
+		Lifetime:    time.Minute,
+		Allow:       []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")},
+		Deny:        []netip.Prefix{netip.MustParsePrefix("10.1.0.5/32")},
+	}, opts.ClientLimits)
+}
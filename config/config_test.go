@@ -2,13 +2,18 @@ package config
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"net"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/Kilemonn/flow/ipc"
 	"github.com/Kilemonn/flow/testutil"
+	ipcClient "github.com/Kilemonn/go-ipc/client"
 	"github.com/stretchr/testify/require"
 	goSerial "go.bug.st/serial"
 )
@@ -583,3 +588,195 @@ func TestApplyConfig_WithMultipleIPC(t *testing.T) {
 		})
 	})
 }
+
+// Ensure that the data of each TCP client is prefixed with its client header, and written to its own file when the
+// writer has a templated path.
+func TestApplyConfig_WithTCPClientHeaderAndPerClientFiles(t *testing.T) {
+	content := "TestApplyConfig_WithTCPClientHeaderAndPerClientFiles"
+	socketPort := uint16(64622)
+	dir := t.TempDir()
+	testutil.WithBytesInStdIn(t, []byte(content), func() {
+		testutil.WithTempFile(t, func(outputFile string) {
+			fileConfig := []ConfigFile{
+				{
+					ID:   "outputfile",
+					Path: outputFile,
+				},
+				{
+					ID:   "clientfiles",
+					Path: filepath.Join(dir, "client-{{.ID}}.txt"),
+				},
+			}
+
+			socketConfig := []ConfigSocket{
+				{
+					ID:       "sender-socket",
+					Protocol: "tcp",
+					Port:     socketPort,
+					Address:  "127.0.0.1",
+				},
+				{
+					ID:       "sender-socket2",
+					Protocol: "tcp",
+					Port:     socketPort,
+					Address:  "127.0.0.1",
+				},
+				{
+					ID:           "recv-socket",
+					Protocol:     "tcp",
+					Port:         socketPort,
+					Address:      "127.0.0.1",
+					ClientHeader: "[{{.ID}}] ",
+				},
+			}
+
+			connections := []ConfigConnection{
+				{
+					ReaderID: "stdin",
+					WriterID: "sender-socket",
+				},
+				{
+					ReaderID: "stdin",
+					WriterID: "sender-socket2",
+				},
+				{
+					ReaderID: "recv-socket",
+					WriterID: "outputfile",
+				},
+				{
+					ReaderID: "recv-socket",
+					WriterID: "clientfiles",
+				},
+			}
+
+			config := Config{
+				Connections: connections,
+				Nodes: ConfigNodes{
+					Files:   fileConfig,
+					Sockets: socketConfig,
+				},
+			}
+
+			err := config.Initialise()
+			require.NoError(t, err)
+
+			settings := ConfigSettings{Timeout: 1}
+			testutil.TakesAtleast(t, time.Duration(settings.Timeout*int(time.Second)), func() {
+				ctx, cancelFunc := context.WithCancel(context.Background())
+				defer config.Close()
+				go applyConfig(ctx, cancelFunc, config.Conns, settings)
+				<-ctx.Done()
+			})
+
+			writtenToFile, err := os.ReadFile(outputFile)
+			require.NoError(t, err)
+			require.Len(t, writtenToFile, 2*len("[1] "+content))
+			require.Contains(t, string(writtenToFile), "[1] "+content)
+			require.Contains(t, string(writtenToFile), "[2] "+content)
+
+			for _, name := range []string{"client-1.txt", "client-2.txt"} {
+				writtenToFile, err = os.ReadFile(filepath.Join(dir, name))
+				require.NoError(t, err)
+				require.Equal(t, content, string(writtenToFile))
+			}
+		})
+	})
+}
+
+// Ensure that a bridge between a TCP reader and an IPC reader forwards data in both directions, replying only to the
+// client that sent the request.
+func TestApplyConfig_BridgeTCPAndIPC(t *testing.T) {
+	socketPort := uint16(64623)
+	channel := "TestApplyConfig_BridgeTCPAndIPC"
+	config := Config{
+		Connections: []ConfigConnection{
+			{
+				ReaderID: "tcp",
+				WriterID: "ipc",
+				Bridge:   true,
+			},
+		},
+		Nodes: ConfigNodes{
+			Sockets: []ConfigSocket{
+				{
+					ID:       "tcp",
+					Protocol: "tcp",
+					Port:     socketPort,
+					Address:  "127.0.0.1",
+				},
+			},
+			Ipcs: []ConfigIPC{
+				{
+					ID:      "ipc",
+					Channel: channel,
+				},
+			},
+		},
+	}
+
+	err := config.Initialise()
+	require.NoError(t, err)
+	require.Len(t, config.Conns, 2)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		applyConfig(ctx, cancelFunc, config.Conns, ConfigSettings{})
+		close(done)
+	}()
+	defer config.Close()
+	defer func() {
+		cancelFunc()
+		<-done
+	}()
+
+	tcpClient, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", socketPort))
+	require.NoError(t, err)
+	defer tcpClient.Close()
+	otherClient, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", socketPort))
+	require.NoError(t, err)
+	defer otherClient.Close()
+	ipcWriter, err := ipc.NewIPCWriter(channel)
+	require.NoError(t, err)
+	defer ipcWriter.Close()
+	// Give the readers time to accept both clients
+	time.Sleep(100 * time.Millisecond)
+
+	request := "request"
+	_, err = tcpClient.Write([]byte(request))
+	require.NoError(t, err)
+	b := make([]byte, len(request))
+	require.NoError(t, ipcWriter.(ipcClient.IPCClient).Conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = io.ReadFull(ipcWriter.(ipcClient.IPCClient).Conn, b)
+	require.NoError(t, err)
+	require.Equal(t, request, string(b))
+
+	reply := "reply"
+	_, err = ipcWriter.Write([]byte(reply))
+	require.NoError(t, err)
+	b = make([]byte, len(reply))
+	require.NoError(t, tcpClient.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = io.ReadFull(tcpClient, b)
+	require.NoError(t, err)
+	require.Equal(t, reply, string(b))
+
+	require.NoError(t, otherClient.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, err = otherClient.Read(b)
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+}
+
+func TestConfig_BridgeWithStdio(t *testing.T) {
+	config := Config{
+		Connections: []ConfigConnection{
+			{
+				ReaderID: StdIn,
+				WriterID: "file",
+				Bridge:   true,
+			},
+		},
+		Nodes: ConfigNodes{
+			Files: []ConfigFile{{ID: "file", Path: filepath.Join(t.TempDir(), "file.txt")}},
+		},
+	}
+	require.Error(t, config.Initialise())
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/Kilemonn/flow/socket"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLS settings for TCP sockets. When used by a reader, the socket accepts TLS clients and when used by a writer the
+// socket will connect to a TLS server.
+type ConfigTLS struct {
+	// The PEM encoded certificate and key files. Required for readers, optional for writers where they are presented
+	// as the client certificate (mutual TLS).
+	CertFile string
+	KeyFile  string
+	// The PEM encoded CA bundle used to verify the server certificate (writer) or client certificates (reader).
+	// When not provided for writers the system CA pool is used.
+	CAFile string
+	// Readers only, requires clients to present a certificate that is signed by the CAFile
+	VerifyClient bool
+	// Writers only, the expected name of the server certificate. The socket address is used when not provided.
+	ServerName string
+	// The minimum TLS version to accept, one of "1.0", "1.1", "1.2" or "1.3". Defaults to "1.2".
+	MinVersion string
+	// Writers only, skips all verification of the server certificate. This should only be used for testing.
+	InsecureSkipVerify bool
+}
+
+// Check that the configured files can be loaded and the settings are consistent.
+func (c ConfigTLS) validate(id string) error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("socket with ID [%s] must provide both a TLS certfile and keyfile", id)
+	}
+	if c.VerifyClient && c.CAFile == "" {
+		return fmt.Errorf("socket with ID [%s] requires a TLS cafile to verify clients", id)
+	}
+
+	_, err := c.baseConfig()
+	if err != nil {
+		return fmt.Errorf("socket with ID [%s] has invalid TLS configuration: %s", id, err.Error())
+	}
+	return nil
+}
+
+// The settings shared by both server and client configurations.
+func (c ConfigTLS) baseConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.MinVersion != "" {
+		version, ok := tlsVersions[c.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("invalid minversion [%s]", c.MinVersion)
+		}
+		config.MinVersion = version
+	}
+
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+func (c ConfigTLS) certPool() (*x509.CertPool, error) {
+	data, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in cafile [%s]", c.CAFile)
+	}
+	return pool, nil
+}
+
+// The [tls.Config] used by a reader to accept TLS clients.
+func (c ConfigTLS) serverConfig() (*tls.Config, error) {
+	if c.CertFile == "" {
+		return nil, fmt.Errorf("a TLS certfile and keyfile are required to accept TLS connections")
+	}
+
+	config, err := c.baseConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.VerifyClient {
+		config.ClientCAs, err = c.certPool()
+		if err != nil {
+			return nil, err
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// The [tls.Config] used by a writer to connect to a TLS server at the provided address.
+func (c ConfigTLS) clientConfig(address string) (*tls.Config, error) {
+	config, err := c.baseConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.CAFile != "" {
+		config.RootCAs, err = c.certPool()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	config.ServerName = c.ServerName
+	if config.ServerName == "" {
+		// The certificate of an IPv6 address is verified against the address without its brackets
+		config.ServerName = socket.TrimBrackets(address)
+	}
+	config.InsecureSkipVerify = c.InsecureSkipVerify
+	return config, nil
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Kilemonn/flow/socket"
+	"github.com/Kilemonn/flow/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that a mutual TLS reader and writer can be created from the configuration and data can be sent between them,
+// including when the writer verifies the certificate against a bracketed IPv6 address.
+func TestConfigTLS_ReaderAndWriter(t *testing.T) {
+	for _, address := range []string{"127.0.0.1", "[::1]"} {
+		t.Run(address, func(t *testing.T) {
+			testConfigTLSReaderAndWriter(t, address)
+		})
+	}
+}
+
+func testConfigTLSReaderAndWriter(t *testing.T, address string) {
+	certs := testutil.CreateTestCertificates(t)
+	readerConfig := ConfigSocket{
+		ID:       "tls-reader",
+		Protocol: "tcp",
+		Address:  address,
+		TLS: &ConfigTLS{
+			CertFile:     certs.ServerCertFile,
+			KeyFile:      certs.ServerKeyFile,
+			CAFile:       certs.CAFile,
+			VerifyClient: true,
+			MinVersion:   "1.3",
+		},
+	}
+	require.NoError(t, readerConfig.Validate())
+	reader, err := readerConfig.Reader()
+	require.NoError(t, err)
+	defer reader.Close()
+
+	writerConfig := ConfigSocket{
+		ID:       "tls-writer",
+		Protocol: "tcp",
+		Address:  address,
+		Port:     testutil.GetTCPPort(reader.(*socket.TCPTimeoutReader).Listener),
+		TLS: &ConfigTLS{
+			CertFile: certs.ClientCertFile,
+			KeyFile:  certs.ClientKeyFile,
+			CAFile:   certs.CAFile,
+		},
+	}
+	require.NoError(t, writerConfig.Validate())
+	writer, err := writerConfig.Writer()
+	require.NoError(t, err)
+	defer writer.Close()
+
+	content := "TestConfigTLS_ReaderAndWriter"
+	_, err = writer.Write([]byte(content))
+	require.NoError(t, err)
+
+	b := make([]byte, len(content))
+	require.Eventually(t, func() bool {
+		n, err := reader.Read(b)
+		return err == nil && n == len(content)
+	}, 2*time.Second, socket.SocketReadDeadline)
+	require.Equal(t, content, string(b))
+}
+
+func TestConfigTLS_Validate(t *testing.T) {
+	certs := testutil.CreateTestCertificates(t)
+	invalid := []ConfigSocket{
+		{ID: "udp", Protocol: "udp", TLS: &ConfigTLS{}},
+		{ID: "cert-without-key", Protocol: "tcp", TLS: &ConfigTLS{CertFile: certs.ServerCertFile}},
+		{ID: "verify-without-ca", Protocol: "tcp", TLS: &ConfigTLS{CertFile: certs.ServerCertFile, KeyFile: certs.ServerKeyFile, VerifyClient: true}},
+		{ID: "missing-files", Protocol: "tcp", TLS: &ConfigTLS{CertFile: "missing.pem", KeyFile: "missing-key.pem"}},
+		{ID: "min-version", Protocol: "tcp", TLS: &ConfigTLS{MinVersion: "2.0"}},
+	}
+	for _, s := range invalid {
+		require.Error(t, s.Validate(), s.ID)
+	}
+
+	// A reader must have a certificate to serve
+	_, err := ConfigSocket{ID: "no-cert", Protocol: "tcp", Address: "127.0.0.1", TLS: &ConfigTLS{}}.Reader()
+	require.Error(t, err)
+}
+
+// Ensure that the server name of a writer defaults to its address without the brackets of an IPv6 address.
+func TestConfigTLS_ClientServerName(t *testing.T) {
+	for address, expected := range map[string]string{"[::1]": "::1", "::1": "::1", "localhost": "localhost"} {
+		config, err := ConfigTLS{}.clientConfig(address)
+		require.NoError(t, err)
+		require.Equal(t, expected, config.ServerName)
+	}
+	config, err := ConfigTLS{ServerName: "flow.example"}.clientConfig("[::1]")
+	require.NoError(t, err)
+	require.Equal(t, "flow.example", config.ServerName)
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"io"
+
+	"github.com/Kilemonn/flow/clientinfo"
+	"github.com/Kilemonn/flow/socket"
+)
+
+// The client or datagram that data was written with, so that it can be forwarded along with the data. Both are nil
+// for data written with [io.Writer.Write].
+type dataSource struct {
+	client   *clientinfo.Info
+	datagram *socket.Datagram
+}
+
+// Writes the data to the writer along with the client or datagram of the source, if the writer supports it.
+func (s dataSource) write(w io.Writer, b []byte) (int, error) {
+	switch {
+	case s.datagram != nil:
+		d := *s.datagram
+		d.Data = b
+		return socket.WriteDatagram(w, d)
+	case s.client != nil:
+		return clientinfo.Write(w, *s.client, b)
+	default:
+		return w.Write(b)
+	}
+}
+
+// Writes the data written to it to the writer along with its current source. This is the output of the stages that
+// don't know about clients or datagrams, see [clientStages].
+type sourceWriter struct {
+	writer io.Writer
+	source dataSource
+}
+
+// [io.Writer.Write]
+func (w *sourceWriter) Write(b []byte) (int, error) {
+	return w.source.write(w.writer, b)
+}
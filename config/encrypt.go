@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Kilemonn/flow/encryption"
+)
+
+// Encrypts or decrypts the data of a [ConfigConnection] with a symmetric key, see [encryption.EncryptWriter].
+type ConfigEncryption struct {
+	// The encryption algorithm, either "aes-gcm" or "chacha20-poly1305"
+	Algorithm string
+	// The path of the file containing the key, either as hex or the raw key
+	KeyFile string
+	// The encrypters of the side of the bridge this belongs to, whose frames are rejected when decrypting
+	senders *encryption.Senders
+}
+
+// Checks the algorithm, and that the key file contains a valid key for it.
+func (c ConfigEncryption) validate(connection ConfigConnection) error {
+	err := encryption.Validate(c.Algorithm)
+	if err == nil {
+		var key []byte
+		key, err = encryption.ReadKeyFile(c.KeyFile)
+		if err == nil {
+			_, err = encryption.NewAEAD(c.Algorithm, key)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("connection from [%s] to [%s] has an invalid encryption with error: [%s]", connection.ReaderID, connection.WriterID, err.Error())
+	}
+	return nil
+}
+
+// Wraps the writer in a stage that encrypts the data written to it.
+func (c ConfigEncryption) encryptWriter(writer io.Writer) (codecStage, error) {
+	key, err := encryption.ReadKeyFile(c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return encryption.NewEncryptWriter(c.Algorithm, key, c.senders, writer)
+}
+
+// Wraps the writer in a stage that decrypts the frames written to it.
+func (c ConfigEncryption) decryptWriter(writer io.Writer) (codecStage, error) {
+	key, err := encryption.ReadKeyFile(c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return encryption.NewDecryptWriter(c.Algorithm, key, c.senders, writer)
+}
+
+// Gives the encryptions of both directions of each bridge the same [encryption.Senders], so that the frames encrypted
+// by one direction of a bridge are rejected if they are sent back to the other direction. The encryptions are copied
+// since they can also be used by other connections.
+func (c *Config) shareBridgeSenders() {
+	for i, connection := range c.Connections {
+		if !connection.Bridge || (connection.Encrypt == nil && connection.Decrypt == nil) {
+			continue
+		}
+		senders := encryption.NewSenders()
+		for _, crypt := range []**ConfigEncryption{&c.Connections[i].Encrypt, &c.Connections[i].Decrypt} {
+			if *crypt != nil {
+				copied := **crypt
+				copied.senders = senders
+				*crypt = &copied
+			}
+		}
+	}
+}
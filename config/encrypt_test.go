@@ -0,0 +1,89 @@
+package config
+
+import (
+	"context"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that data encrypted by one connection is delimited and decrypted by another after passing through a TCP
+// socket.
+func TestApplyConfig_EncryptAndDecrypt(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	output := filepath.Join(dir, "output.txt")
+	keyFile := filepath.Join(dir, "flow.key")
+	content := strings.Repeat("TestApplyConfig_EncryptAndDecrypt", 100)
+	require.NoError(t, os.WriteFile(input, []byte(content), 0666))
+	require.NoError(t, os.WriteFile(keyFile, []byte(hex.EncodeToString([]byte(strings.Repeat("k", 32)))), 0600))
+
+	socketPort := uint16(64625)
+	encryption := &ConfigEncryption{Algorithm: "chacha20-poly1305", KeyFile: keyFile}
+	config := Config{
+		Connections: []ConfigConnection{
+			{ReaderID: "in", WriterID: "sender-socket", Compress: "zstd", Encrypt: encryption},
+			{ReaderID: "recv-socket", WriterID: "out", Decrypt: encryption, Decompress: "zstd"},
+		},
+		Nodes: ConfigNodes{
+			Files: []ConfigFile{{ID: "in", Path: input}, {ID: "out", Path: output}},
+			Sockets: []ConfigSocket{
+				{ID: "sender-socket", Protocol: "tcp", Port: socketPort, Address: "127.0.0.1"},
+				{ID: "recv-socket", Protocol: "tcp", Port: socketPort, Address: "127.0.0.1"},
+			},
+		},
+		Settings: ConfigSettings{Timeout: 1},
+	}
+	require.NoError(t, config.Initialise())
+	defer config.Close()
+	require.Len(t, config.Conns, 2)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	require.NoError(t, applyConfig(ctx, cancelFunc, config.Conns, config.Settings))
+
+	read, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, content, string(read))
+}
+
+// Ensure that the reverse direction of a bridge decrypts the data that the configured direction encrypts.
+func TestConfig_EncryptBridge(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "flow.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(strings.Repeat("k", 16)), 0600))
+
+	encryption := &ConfigEncryption{Algorithm: "aes-gcm", KeyFile: keyFile}
+	config := Config{Connections: []ConfigConnection{{ReaderID: "a", WriterID: "b", Bridge: true, Compress: "gzip", Encrypt: encryption}}}
+	config.shareBridgeSenders()
+	connections := config.allConnections()
+	require.Len(t, connections, 2)
+	require.Equal(t, "gzip", connections[0].Compress)
+	require.Equal(t, encryption.KeyFile, connections[0].Encrypt.KeyFile)
+	require.Equal(t, "gzip", connections[1].Decompress)
+	require.Equal(t, encryption.KeyFile, connections[1].Decrypt.KeyFile)
+	// Both directions share the senders, so the frames of the configured direction are rejected by the reverse
+	require.NotNil(t, connections[0].Encrypt.senders)
+	require.Same(t, connections[0].Encrypt.senders, connections[1].Decrypt.senders)
+	require.Empty(t, connections[1].Compress)
+	require.Nil(t, connections[1].Encrypt)
+}
+
+// Ensure that the algorithm and key file are validated.
+func TestConfigEncryption_Validate(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "flow.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(strings.Repeat("k", 16)), 0600))
+	connection := ConfigConnection{ReaderID: StdIn, WriterID: StdOut}
+
+	require.NoError(t, ConfigEncryption{Algorithm: "aes-gcm", KeyFile: keyFile}.validate(connection))
+	require.Error(t, ConfigEncryption{Algorithm: "des", KeyFile: keyFile}.validate(connection))
+	require.Error(t, ConfigEncryption{Algorithm: "aes-gcm", KeyFile: filepath.Join(dir, "missing.key")}.validate(connection))
+	// ChaCha20-Poly1305 requires a 32 byte key
+	require.Error(t, ConfigEncryption{Algorithm: "chacha20-poly1305", KeyFile: keyFile}.validate(connection))
+
+	config := Config{Connections: []ConfigConnection{{ReaderID: StdIn, WriterID: StdOut, Decrypt: &ConfigEncryption{Algorithm: "des"}}}}
+	require.Equal(t, ExitCodeConfigError, ExitCode(config.Initialise()))
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// The exit codes returned once the flow configuration ends
+const (
+	// The flow ended normally, e.g. the idle timeout was reached or an exit condition was met (unless it configures
+	// its own exit code)
+	ExitCodeSuccess = 0
+	// The flow failed for a reason that does not have its own exit code
+	ExitCodeFailure = 1
+	// The configuration file could not be read or is invalid
+	ExitCodeConfigError = 2
+	// A reader or writer could not be opened
+	ExitCodeNodeOpenFailure = 3
+	// Copying data between a reader and its writers failed at least once before the flow ended, this is returned
+	// instead of the reason the flow ended. Also returned if an otherwise successful flow failed to shut down, e.g. a
+	// writer failed to be closed
+	ExitCodeIOFailure = 4
+	// A script failed, e.g. an expect step timed out
+	ExitCodeScriptFailure = 5
+	// The idle timeout was reached while exit conditions are configured and none of them were met
+	ExitCodeIdleTimeout = 10
+	// The maximum runtime was reached
+	ExitCodeMaxRuntime = 11
+	// The flow was stopped by a signal, the signal number is added to this value
+	ExitCodeSignal = 128
+)
+
+// Whether the provided exit code is one of the exit codes above, other than [ExitCodeSuccess], so it can't be used by
+// an exit condition without being mistaken for another reason.
+func isReservedExitCode(code int) bool {
+	return (code >= ExitCodeFailure && code <= ExitCodeScriptFailure) || code == ExitCodeIdleTimeout ||
+		code == ExitCodeMaxRuntime || code >= ExitCodeSignal
+}
+
+// An error that ended the flow along with the process exit code that describes it.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func newExitError(code int, err error) *ExitError {
+	return &ExitError{Code: code, Err: err}
+}
+
+// Returns the [ExitError] for the flow being stopped by the provided signal.
+func newSignalExitError(sig os.Signal) *ExitError {
+	code := ExitCodeSignal
+	if s, ok := sig.(syscall.Signal); ok {
+		code += int(s)
+	}
+	return newExitError(code, fmt.Errorf("stopped by signal [%s]", sig))
+}
+
+// [error.Error]
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+// Returns the wrapped error
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// Returns the process exit code for an error returned by [ApplyConfigurationFromFile]. [ExitCodeSuccess] is returned
+// for a nil error and [ExitCodeFailure] for an error that is not an [ExitError].
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeSuccess
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitCodeFailure
+}
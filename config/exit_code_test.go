@@ -0,0 +1,101 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCode(t *testing.T) {
+	require.Equal(t, ExitCodeSuccess, ExitCode(nil))
+	require.Equal(t, ExitCodeFailure, ExitCode(errors.New("failure")))
+
+	err := fmt.Errorf("wrapped: %w", newExitError(ExitCodeNodeOpenFailure, errors.New("failure")))
+	require.Equal(t, ExitCodeNodeOpenFailure, ExitCode(err))
+	require.Equal(t, "wrapped: failure", err.Error())
+
+	require.Equal(t, ExitCodeSignal+int(syscall.SIGINT), ExitCode(newSignalExitError(syscall.SIGINT)))
+	require.Equal(t, ExitCodeSignal+int(syscall.SIGTERM), ExitCode(newSignalExitError(syscall.SIGTERM)))
+}
+
+// Ensure that an invalid configuration and a reader that cannot be opened are reported with different exit codes.
+func TestInitialise_ExitCodes(t *testing.T) {
+	invalid := Config{
+		Connections: []ConfigConnection{{ReaderID: StdIn, WriterID: StdOut, Bridge: true}},
+	}
+	require.Equal(t, ExitCodeConfigError, ExitCode(invalid.Initialise()))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	inUse := Config{
+		Connections: []ConfigConnection{{ReaderID: "tcp", WriterID: StdOut}},
+		Nodes: ConfigNodes{
+			Sockets: []ConfigSocket{
+				{
+					ID:       "tcp",
+					Protocol: "tcp",
+					Address:  "127.0.0.1",
+					Port:     uint16(listener.Addr().(*net.TCPAddr).Port),
+				},
+			},
+		},
+	}
+	err = inUse.Initialise()
+	defer inUse.Close()
+	require.Equal(t, ExitCodeNodeOpenFailure, ExitCode(err))
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(b []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+// Ensure that the flow reports an I/O failure if copying data failed, however the flow ends.
+func TestApplyConfig_IOFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings ConfigSettings
+	}{
+		{name: "idle timeout", settings: ConfigSettings{Timeout: 1}},
+		{name: "max runtime", settings: ConfigSettings{MaxRuntime: 1}},
+		{name: "exit condition", settings: ConfigSettings{ExitConditions: []ConfigExitCondition{{ReaderID: "reader", Bytes: 1, ExitCode: 20}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			connections := []Connection{
+				{
+					Reader:    strings.NewReader("data"),
+					ReaderId:  "reader",
+					Writer:    failingWriter{},
+					WriterIds: []string{"writer"},
+				},
+			}
+			// The exit conditions are met by a second reader whose data is copied successfully
+			for _, c := range test.settings.ExitConditions {
+				condition := newExitConditionWriter(c)
+				connections = append(connections, Connection{
+					Reader:         strings.NewReader("data"),
+					ReaderId:       c.ReaderID,
+					Writer:         condition,
+					exitConditions: []*exitConditionWriter{condition},
+				})
+			}
+
+			ctx, cancelFunc := context.WithCancel(context.Background())
+			err := applyConfig(ctx, cancelFunc, connections, test.settings)
+			require.Equal(t, ExitCodeIOFailure, ExitCode(err))
+			require.ErrorContains(t, err, io.ErrClosedPipe.Error())
+		})
+	}
+}
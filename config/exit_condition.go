@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// The amount of previously read bytes that are kept to match a [ConfigExitCondition.Pattern] that spans multiple
+// reads
+const patternWindow = 64 * 1024
+
+// A condition on the data read from a reader that stops the flow once it is met. Only one of
+// [ConfigExitCondition.EOF], [ConfigExitCondition.Bytes], [ConfigExitCondition.Messages] or
+// [ConfigExitCondition.Pattern] can be set.
+type ConfigExitCondition struct {
+	// The ID of the reader whose data is checked
+	ReaderID string
+	// Met once the reader has been read from and has then ended, see [endedReader]. A reader that is only waiting for
+	// more data has not ended, and readers that can't end (e.g. a UDP socket or a serial port) never meet this
+	EOF bool
+	// Met once this many bytes have been read from the reader
+	Bytes int64
+	// Met once this many messages (chunks of data or datagrams) have been read from the reader
+	Messages int
+	// A regular expression which is met once it matches the data read from the reader
+	Pattern string
+	// The process exit code once this condition is met, defaults to [ExitCodeSuccess]. The exit codes the flow already
+	// uses can't be configured, see [isReservedExitCode]
+	ExitCode int
+}
+
+// Describes the condition for logging
+func (c ConfigExitCondition) String() string {
+	switch {
+	case c.EOF:
+		return fmt.Sprintf("reader [%s] reached EOF", c.ReaderID)
+	case c.Bytes > 0:
+		return fmt.Sprintf("reader [%s] read [%d] bytes", c.ReaderID, c.Bytes)
+	case c.Messages > 0:
+		return fmt.Sprintf("reader [%s] read [%d] messages", c.ReaderID, c.Messages)
+	default:
+		return fmt.Sprintf("reader [%s] matched pattern [%s]", c.ReaderID, c.Pattern)
+	}
+}
+
+func (c ConfigExitCondition) validate() error {
+	criteria := 0
+	for _, set := range []bool{c.EOF, c.Bytes != 0, c.Messages != 0, c.Pattern != ""} {
+		if set {
+			criteria++
+		}
+	}
+	if criteria != 1 {
+		return fmt.Errorf("exit condition for reader with ID [%s] must have exactly one of \"eof\", \"bytes\", \"messages\" or \"pattern\" set", c.ReaderID)
+	}
+
+	if c.Bytes < 0 || c.Messages < 0 {
+		return fmt.Errorf("exit condition for reader with ID [%s] has a negative count", c.ReaderID)
+	}
+	if c.ExitCode < 0 || c.ExitCode > 255 {
+		return fmt.Errorf("exit condition for reader with ID [%s] has exit code [%d] which is not between 0 and 255", c.ReaderID, c.ExitCode)
+	}
+	if isReservedExitCode(c.ExitCode) {
+		return fmt.Errorf("exit condition for reader with ID [%s] has exit code [%d] which is already used by the flow, use 0, 6 to 9 or 12 to 127", c.ReaderID, c.ExitCode)
+	}
+
+	if c.Pattern != "" {
+		_, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return fmt.Errorf("exit condition for reader with ID [%s] has an invalid pattern [%s] with error: [%s]", c.ReaderID, c.Pattern, err.Error())
+		}
+	}
+	return nil
+}
+
+// Implemented by readers that know when they will have no more data, rather than only having no data available yet,
+// e.g. a file that has been read to its end, a replay that has finished or a TCP reader whose clients have all
+// disconnected.
+type endedReader interface {
+	Ended() bool
+}
+
+// Tracks the data read from the reader of a [ConfigExitCondition]. This is added as one of the writers of the
+// reader so that it sees all the data that is read.
+type exitConditionWriter struct {
+	condition ConfigExitCondition
+	pattern   *regexp.Regexp
+	bytes     int64
+	messages  int
+	window    []byte
+	met       bool
+}
+
+func newExitConditionWriter(condition ConfigExitCondition) *exitConditionWriter {
+	w := &exitConditionWriter{condition: condition}
+	if condition.Pattern != "" {
+		w.pattern = regexp.MustCompile(condition.Pattern)
+	}
+	return w
+}
+
+// [io.Writer.Write], each call is counted as a single message.
+func (w *exitConditionWriter) Write(b []byte) (int, error) {
+	w.bytes += int64(len(b))
+	w.messages++
+
+	switch {
+	case w.condition.Bytes > 0 && w.bytes >= w.condition.Bytes:
+		w.met = true
+	case w.condition.Messages > 0 && w.messages >= w.condition.Messages:
+		w.met = true
+	case w.pattern != nil:
+		w.window = append(w.window, b...)
+		if w.pattern.Match(w.window) {
+			w.met = true
+		}
+		if len(w.window) > patternWindow {
+			w.window = w.window[len(w.window)-patternWindow:]
+		}
+	}
+	return len(b), nil
+}
+
+// Called after each poll of the reader with the amount of bytes that were read. The EOF condition is met once no
+// bytes are read after having previously read data, and the reader has ended.
+func (w *exitConditionWriter) polled(reader io.Reader, written int64) {
+	if !w.condition.EOF || written > 0 || w.bytes == 0 {
+		return
+	}
+	if ended, ok := reader.(endedReader); ok && ended.Ended() {
+		w.met = true
+	}
+}
+
+// Validate the exit conditions, ensuring that each references a reader that is used in a connection.
+func (c Config) validateExitConditions() error {
+	for _, condition := range c.Settings.ExitConditions {
+		err := condition.validate()
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for _, connection := range c.allConnections() {
+			if connection.ReaderID == condition.ReaderID {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("exit condition references reader with ID [%s] which is not used as a reader in any connection", condition.ReaderID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that exactly one criterion must be set and that the values are checked.
+func TestConfigExitCondition_Validate(t *testing.T) {
+	require.NoError(t, ConfigExitCondition{ReaderID: "r", EOF: true}.validate())
+	require.NoError(t, ConfigExitCondition{ReaderID: "r", Bytes: 10, ExitCode: 20}.validate())
+	require.NoError(t, ConfigExitCondition{ReaderID: "r", Bytes: 10, ExitCode: 127}.validate())
+	require.NoError(t, ConfigExitCondition{ReaderID: "r", Pattern: "done$"}.validate())
+
+	require.Error(t, ConfigExitCondition{ReaderID: "r"}.validate())
+	require.Error(t, ConfigExitCondition{ReaderID: "r", EOF: true, Bytes: 10}.validate())
+	require.Error(t, ConfigExitCondition{ReaderID: "r", Messages: -1}.validate())
+	require.Error(t, ConfigExitCondition{ReaderID: "r", EOF: true, ExitCode: 256}.validate())
+	// The exit codes used by the flow itself
+	for _, code := range []int{ExitCodeFailure, ExitCodeIOFailure, ExitCodeScriptFailure, ExitCodeIdleTimeout, ExitCodeMaxRuntime, ExitCodeSignal, 255} {
+		require.Error(t, ConfigExitCondition{ReaderID: "r", EOF: true, ExitCode: code}.validate())
+	}
+	require.Error(t, ConfigExitCondition{ReaderID: "r", Pattern: "("}.validate())
+}
+
+// Ensure that an exit condition must reference a reader that is used in a connection.
+func TestConfig_ExitConditionUnknownReader(t *testing.T) {
+	dir := t.TempDir()
+	config := Config{
+		Connections: []ConfigConnection{{ReaderID: "in", WriterID: "out"}},
+		Nodes: ConfigNodes{
+			Files: []ConfigFile{
+				{ID: "in", Path: filepath.Join(dir, "in.txt")},
+				{ID: "out", Path: filepath.Join(dir, "out.txt")},
+			},
+		},
+		Settings: ConfigSettings{
+			ExitConditions: []ConfigExitCondition{{ReaderID: "out", EOF: true}},
+		},
+	}
+	require.Error(t, config.Initialise())
+}
+
+func TestExitConditionWriter(t *testing.T) {
+	t.Run("bytes", func(t *testing.T) {
+		w := newExitConditionWriter(ConfigExitCondition{Bytes: 5})
+		w.Write([]byte("abc"))
+		require.False(t, w.met)
+		w.Write([]byte("de"))
+		require.True(t, w.met)
+	})
+
+	t.Run("messages", func(t *testing.T) {
+		w := newExitConditionWriter(ConfigExitCondition{Messages: 2})
+		w.Write([]byte("abc"))
+		require.False(t, w.met)
+		w.Write([]byte("d"))
+		require.True(t, w.met)
+	})
+
+	t.Run("pattern across writes", func(t *testing.T) {
+		w := newExitConditionWriter(ConfigExitCondition{Pattern: "DONE"})
+		w.Write([]byte("still going DO"))
+		require.False(t, w.met)
+		w.Write([]byte("NE\n"))
+		require.True(t, w.met)
+	})
+
+	t.Run("eof", func(t *testing.T) {
+		reader := &endingReader{}
+		w := newExitConditionWriter(ConfigExitCondition{EOF: true})
+		// Nothing has been read yet, so the reader has not reached its end
+		reader.ended = true
+		w.polled(reader, 0)
+		require.False(t, w.met)
+		reader.ended = false
+		w.Write([]byte("abc"))
+		w.polled(reader, 3)
+		require.False(t, w.met)
+		// A pause in the data is not the end of the reader
+		w.polled(reader, 0)
+		require.False(t, w.met)
+		reader.ended = true
+		w.polled(reader, 0)
+		require.True(t, w.met)
+	})
+
+	t.Run("eof of a reader that can't end", func(t *testing.T) {
+		w := newExitConditionWriter(ConfigExitCondition{EOF: true})
+		w.Write([]byte("abc"))
+		w.polled(strings.NewReader(""), 0)
+		require.False(t, w.met)
+	})
+}
+
+type endingReader struct {
+	ended bool
+}
+
+func (r *endingReader) Read(b []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (r *endingReader) Ended() bool {
+	return r.ended
+}
+
+// Ensure that the flow stops with the exit code of the condition that is met, or the reason it stopped otherwise.
+func TestApplyConfig_ExitConditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings ConfigSettings
+		expected int
+	}{
+		{
+			name:     "eof",
+			settings: ConfigSettings{ExitConditions: []ConfigExitCondition{{ReaderID: "in", EOF: true, ExitCode: 20}}},
+			expected: 20,
+		},
+		{
+			name:     "pattern",
+			settings: ConfigSettings{ExitConditions: []ConfigExitCondition{{ReaderID: "in", Pattern: "wor.d", ExitCode: 21}}},
+			expected: 21,
+		},
+		{
+			name:     "idle timeout",
+			settings: ConfigSettings{Timeout: 1, ExitConditions: []ConfigExitCondition{{ReaderID: "in", Bytes: 1000}}},
+			expected: ExitCodeIdleTimeout,
+		},
+		{
+			name:     "idle timeout without conditions",
+			settings: ConfigSettings{Timeout: 1},
+			expected: ExitCodeSuccess,
+		},
+		{
+			name:     "max runtime",
+			settings: ConfigSettings{MaxRuntime: 1},
+			expected: ExitCodeMaxRuntime,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "in.txt")
+			output := filepath.Join(dir, "out.txt")
+			require.NoError(t, os.WriteFile(input, []byte("hello world"), 0666))
+
+			config := Config{
+				Connections: []ConfigConnection{{ReaderID: "in", WriterID: "out"}},
+				Nodes: ConfigNodes{
+					Files: []ConfigFile{
+						{ID: "in", Path: input},
+						{ID: "out", Path: output},
+					},
+				},
+				Settings: test.settings,
+			}
+			require.NoError(t, config.Initialise())
+			defer config.Close()
+
+			ctx, cancelFunc := context.WithCancel(context.Background())
+			exitCode := ExitCode(applyConfig(ctx, cancelFunc, config.Conns, config.Settings))
+			require.Equal(t, test.expected, exitCode)
+			require.Error(t, ctx.Err())
+
+			read, err := os.ReadFile(output)
+			require.NoError(t, err)
+			require.Equal(t, "hello world", string(read))
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Impairs the data written to the writer of a [ConfigConnection] to emulate an unreliable network, each chunk of data
+// (or datagram) is impaired independently. The probabilities are between 0 and 1.
+type ConfigImpairment struct {
+	// The milliseconds each chunk is delayed by
+	Latency int
+	// The maximum milliseconds randomly added to or removed from the latency of each chunk, chunks can be reordered
+	// since they are written in the order that they are due
+	Jitter int
+	// The probability that a chunk is dropped
+	Loss float64
+	// The probability that a chunk is written twice
+	Duplicate float64
+	// The probability that a chunk is written without any latency, overtaking the delayed chunks before it
+	Reorder float64
+	// The probability that a random bit of a chunk is flipped
+	Corrupt float64
+	// The seed of the random impairments so a run can be reproduced, a random seed is used (and logged once) when 0.
+	// The reverse direction of a bridge derives its own stream from the same seed, so the directions are impaired
+	// independently
+	Seed uint64
+	// Set for the reverse direction of a bridge, see [ConfigImpairment.Seed]
+	reverse bool
+}
+
+func (c ConfigImpairment) validate(connection ConfigConnection) error {
+	if c.Latency < 0 || c.Jitter < 0 {
+		return fmt.Errorf("impairment of connection from [%s] to [%s] has a negative latency or jitter", connection.ReaderID, connection.WriterID)
+	}
+	for _, probability := range []float64{c.Loss, c.Duplicate, c.Reorder, c.Corrupt} {
+		if probability < 0 || probability > 1 {
+			return fmt.Errorf("impairment of connection from [%s] to [%s] has probability [%f] which is not between 0 and 1", connection.ReaderID, connection.WriterID, probability)
+		}
+	}
+	return nil
+}
+
+// Applies a [ConfigImpairment] to the chunks of data written to a [shapedWriter].
+type impairment struct {
+	config ConfigImpairment
+	random *rand.Rand
+}
+
+// Gives each impairment without a seed a random seed and logs it, so that both directions of a bridge are impaired
+// from the same seed and the run can be reproduced. The impairments are copied since they can also be used by other
+// connections.
+func (c *Config) seedImpairments() {
+	for i, connection := range c.Connections {
+		if connection.Impair == nil || connection.Impair.Seed != 0 {
+			continue
+		}
+		seeded := *connection.Impair
+		seeded.Seed = rand.Uint64()
+		c.Connections[i].Impair = &seeded
+		fmt.Printf("Impairing connection from [%s] to [%s] with seed [%d].\n", connection.ReaderID, connection.WriterID, seeded.Seed)
+	}
+}
+
+func newImpairment(connection ConfigConnection) *impairment {
+	config := *connection.Impair
+	stream := config.Seed
+	if config.reverse {
+		stream = ^stream
+	}
+	return &impairment{config: config, random: rand.New(rand.NewPCG(config.Seed, stream))}
+}
+
+// Returns true with the provided probability
+func (i *impairment) chance(probability float64) bool {
+	return probability > 0 && i.random.Float64() < probability
+}
+
+// Returns the chunks to be queued for the provided data, which is empty if the data is lost or contains the data
+// twice if it is duplicated. Each chunk is delayed by the latency and jitter on top of the provided delay.
+func (i *impairment) apply(data []byte, now time.Time, delay time.Duration) []shapedChunk {
+	if i.chance(i.config.Loss) {
+		return nil
+	}
+
+	if i.chance(i.config.Corrupt) && len(data) > 0 {
+		bit := i.random.IntN(len(data) * 8)
+		data[bit/8] ^= 1 << (bit % 8)
+	}
+
+	if !i.chance(i.config.Reorder) {
+		delay += time.Duration(i.config.Latency) * time.Millisecond
+		if i.config.Jitter > 0 {
+			delay += time.Duration(i.random.IntN(2*i.config.Jitter+1)-i.config.Jitter) * time.Millisecond
+		}
+	}
+
+	chunks := []shapedChunk{{data: data, due: now.Add(max(delay, 0))}}
+	if i.chance(i.config.Duplicate) {
+		chunks = append(chunks, shapedChunk{data: append([]byte(nil), data...), due: chunks[0].due})
+	}
+	return chunks
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"math/bits"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Writes the messages to a new impaired writer and returns the chunks released once they are all due.
+func impairMessages(t *testing.T, impair ConfigImpairment, messages int) []string {
+	output := &chunkRecorder{}
+	w := newShapedWriter(output, ConfigConnection{Impair: &impair})
+	for i := range messages {
+		_, err := w.Write([]byte(fmt.Sprintf("message-%d", i)))
+		require.NoError(t, err)
+	}
+	time.Sleep(time.Duration(impair.Latency+impair.Jitter+10) * time.Millisecond)
+	_, err := w.release()
+	require.NoError(t, err)
+	require.False(t, w.pending())
+	return output.chunks
+}
+
+// Ensure that the same seed impairs the data in the same way.
+func TestImpairment_Seed(t *testing.T) {
+	impair := ConfigImpairment{Loss: 0.5, Duplicate: 0.2, Seed: 42}
+	first := impairMessages(t, impair, 100)
+	require.Equal(t, first, impairMessages(t, impair, 100))
+	require.InDelta(t, 60, len(first), 25)
+
+	impair.Seed = 43
+	require.NotEqual(t, first, impairMessages(t, impair, 100))
+}
+
+// Ensure that the reverse direction of a bridge is impaired with its own seed.
+func TestImpairment_BridgeSeed(t *testing.T) {
+	config := Config{Connections: []ConfigConnection{
+		{ReaderID: "a", WriterID: "b", Bridge: true, Impair: &ConfigImpairment{Loss: 0.5, Seed: 42}},
+	}}
+	connections := config.allConnections()
+	require.Len(t, connections, 2)
+	require.Equal(t, uint64(42), connections[1].Impair.Seed)
+	require.False(t, connections[0].Impair.reverse)
+
+	forward := impairMessages(t, *connections[0].Impair, 100)
+	require.Equal(t, forward, impairMessages(t, ConfigImpairment{Loss: 0.5, Seed: 42}, 100))
+	require.NotEqual(t, forward, impairMessages(t, *connections[1].Impair, 100))
+}
+
+// Ensure that an impairment without a seed is given a single random seed, which is used by both directions of a bridge.
+func TestConfig_SeedImpairments(t *testing.T) {
+	impair := &ConfigImpairment{Loss: 0.5}
+	config := Config{Connections: []ConfigConnection{
+		{ReaderID: "a", WriterID: "b", Bridge: true, Impair: impair},
+	}}
+	config.seedImpairments()
+	require.NotZero(t, config.Connections[0].Impair.Seed)
+	require.Zero(t, impair.Seed)
+
+	connections := config.allConnections()
+	require.Len(t, connections, 2)
+	require.Equal(t, config.Connections[0].Impair.Seed, connections[0].Impair.Seed)
+	require.Equal(t, config.Connections[0].Impair.Seed, connections[1].Impair.Seed)
+	require.Equal(t, connections[0].Impair.Seed, config.allConnections()[0].Impair.Seed)
+}
+
+func TestImpairment_LossAndDuplicate(t *testing.T) {
+	require.Empty(t, impairMessages(t, ConfigImpairment{Loss: 1, Seed: 1}, 10))
+	require.Equal(t, []string{"message-0", "message-0", "message-1", "message-1"}, impairMessages(t, ConfigImpairment{Duplicate: 1, Seed: 1}, 2))
+}
+
+// Ensure that a corrupted chunk has exactly one bit flipped.
+func TestImpairment_Corrupt(t *testing.T) {
+	chunks := impairMessages(t, ConfigImpairment{Corrupt: 1, Seed: 1}, 1)
+	require.Len(t, chunks, 1)
+
+	original := []byte("message-0")
+	flipped := 0
+	for i := range original {
+		flipped += bits.OnesCount8(original[i] ^ chunks[0][i])
+	}
+	require.Equal(t, 1, flipped)
+}
+
+// Ensure that each chunk is due within the latency and jitter, and that a reordered chunk overtakes the delayed chunks
+// before it.
+func TestImpairment_LatencyAndReorder(t *testing.T) {
+	output := &chunkRecorder{}
+	w := newShapedWriter(output, ConfigConnection{Impair: &ConfigImpairment{Latency: 100, Jitter: 20, Seed: 1}})
+	start := time.Now()
+	for range 20 {
+		_, err := w.Write([]byte("delayed"))
+		require.NoError(t, err)
+	}
+	for _, chunk := range w.queue {
+		require.GreaterOrEqual(t, chunk.due.Sub(start), 80*time.Millisecond)
+		require.LessOrEqual(t, chunk.due.Sub(time.Now()), 120*time.Millisecond)
+	}
+
+	w.impair.config.Reorder = 1
+	_, err := w.Write([]byte("reordered"))
+	require.NoError(t, err)
+	_, err = w.release()
+	require.NoError(t, err)
+	require.Equal(t, []string{"reordered"}, output.chunks)
+}
+
+func TestConfigImpairment_Validate(t *testing.T) {
+	connection := ConfigConnection{ReaderID: "r", WriterID: "w"}
+	require.NoError(t, ConfigImpairment{Latency: 10, Jitter: 5, Loss: 0.1, Corrupt: 1}.validate(connection))
+	require.Error(t, ConfigImpairment{Latency: -1}.validate(connection))
+	require.Error(t, ConfigImpairment{Loss: 1.5}.validate(connection))
+	require.Error(t, ConfigImpairment{Reorder: -0.1}.validate(connection))
+
+	config := Config{Connections: []ConfigConnection{{ReaderID: StdIn, WriterID: StdOut, Impair: &ConfigImpairment{Duplicate: 2}}}}
+	require.Error(t, config.Initialise())
+}
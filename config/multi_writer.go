@@ -0,0 +1,138 @@
+package config
+
+import (
+	"io"
+	"time"
+
+	"github.com/Kilemonn/flow/clientinfo"
+	"github.com/Kilemonn/flow/socket"
+)
+
+// Similar to [io.MultiWriter], however any client or datagram information is also forwarded to the writers that
+// support it (see [clientinfo.Writer] and [socket.DatagramWriter]), otherwise they receive a plain Write.
+type multiWriter struct {
+	writers []io.Writer
+}
+
+func newMultiWriter(writers ...io.Writer) multiWriter {
+	return multiWriter{writers: writers}
+}
+
+// [io.Writer.Write]
+func (m multiWriter) Write(b []byte) (int, error) {
+	for _, w := range m.writers {
+		n, err := w.Write(b)
+		if err != nil {
+			return n, err
+		}
+		if n != len(b) {
+			return n, io.ErrShortWrite
+		}
+	}
+	return len(b), nil
+}
+
+// [clientinfo.Writer.WriteClient]
+func (m multiWriter) WriteClient(info clientinfo.Info, b []byte) (int, error) {
+	for _, w := range m.writers {
+		n, err := clientinfo.Write(w, info, b)
+		if err != nil {
+			return n, err
+		}
+		if n != len(b) {
+			return n, io.ErrShortWrite
+		}
+	}
+	return len(b), nil
+}
+
+// [clientinfo.Writer.CloseClient], only the first occurring error will be returned.
+func (m multiWriter) CloseClient(info clientinfo.Info) error {
+	var err error
+	for _, w := range m.writers {
+		e := clientinfo.Close(w, []clientinfo.Info{info})
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// [socket.DatagramWriter.WriteDatagram]
+func (m multiWriter) WriteDatagram(d socket.Datagram) (int, error) {
+	for _, w := range m.writers {
+		n, err := socket.WriteDatagram(w, d)
+		if err != nil {
+			return n, err
+		}
+		if n != len(d.Data) {
+			return n, io.ErrShortWrite
+		}
+	}
+	return len(d.Data), nil
+}
+
+// Implemented by writers that record which reader the data was read from, e.g. [capture.Writer] and [monitor.Writer].
+type readerIDWriter interface {
+	WriteFrom(readerID string, b []byte) (int, error)
+}
+
+// Implemented by [readerIDWriter]s that keep the data of each client of a reader apart, e.g. [merge.Merger] and
+// [clientinfo.FileWriter].
+type clientReaderIDWriter interface {
+	WriteClientFrom(readerID string, info clientinfo.Info, b []byte) (int, error)
+	CloseClientFrom(readerID string, info clientinfo.Info) error
+}
+
+// Implemented by writers that order data by when it was originally read, e.g. [merge.Merger].
+type timestampWriter interface {
+	WriteAt(readerID string, timestamp time.Duration, b []byte) (int, error)
+}
+
+// Implemented by readers that know when the data they last read was originally read, e.g. [capture.ReplayReader].
+type offsetReader interface {
+	Offset() time.Duration
+}
+
+// Writes the data to a [readerIDWriter] as read from the reader with the ID readerID. If the writer is a
+// [timestampWriter] and the reader is an [offsetReader], the data is written with the reader's offset.
+type fromReaderWriter struct {
+	writer   readerIDWriter
+	readerID string
+	offsets  offsetReader
+}
+
+func newFromReaderWriter(writer readerIDWriter, readerID string, reader io.Reader) fromReaderWriter {
+	w := fromReaderWriter{writer: writer, readerID: readerID}
+	if offsets, ok := reader.(offsetReader); ok {
+		if _, ok := writer.(timestampWriter); ok {
+			w.offsets = offsets
+		}
+	}
+	return w
+}
+
+// [io.Writer.Write]
+func (w fromReaderWriter) Write(b []byte) (int, error) {
+	if w.offsets != nil {
+		return w.writer.(timestampWriter).WriteAt(w.readerID, w.offsets.Offset(), b)
+	}
+	return w.writer.WriteFrom(w.readerID, b)
+}
+
+// [clientinfo.Writer.WriteClient], the data is written as read from the reader if the writer does not keep the data of
+// each client apart.
+func (w fromReaderWriter) WriteClient(info clientinfo.Info, b []byte) (int, error) {
+	if cw, ok := w.writer.(clientReaderIDWriter); ok {
+		return cw.WriteClientFrom(w.readerID, info, b)
+	}
+	return w.Write(b)
+}
+
+// [clientinfo.Writer.CloseClient]
+func (w fromReaderWriter) CloseClient(info clientinfo.Info) error {
+	if cw, ok := w.writer.(clientReaderIDWriter); ok {
+		return cw.CloseClientFrom(w.readerID, info)
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/Kilemonn/flow/clientinfo"
+	"github.com/Kilemonn/flow/socket"
+)
+
+// Limits the rate that data is written to the writer of a [ConfigConnection], e.g. to emulate a slow serial link.
+// Data that is not yet allowed to be written is queued, and the reader is no longer read from while the queue is full.
+type ConfigRateLimit struct {
+	// The bytes written per second, no limit when 0. Chunks of data may be split to keep to this rate.
+	BytesPerSecond int
+	// The messages (chunks of data or datagrams) written per second, no limit when 0
+	MessagesPerSecond int
+	// The amount of bytes or messages that can be written at once after the writer is idle. Defaults to a tenth of a
+	// second's worth of bytes when [ConfigRateLimit.BytesPerSecond] is set, otherwise a single message.
+	Burst int
+}
+
+func (c ConfigRateLimit) validate(connection ConfigConnection) error {
+	if c.BytesPerSecond < 0 || c.MessagesPerSecond < 0 || c.Burst < 0 {
+		return fmt.Errorf("rate limit of connection from [%s] to [%s] has a negative value", connection.ReaderID, connection.WriterID)
+	}
+	if c.BytesPerSecond == 0 && c.MessagesPerSecond == 0 {
+		return fmt.Errorf("rate limit of connection from [%s] to [%s] must set \"bytespersecond\" or \"messagespersecond\"", connection.ReaderID, connection.WriterID)
+	}
+	return nil
+}
+
+// Returns the configured [ConfigRateLimit.Burst] or its default for the provided rate.
+func (c ConfigRateLimit) burst(rate int, isBytes bool) float64 {
+	if c.Burst > 0 {
+		return float64(c.Burst)
+	}
+	if isBytes {
+		return float64(max(rate/10, 1))
+	}
+	return 1
+}
+
+// A token bucket, tokens are added at the rate per second up to the burst.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int, burst float64) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &tokenBucket{rate: float64(rate), burst: burst, tokens: burst, last: time.Now()}
+}
+
+// Adds the tokens accrued since the last refill.
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// A chunk of data waiting to be written by a [shapedWriter], along with the client or datagram it was written with.
+type shapedChunk struct {
+	data   []byte
+	due    time.Time
+	source dataSource
+	// Whether this notifies the writer that the client has disconnected, rather than writing data
+	closeClient bool
+}
+
+// Queues the data written to it and writes it to the underlying writer once it is due, as limited by the configured
+// [ConfigRateLimit], delay and [ConfigImpairment]. [shapedWriter.release] must be called to write the queued data.
+// Any client or datagram information is queued with the data and forwarded to the writer if it supports it.
+type shapedWriter struct {
+	writer   io.Writer
+	delay    time.Duration
+	bytes    *tokenBucket
+	messages *tokenBucket
+	impair   *impairment
+	// The chunks in the order that they are due
+	queue  []shapedChunk
+	queued int
+	// The amount of bytes or messages that can be queued before the reader is no longer read from, no limit when 0
+	maxBytes    int
+	maxMessages int
+}
+
+// Returns a [shapedWriter] for the connection, or nil if it has no rate limit, delay or impairment.
+func newShapedWriter(writer io.Writer, connection ConfigConnection) *shapedWriter {
+	if connection.RateLimit == nil && connection.Delay <= 0 && connection.Impair == nil {
+		return nil
+	}
+
+	w := &shapedWriter{writer: writer, delay: time.Duration(connection.Delay) * time.Millisecond}
+	if connection.Impair != nil {
+		w.impair = newImpairment(connection)
+	}
+	if limit := connection.RateLimit; limit != nil {
+		w.bytes = newTokenBucket(limit.BytesPerSecond, limit.burst(limit.BytesPerSecond, true))
+		w.messages = newTokenBucket(limit.MessagesPerSecond, limit.burst(limit.MessagesPerSecond, false))
+		// Up to a second's worth of data is queued
+		if w.bytes != nil {
+			w.maxBytes = max(limit.BytesPerSecond, int(w.bytes.burst))
+		}
+		if w.messages != nil {
+			w.maxMessages = max(limit.MessagesPerSecond, int(w.messages.burst))
+		}
+	}
+	return w
+}
+
+// [io.Writer.Write], queues the data to be written once it is due.
+func (w *shapedWriter) Write(b []byte) (int, error) {
+	w.queueData(b, dataSource{})
+	return len(b), nil
+}
+
+// [clientinfo.Writer.WriteClient]
+func (w *shapedWriter) WriteClient(info clientinfo.Info, b []byte) (int, error) {
+	w.queueData(b, dataSource{client: &info})
+	return len(b), nil
+}
+
+// [clientinfo.Writer.CloseClient], the writer is notified once all of the data queued before this is written.
+func (w *shapedWriter) CloseClient(info clientinfo.Info) error {
+	due := time.Now().Add(w.delay)
+	if len(w.queue) > 0 && w.queue[len(w.queue)-1].due.After(due) {
+		due = w.queue[len(w.queue)-1].due
+	}
+	w.enqueue(shapedChunk{due: due, source: dataSource{client: &info}, closeClient: true})
+	return nil
+}
+
+// [socket.DatagramWriter.WriteDatagram], the datagram is not split by the rate limit.
+func (w *shapedWriter) WriteDatagram(d socket.Datagram) (int, error) {
+	w.queueData(d.Data, dataSource{datagram: &d})
+	return len(d.Data), nil
+}
+
+// Queues a copy of the data to be written once it is due, along with its source.
+func (w *shapedWriter) queueData(b []byte, source dataSource) {
+	now := time.Now()
+	data := append([]byte(nil), b...)
+	chunks := []shapedChunk{{data: data, due: now.Add(w.delay)}}
+	if w.impair != nil {
+		chunks = w.impair.apply(data, now, w.delay)
+	}
+
+	for _, chunk := range chunks {
+		chunk.source = source
+		w.enqueue(chunk)
+	}
+}
+
+// Inserts the chunk after all the queued chunks that are due before or at the same time as it.
+func (w *shapedWriter) enqueue(chunk shapedChunk) {
+	i := len(w.queue)
+	for i > 0 && w.queue[i-1].due.After(chunk.due) {
+		i--
+	}
+	w.queue = slices.Insert(w.queue, i, chunk)
+	w.queued += len(chunk.data)
+}
+
+// Whether any data is queued
+func (w *shapedWriter) pending() bool {
+	return len(w.queue) > 0
+}
+
+// Returns the amount of bytes that can be queued before the queue is full, or -1 if there is no limit.
+func (w *shapedWriter) space() int64 {
+	if w.maxMessages > 0 && len(w.queue) >= w.maxMessages {
+		return 0
+	}
+	if w.maxBytes > 0 {
+		return int64(max(w.maxBytes-w.queued, 0))
+	}
+	return -1
+}
+
+// Writes the queued data that is due and allowed by the rate limit, returning the amount of bytes written.
+func (w *shapedWriter) release() (int64, error) {
+	now := time.Now()
+	if w.bytes != nil {
+		w.bytes.refill(now)
+	}
+	if w.messages != nil {
+		w.messages.refill(now)
+	}
+
+	released := int64(0)
+	for len(w.queue) > 0 {
+		chunk := w.queue[0]
+		if now.Before(chunk.due) {
+			break
+		}
+		if chunk.closeClient {
+			w.queue = w.queue[1:]
+			err := clientinfo.Close(w.writer, []clientinfo.Info{*chunk.source.client})
+			if err != nil {
+				return released, err
+			}
+			continue
+		}
+		if w.messages != nil && w.messages.tokens < 1 {
+			break
+		}
+
+		data := chunk.data
+		if w.bytes != nil {
+			allowed := int(w.bytes.tokens)
+			// A datagram is written whole once there are enough tokens for it, or the bucket is full
+			if allowed <= 0 || (chunk.source.datagram != nil && allowed < min(len(data), int(w.bytes.burst))) {
+				break
+			}
+			if chunk.source.datagram == nil {
+				data = data[:min(allowed, len(data))]
+			}
+			w.bytes.tokens -= float64(len(data))
+		}
+
+		n, err := chunk.source.write(w.writer, data)
+		released += int64(n)
+		w.queued -= len(data)
+		if len(data) < len(chunk.data) {
+			// The rest of the chunk is written once more tokens are available, and is not counted as another message
+			w.queue[0].data = chunk.data[len(data):]
+		} else {
+			w.queue = w.queue[1:]
+			if w.messages != nil {
+				w.messages.tokens--
+			}
+		}
+		if err != nil {
+			return released, err
+		}
+	}
+	return released, nil
+}
+
+// Limits the data read from the reader of the connection so that it fits in the queue of each of its
+// [shapedWriter]s. The limit is 0 when any queue is full, otherwise -1 if there is no limit.
+func (c Connection) readLimit() int64 {
+	limit := int64(-1)
+	for _, shaper := range c.shapers {
+		space := shaper.space()
+		if space >= 0 && (limit < 0 || space < limit) {
+			limit = space
+		}
+	}
+	return limit
+}
+
+// Reads from the connection's reader into its writer, reading no more than what fits in the queue of its
+// [shapedWriter]s. Readers that implement [io.WriterTo] read a chunk of data at a time, so they are instead stopped once
+// a queue is full, see [queueLimitWriter]. The reader is not read from while a queue is full, in which case false is
+// returned.
+func (c Connection) copy() (int64, bool, error) {
+	reader := c.Reader
+	writer := c.Writer
+	limit := c.readLimit()
+	if limit == 0 {
+		return 0, false, nil
+	}
+	if limit > 0 {
+		if _, ok := reader.(io.WriterTo); ok {
+			writer = queueLimitWriter{writer: writer, connection: c}
+		} else {
+			reader = io.LimitReader(reader, limit)
+		}
+	}
+	written, err := io.Copy(writer, reader)
+	if errors.Is(err, errQueueFull) {
+		err = nil
+	}
+	return written, true, err
+}
+
+// Returned by a [queueLimitWriter] to stop its reader once a queue is full.
+var errQueueFull = errors.New("the rate limit queue is full")
+
+// Wraps the writer of a connection whose reader implements [io.WriterTo], returning [errQueueFull] once the queue of
+// any of the connection's [shapedWriter]s is full so that the reader stops reading. The data that fills the queue is
+// still queued, so a queue can only exceed its limit by a single chunk of data.
+type queueLimitWriter struct {
+	writer     io.Writer
+	connection Connection
+}
+
+// Returns [errQueueFull] instead of a nil error once a queue is full.
+func (w queueLimitWriter) limit(n int, err error) (int, error) {
+	if err == nil && w.connection.readLimit() == 0 {
+		err = errQueueFull
+	}
+	return n, err
+}
+
+// [io.Writer.Write]
+func (w queueLimitWriter) Write(b []byte) (int, error) {
+	return w.limit(w.writer.Write(b))
+}
+
+// [clientinfo.Writer.WriteClient]
+func (w queueLimitWriter) WriteClient(info clientinfo.Info, b []byte) (int, error) {
+	return w.limit(clientinfo.Write(w.writer, info, b))
+}
+
+// [clientinfo.Writer.CloseClient]
+func (w queueLimitWriter) CloseClient(info clientinfo.Info) error {
+	return clientinfo.Close(w.writer, []clientinfo.Info{info})
+}
+
+// [socket.DatagramWriter.WriteDatagram]
+func (w queueLimitWriter) WriteDatagram(d socket.Datagram) (int, error) {
+	return w.limit(socket.WriteDatagram(w.writer, d))
+}
+
+// Writes the queued data of the connection's [shapedWriter]s that is due, returning the amount of bytes written and
+// whether any data is still queued.
+func (c Connection) release() (int64, bool, error) {
+	released := int64(0)
+	pending := false
+	var err error
+	for _, shaper := range c.shapers {
+		n, e := shaper.release()
+		released += n
+		if e != nil && err == nil {
+			err = e
+		}
+		pending = pending || shaper.pending()
+	}
+	return released, pending, err
+}
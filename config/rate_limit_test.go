@@ -0,0 +1,229 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kilemonn/flow/clientinfo"
+	"github.com/Kilemonn/flow/generator"
+	"github.com/Kilemonn/flow/socket"
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that the bytes are released at the configured rate, splitting chunks once the burst is used.
+func TestShapedWriter_BytesPerSecond(t *testing.T) {
+	var output bytes.Buffer
+	w := newShapedWriter(&output, ConfigConnection{RateLimit: &ConfigRateLimit{BytesPerSecond: 1000, Burst: 100}})
+	require.Equal(t, int64(1000), w.space())
+
+	n, err := w.Write(bytes.Repeat([]byte("a"), 300))
+	require.NoError(t, err)
+	require.Equal(t, 300, n)
+	require.Equal(t, int64(700), w.space())
+
+	released, err := w.release()
+	require.NoError(t, err)
+	require.Equal(t, int64(100), released)
+	require.True(t, w.pending())
+
+	time.Sleep(50 * time.Millisecond)
+	released, err = w.release()
+	require.NoError(t, err)
+	require.InDelta(t, 50, released, 15)
+
+	// No more than the burst is released at once
+	time.Sleep(200 * time.Millisecond)
+	released, err = w.release()
+	require.NoError(t, err)
+	require.Equal(t, int64(100), released)
+
+	time.Sleep(100 * time.Millisecond)
+	_, err = w.release()
+	require.NoError(t, err)
+	require.False(t, w.pending())
+	require.Equal(t, 300, output.Len())
+}
+
+type chunkRecorder struct {
+	chunks []string
+}
+
+func (c *chunkRecorder) Write(b []byte) (int, error) {
+	c.chunks = append(c.chunks, string(b))
+	return len(b), nil
+}
+
+// Ensure that whole messages are released at the configured rate and the queue is full once a second's worth of
+// messages is queued.
+func TestShapedWriter_MessagesPerSecond(t *testing.T) {
+	output := &chunkRecorder{}
+	w := newShapedWriter(output, ConfigConnection{RateLimit: &ConfigRateLimit{MessagesPerSecond: 2}})
+	for _, message := range []string{"first", "second"} {
+		require.NotEqual(t, int64(0), w.space())
+		_, err := w.Write([]byte(message))
+		require.NoError(t, err)
+	}
+	require.Equal(t, int64(0), w.space())
+
+	_, err := w.release()
+	require.NoError(t, err)
+	require.Equal(t, []string{"first"}, output.chunks)
+
+	time.Sleep(550 * time.Millisecond)
+	_, err = w.release()
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second"}, output.chunks)
+}
+
+// Records each write along with the client or datagram it was written with.
+type sourceRecorder struct {
+	writes []string
+}
+
+func (r *sourceRecorder) Write(b []byte) (int, error) {
+	r.writes = append(r.writes, "write "+string(b))
+	return len(b), nil
+}
+
+func (r *sourceRecorder) WriteClient(info clientinfo.Info, b []byte) (int, error) {
+	r.writes = append(r.writes, fmt.Sprintf("client %d %s", info.ID, b))
+	return len(b), nil
+}
+
+func (r *sourceRecorder) CloseClient(info clientinfo.Info) error {
+	r.writes = append(r.writes, fmt.Sprintf("close %d", info.ID))
+	return nil
+}
+
+func (r *sourceRecorder) WriteDatagram(d socket.Datagram) (int, error) {
+	r.writes = append(r.writes, fmt.Sprintf("datagram %d", len(d.Data)))
+	return len(d.Data), nil
+}
+
+// Ensure that the client and datagram information is released with the data, a client is closed after its data and
+// a datagram is not split by the rate limit.
+func TestShapedWriter_ClientsAndDatagrams(t *testing.T) {
+	output := &sourceRecorder{}
+	w := newShapedWriter(output, ConfigConnection{RateLimit: &ConfigRateLimit{BytesPerSecond: 1000, Burst: 100}})
+
+	_, err := w.WriteClient(clientinfo.Info{ID: 1}, []byte("request"))
+	require.NoError(t, err)
+	require.NoError(t, w.CloseClient(clientinfo.Info{ID: 1}))
+	_, err = w.WriteDatagram(socket.Datagram{Data: bytes.Repeat([]byte("a"), 150)})
+	require.NoError(t, err)
+	_, err = w.Write([]byte("plain"))
+	require.NoError(t, err)
+
+	_, err = w.release()
+	require.NoError(t, err)
+	require.Equal(t, []string{"client 1 request", "close 1"}, output.writes)
+
+	// The datagram is written whole once the bucket is full, and the following data waits for the tokens it used
+	time.Sleep(150 * time.Millisecond)
+	_, err = w.release()
+	require.NoError(t, err)
+	require.Equal(t, []string{"client 1 request", "close 1", "datagram 150"}, output.writes)
+
+	time.Sleep(100 * time.Millisecond)
+	_, err = w.release()
+	require.NoError(t, err)
+	require.Equal(t, []string{"client 1 request", "close 1", "datagram 150", "write plain"}, output.writes)
+}
+
+// Ensure that a reader that implements [io.WriterTo] stops reading once the queue is full.
+func TestConnection_CopyLimitsWriterTo(t *testing.T) {
+	shaper := newShapedWriter(io.Discard, ConfigConnection{RateLimit: &ConfigRateLimit{BytesPerSecond: 1000}})
+	connection := Connection{
+		Reader:  generator.NewRepeat([]byte("0123456789")),
+		Writer:  shaper,
+		shapers: []*shapedWriter{shaper},
+	}
+
+	written, read, err := connection.copy()
+	require.NoError(t, err)
+	require.True(t, read)
+	require.Equal(t, int64(1000), written)
+	require.Equal(t, int64(0), shaper.space())
+
+	_, read, err = connection.copy()
+	require.NoError(t, err)
+	require.False(t, read)
+}
+
+// Ensure that each chunk is only released once its delay has passed.
+func TestShapedWriter_Delay(t *testing.T) {
+	var output bytes.Buffer
+	w := newShapedWriter(&output, ConfigConnection{Delay: 100})
+	require.Equal(t, int64(-1), w.space())
+	_, err := w.Write([]byte("delayed"))
+	require.NoError(t, err)
+
+	released, err := w.release()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), released)
+
+	time.Sleep(110 * time.Millisecond)
+	released, err = w.release()
+	require.NoError(t, err)
+	require.Equal(t, int64(len("delayed")), released)
+	require.Equal(t, "delayed", output.String())
+
+	require.Nil(t, newShapedWriter(&output, ConfigConnection{}))
+}
+
+func TestConfigRateLimit_Validate(t *testing.T) {
+	connection := ConfigConnection{ReaderID: "r", WriterID: "w"}
+	require.NoError(t, ConfigRateLimit{BytesPerSecond: 960}.validate(connection))
+	require.NoError(t, ConfigRateLimit{MessagesPerSecond: 10, Burst: 5}.validate(connection))
+	require.Error(t, ConfigRateLimit{}.validate(connection))
+	require.Error(t, ConfigRateLimit{BytesPerSecond: -1}.validate(connection))
+	require.Error(t, ConfigRateLimit{BytesPerSecond: 1, Burst: -1}.validate(connection))
+}
+
+// Ensure that a file is written at the configured rate, only reading as much of the file as can be queued.
+func TestApplyConfig_RateLimit(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	output := filepath.Join(dir, "output.txt")
+	content := strings.Repeat("0123456789", 200)
+	require.NoError(t, os.WriteFile(input, []byte(content), 0666))
+
+	config := Config{
+		Connections: []ConfigConnection{
+			{ReaderID: "in", WriterID: "out", RateLimit: &ConfigRateLimit{BytesPerSecond: 4000, Burst: 400}},
+		},
+		Nodes: ConfigNodes{
+			Files: []ConfigFile{{ID: "in", Path: input}, {ID: "out", Path: output}},
+		},
+		Settings: ConfigSettings{Timeout: 1},
+	}
+	require.NoError(t, config.Initialise())
+	defer config.Close()
+	require.Len(t, config.Conns[0].shapers, 1)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	start := time.Now()
+	require.NoError(t, applyConfig(ctx, cancelFunc, config.Conns, config.Settings))
+	// 1600 bytes are released over 400ms after the burst, followed by the idle timeout
+	require.GreaterOrEqual(t, time.Since(start), 1300*time.Millisecond)
+
+	read, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, content, string(read))
+}
+
+// Ensure that the rate limit and delay are validated.
+func TestConfig_RateLimitValidation(t *testing.T) {
+	config := Config{Connections: []ConfigConnection{{ReaderID: StdIn, WriterID: StdOut, Delay: -1}}}
+	require.Error(t, config.Initialise())
+
+	config = Config{Connections: []ConfigConnection{{ReaderID: StdIn, WriterID: StdOut, RateLimit: &ConfigRateLimit{}}}}
+	require.Error(t, config.Initialise())
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"io"
+
+	"github.com/Kilemonn/flow/clientinfo"
+)
+
+// Writes the replies of a bridged node back to a reader that accepts clients (see [clientAcceptor]). The replies are
+// written only to the client that most recently sent data through the bridge, which is recorded by a
+// [requestWriter]. Until a client has sent data, or once that client disconnects, replies are written to all clients.
+type replyWriter struct {
+	writer    io.WriteCloser
+	requester *clientinfo.Info
+}
+
+func newReplyWriter(writer io.WriteCloser) *replyWriter {
+	return &replyWriter{writer: writer}
+}
+
+// [io.Writer.Write]
+func (w *replyWriter) Write(b []byte) (int, error) {
+	if w.requester == nil {
+		return w.writer.Write(b)
+	}
+	return clientinfo.Write(w.writer, *w.requester, b)
+}
+
+// [io.Closer.Close], the writer is closed as a reader so this does nothing.
+func (w *replyWriter) Close() error {
+	return nil
+}
+
+// Wraps the writers of a reader that accepts clients, and records the client that each write came from as the
+// requester of the reader's [replyWriter].
+type requestWriter struct {
+	writer io.Writer
+	reply  *replyWriter
+}
+
+// [io.Writer.Write]
+func (w requestWriter) Write(b []byte) (int, error) {
+	return w.writer.Write(b)
+}
+
+// [clientinfo.Writer.WriteClient]
+func (w requestWriter) WriteClient(info clientinfo.Info, b []byte) (int, error) {
+	w.reply.requester = &info
+	return clientinfo.Write(w.writer, info, b)
+}
+
+// [clientinfo.Writer.CloseClient]
+func (w requestWriter) CloseClient(info clientinfo.Info) error {
+	if w.reply.requester != nil && w.reply.requester.ID == info.ID {
+		w.reply.requester = nil
+	}
+	return clientinfo.Close(w.writer, []clientinfo.Info{info})
+}
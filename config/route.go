@@ -0,0 +1,286 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Kilemonn/flow/clientinfo"
+	"github.com/Kilemonn/flow/framing"
+	"github.com/Kilemonn/flow/socket"
+)
+
+// The framing of the data matched by a [ConfigRoute]
+const (
+	// Each line is matched separately, the newline is kept at the end of the line
+	RouteFramingLine = "line"
+	// Each chunk of data (or datagram) read from the reader is matched as a whole
+	RouteFramingChunk = "chunk"
+)
+
+// Which of the matching [ConfigRouteRule]s of a [ConfigRoute] the data is written to
+const (
+	RouteModeFirst = "first"
+	RouteModeAll   = "all"
+)
+
+// Routes each line or chunk of data read from the reader of a [ConfigConnection] to the writers of the rules that it
+// matches, instead of writing all the data to a single writer.
+type ConfigRoute struct {
+	// Either "line" (the default) or "chunk", see [RouteFramingLine] and [RouteFramingChunk]
+	Framing string
+	// Either "first" (the default) to write the data to the writer of the first matching rule, or "all" to write the
+	// data to the writer of every matching rule
+	Mode  string
+	Rules []ConfigRouteRule
+	// The ID of the writer of the data that matches no rule, the data is discarded when this is not set
+	Default string
+}
+
+// A rule of a [ConfigRoute], the data matches the rule when it matches all of the rule's criteria.
+type ConfigRouteRule struct {
+	// The ID of the writer of the data that matches this rule
+	WriterID string
+	// A regular expression that matches the data
+	Regex string
+	// The data starts with this prefix
+	Prefix string
+	// The byte at [ConfigRouteRule.Offset] of the data equals this value
+	Byte   *int
+	Offset int
+	// The data is a JSON object whose field equals [ConfigRouteRule.JSONValue], nested fields are separated by "."
+	// (e.g. "event.level")
+	JSONField string
+	// The value of the JSON field, values that are not strings are compared as JSON (e.g. "true" or "3")
+	JSONValue string
+}
+
+func (c ConfigRoute) validate(connection ConfigConnection) error {
+	if connection.WriterID != "" || connection.Bridge {
+		return fmt.Errorf("route of connection from reader [%s] cannot also set a \"writerid\" or be a bridge", connection.ReaderID)
+	}
+	if c.Framing != "" && c.Framing != RouteFramingLine && c.Framing != RouteFramingChunk {
+		return fmt.Errorf("route of connection from reader [%s] has an invalid framing [%s], expected one of [%s, %s]", connection.ReaderID, c.Framing, RouteFramingLine, RouteFramingChunk)
+	}
+	if c.Mode != "" && c.Mode != RouteModeFirst && c.Mode != RouteModeAll {
+		return fmt.Errorf("route of connection from reader [%s] has an invalid mode [%s], expected one of [%s, %s]", connection.ReaderID, c.Mode, RouteModeFirst, RouteModeAll)
+	}
+	if len(c.Rules) == 0 {
+		return fmt.Errorf("route of connection from reader [%s] has no rules", connection.ReaderID)
+	}
+
+	for i, rule := range c.Rules {
+		if rule.WriterID == "" {
+			return fmt.Errorf("rule [%d] of the route of connection from reader [%s] has no \"writerid\"", i, connection.ReaderID)
+		}
+		if rule.Regex == "" && rule.Prefix == "" && rule.Byte == nil && rule.JSONField == "" {
+			return fmt.Errorf("rule [%d] of the route of connection from reader [%s] must set at least one of \"regex\", \"prefix\", \"byte\" or \"jsonfield\"", i, connection.ReaderID)
+		}
+		if rule.Regex != "" {
+			_, err := regexp.Compile(rule.Regex)
+			if err != nil {
+				return fmt.Errorf("rule [%d] of the route of connection from reader [%s] has an invalid regex [%s] with error: [%s]", i, connection.ReaderID, rule.Regex, err.Error())
+			}
+		}
+		if rule.Byte != nil && (*rule.Byte < 0 || *rule.Byte > 255 || rule.Offset < 0) {
+			return fmt.Errorf("rule [%d] of the route of connection from reader [%s] has a byte that is not between 0 and 255 or a negative offset", i, connection.ReaderID)
+		}
+	}
+	return nil
+}
+
+// Returns the IDs of the writers that the route writes to, without duplicates.
+func (c ConfigRoute) writerIDs() []string {
+	ids := []string{}
+	for _, rule := range c.Rules {
+		if !slices.Contains(ids, rule.WriterID) {
+			ids = append(ids, rule.WriterID)
+		}
+	}
+	if c.Default != "" && !slices.Contains(ids, c.Default) {
+		ids = append(ids, c.Default)
+	}
+	return ids
+}
+
+// Returns true if the data matches all the criteria of the rule. The regex is provided already compiled.
+func (r ConfigRouteRule) matches(data []byte, regex *regexp.Regexp) bool {
+	if regex != nil && !regex.Match(data) {
+		return false
+	}
+	if r.Prefix != "" && !bytes.HasPrefix(data, []byte(r.Prefix)) {
+		return false
+	}
+	if r.Byte != nil && (r.Offset >= len(data) || data[r.Offset] != byte(*r.Byte)) {
+		return false
+	}
+	if r.JSONField != "" && !jsonFieldEquals(data, r.JSONField, r.JSONValue) {
+		return false
+	}
+	return true
+}
+
+// Returns true if the data is a JSON object with the field (separated by "." for nested fields) equal to the value.
+func jsonFieldEquals(data []byte, field string, value string) bool {
+	var object any
+	if json.Unmarshal(data, &object) != nil {
+		return false
+	}
+	for _, name := range strings.Split(field, ".") {
+		fields, ok := object.(map[string]any)
+		if !ok {
+			return false
+		}
+		if object, ok = fields[name]; !ok {
+			return false
+		}
+	}
+
+	if s, ok := object.(string); ok {
+		return s == value
+	}
+	encoded, err := json.Marshal(object)
+	return err == nil && string(encoded) == value
+}
+
+// Writes each line or chunk of data written to it to the writers of the [ConfigRoute] rules that it matches, along
+// with the client or datagram it was written with. For line framing, a partial line is buffered until the rest of the
+// line is written or the writer is closed, and the lines of each client are buffered separately.
+type routeWriter struct {
+	route   ConfigRoute
+	writers map[string]io.Writer
+	regexes []*regexp.Regexp
+	// The lines of the data and datagrams written without a client
+	lines   framing.LineFramer
+	clients map[int]*clientLines
+}
+
+// The lines written by a client of the reader.
+type clientLines struct {
+	info  clientinfo.Info
+	lines framing.LineFramer
+}
+
+// Returns a writer for the route, which writes to the provided writers by their ID.
+func newRouteWriter(route ConfigRoute, writers map[string]io.Writer) *routeWriter {
+	w := &routeWriter{route: route, writers: writers, regexes: make([]*regexp.Regexp, len(route.Rules)), clients: make(map[int]*clientLines)}
+	for i, rule := range route.Rules {
+		if rule.Regex != "" {
+			// The regex is checked when the config is validated
+			w.regexes[i] = regexp.MustCompile(rule.Regex)
+		}
+	}
+	return w
+}
+
+// [io.Writer.Write]
+func (w *routeWriter) Write(b []byte) (int, error) {
+	return len(b), w.frame(&w.lines, b, dataSource{})
+}
+
+// [clientinfo.Writer.WriteClient]
+func (w *routeWriter) WriteClient(info clientinfo.Info, b []byte) (int, error) {
+	client, exists := w.clients[info.ID]
+	if !exists {
+		client = &clientLines{info: info}
+		w.clients[info.ID] = client
+	}
+	return len(b), w.frame(&client.lines, b, dataSource{client: &info})
+}
+
+// [clientinfo.Writer.CloseClient], routes the buffered partial line of the client before each writer is notified.
+func (w *routeWriter) CloseClient(info clientinfo.Info) error {
+	var err error
+	if client, exists := w.clients[info.ID]; exists {
+		delete(w.clients, info.ID)
+		err = w.routePartial(&client.lines, dataSource{client: &info})
+	}
+	for _, id := range slices.Sorted(maps.Keys(w.writers)) {
+		e := clientinfo.Close(w.writers[id], []clientinfo.Info{info})
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// [socket.DatagramWriter.WriteDatagram], each line or chunk is written as a datagram with the metadata of the datagram
+// it was completed by.
+func (w *routeWriter) WriteDatagram(d socket.Datagram) (int, error) {
+	return len(d.Data), w.frame(&w.lines, d.Data, dataSource{datagram: &d})
+}
+
+// Routes the data as a single chunk, or each line once it is complete, along with its source.
+func (w *routeWriter) frame(lines *framing.LineFramer, b []byte, source dataSource) error {
+	if w.route.Framing == RouteFramingChunk {
+		return w.dispatch(b, source)
+	}
+	for _, line := range lines.Write(b) {
+		err := w.dispatch(line.Data, source)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Routes the buffered partial line, if there is one.
+func (w *routeWriter) routePartial(lines *framing.LineFramer, source dataSource) error {
+	line, ok := lines.Flush()
+	if !ok {
+		return nil
+	}
+	return w.dispatch(line.Data, source)
+}
+
+// Writes the frame to the writers of the matching rules, or the default writer if no rule matches.
+func (w *routeWriter) dispatch(frame []byte, source dataSource) error {
+	targets := []string{}
+	for i, rule := range w.route.Rules {
+		if rule.matches(frame, w.regexes[i]) && !slices.Contains(targets, rule.WriterID) {
+			targets = append(targets, rule.WriterID)
+			if w.route.Mode != RouteModeAll {
+				break
+			}
+		}
+	}
+	if len(targets) == 0 && w.route.Default != "" {
+		targets = append(targets, w.route.Default)
+	}
+
+	for _, target := range targets {
+		writer, ok := w.writers[target]
+		if !ok {
+			continue
+		}
+		_, err := source.write(writer, frame)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Partial lines are only routed once they are complete, see [routeWriter.Close].
+func (w *routeWriter) Flush() error {
+	return nil
+}
+
+// [io.Closer.Close], routes the buffered partial lines, the clients' in the order of their IDs.
+func (w *routeWriter) Close() error {
+	err := w.routePartial(&w.lines, dataSource{})
+	for _, id := range slices.Sorted(maps.Keys(w.clients)) {
+		client := w.clients[id]
+		e := w.routePartial(&client.lines, dataSource{client: &client.info})
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	clear(w.clients)
+	return err
+}
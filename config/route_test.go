@@ -0,0 +1,183 @@
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Kilemonn/flow/clientinfo"
+	"github.com/Kilemonn/flow/socket"
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that lines split across writes are routed once complete, to the first matching rule or the default writer.
+func TestRouteWriter_Lines(t *testing.T) {
+	var alerts, warnings, logs bytes.Buffer
+	route := ConfigRoute{
+		Rules: []ConfigRouteRule{
+			{WriterID: "alerts", Prefix: "ERR"},
+			{WriterID: "warnings", Regex: "(?i)warn|ERR"},
+		},
+		Default: "logs",
+	}
+	w := newRouteWriter(route, map[string]io.Writer{"alerts": &alerts, "warnings": &warnings, "logs": &logs})
+
+	for _, chunk := range []string{"ER", "R disk full\nWarning: hot\nok", " fine\n", "partial"} {
+		n, err := w.Write([]byte(chunk))
+		require.NoError(t, err)
+		require.Equal(t, len(chunk), n)
+	}
+	require.Equal(t, "ERR disk full\n", alerts.String())
+	require.Equal(t, "Warning: hot\n", warnings.String())
+	require.Equal(t, "ok fine\n", logs.String())
+
+	require.NoError(t, w.Flush())
+	require.Equal(t, "ok fine\n", logs.String())
+	require.NoError(t, w.Close())
+	require.Equal(t, "ok fine\npartial", logs.String())
+}
+
+// Ensure that the partial lines of each client are buffered separately, and routed with their client before the
+// writers are notified that the client closed.
+func TestRouteWriter_Clients(t *testing.T) {
+	alerts := &sourceRecorder{}
+	logs := &sourceRecorder{}
+	route := ConfigRoute{Rules: []ConfigRouteRule{{WriterID: "alerts", Prefix: "ERR"}}, Default: "logs"}
+	w := newRouteWriter(route, map[string]io.Writer{"alerts": alerts, "logs": logs})
+
+	first, second := clientinfo.Info{ID: 1}, clientinfo.Info{ID: 2}
+	for _, write := range []struct {
+		info clientinfo.Info
+		data string
+	}{{first, "ER"}, {second, "ok\nER"}, {first, "R one\n"}, {second, "R two"}} {
+		_, err := w.WriteClient(write.info, []byte(write.data))
+		require.NoError(t, err)
+	}
+	_, err := w.WriteDatagram(socket.Datagram{Data: []byte("ERR three\n")})
+	require.NoError(t, err)
+	require.NoError(t, w.CloseClient(second))
+
+	require.Equal(t, []string{"client 1 ERR one\n", "datagram 10", "client 2 ERR two", "close 2"}, alerts.writes)
+	require.Equal(t, []string{"client 2 ok\n", "close 2"}, logs.writes)
+}
+
+// Ensure that each chunk is routed as a whole to every matching rule, and discarded if nothing matches without a
+// default writer.
+func TestRouteWriter_ChunksToAll(t *testing.T) {
+	first := &chunkRecorder{}
+	second := &chunkRecorder{}
+	stx := 0x02
+	route := ConfigRoute{
+		Framing: RouteFramingChunk,
+		Mode:    RouteModeAll,
+		Rules: []ConfigRouteRule{
+			{WriterID: "first", Byte: &stx, Offset: 1},
+			{WriterID: "second", JSONField: "event.level", JSONValue: "error"},
+			{WriterID: "second", JSONField: "code", JSONValue: "3"},
+		},
+	}
+	w := newRouteWriter(route, map[string]io.Writer{"first": first, "second": second})
+
+	for _, chunk := range []string{"\x00\x02data\n", `{"event": {"level": "error"}}`, `{"code": 3}`, `{"code": "3"}`, "\x02", "not json"} {
+		_, err := w.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+	require.Equal(t, []string{"\x00\x02data\n"}, first.chunks)
+	require.Equal(t, []string{`{"event": {"level": "error"}}`, `{"code": 3}`, `{"code": "3"}`}, second.chunks)
+}
+
+// Ensure that a file is routed line by line to different files.
+func TestApplyConfig_Route(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	alerts := filepath.Join(dir, "alerts.txt")
+	logs := filepath.Join(dir, "logs.txt")
+	require.NoError(t, os.WriteFile(input, []byte("boot\nERR overheating\nrunning\nERR fan\n"), 0666))
+
+	config := Config{
+		Connections: []ConfigConnection{
+			{ReaderID: "in", Route: &ConfigRoute{Rules: []ConfigRouteRule{{WriterID: "alerts", Prefix: "ERR"}}, Default: "logs"}},
+		},
+		Nodes: ConfigNodes{
+			Files: []ConfigFile{{ID: "in", Path: input}, {ID: "alerts", Path: alerts}, {ID: "logs", Path: logs}},
+		},
+		Settings: ConfigSettings{
+			ExitConditions: []ConfigExitCondition{{ReaderID: "in", EOF: true}},
+		},
+	}
+	require.NoError(t, config.Initialise())
+	defer config.Close()
+	require.Len(t, config.Conns, 1)
+	require.Equal(t, []string{"alerts", "logs"}, config.Conns[0].WriterIds)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	require.NoError(t, applyConfig(ctx, cancelFunc, config.Conns, config.Settings))
+
+	read, err := os.ReadFile(alerts)
+	require.NoError(t, err)
+	require.Equal(t, "ERR overheating\nERR fan\n", string(read))
+	read, err = os.ReadFile(logs)
+	require.NoError(t, err)
+	require.Equal(t, "boot\nrunning\n", string(read))
+}
+
+// Ensure that the data of a route is decompressed once before it is routed, so the rules match the decompressed lines
+// and a line is not split across the writers.
+func TestApplyConfig_RouteDecompress(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.gz")
+	alerts := filepath.Join(dir, "alerts.txt")
+	logs := filepath.Join(dir, "logs.txt")
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write([]byte("boot\nERR overheating\nrunning\nERR fan\n"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, os.WriteFile(input, compressed.Bytes(), 0666))
+
+	config := Config{
+		Connections: []ConfigConnection{
+			{ReaderID: "in", Decompress: "gzip", Route: &ConfigRoute{Rules: []ConfigRouteRule{{WriterID: "alerts", Prefix: "ERR"}}, Default: "logs"}},
+		},
+		Nodes: ConfigNodes{
+			Files: []ConfigFile{{ID: "in", Path: input}, {ID: "alerts", Path: alerts}, {ID: "logs", Path: logs}},
+		},
+		Settings: ConfigSettings{
+			ExitConditions: []ConfigExitCondition{{ReaderID: "in", EOF: true}},
+		},
+	}
+	require.NoError(t, config.Initialise())
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	require.NoError(t, applyConfig(ctx, cancelFunc, config.Conns, config.Settings))
+	require.NoError(t, config.Close())
+
+	read, err := os.ReadFile(alerts)
+	require.NoError(t, err)
+	require.Equal(t, "ERR overheating\nERR fan\n", string(read))
+	read, err = os.ReadFile(logs)
+	require.NoError(t, err)
+	require.Equal(t, "boot\nrunning\n", string(read))
+}
+
+func TestConfigRoute_Validate(t *testing.T) {
+	connection := ConfigConnection{ReaderID: "in"}
+	rules := []ConfigRouteRule{{WriterID: "out", Prefix: "a"}}
+	invalidByte := 256
+
+	require.NoError(t, ConfigRoute{Rules: rules}.validate(connection))
+	require.Error(t, ConfigRoute{Rules: rules}.validate(ConfigConnection{ReaderID: "in", WriterID: "out"}))
+	require.Error(t, ConfigRoute{Rules: rules}.validate(ConfigConnection{ReaderID: "in", Bridge: true}))
+	require.Error(t, ConfigRoute{}.validate(connection))
+	require.Error(t, ConfigRoute{Rules: rules, Framing: "frame"}.validate(connection))
+	require.Error(t, ConfigRoute{Rules: rules, Mode: "any"}.validate(connection))
+	require.Error(t, ConfigRoute{Rules: []ConfigRouteRule{{Prefix: "a"}}}.validate(connection))
+	require.Error(t, ConfigRoute{Rules: []ConfigRouteRule{{WriterID: "out"}}}.validate(connection))
+	require.Error(t, ConfigRoute{Rules: []ConfigRouteRule{{WriterID: "out", Regex: "("}}}.validate(connection))
+	require.Error(t, ConfigRoute{Rules: []ConfigRouteRule{{WriterID: "out", Byte: &invalidByte}}}.validate(connection))
+}
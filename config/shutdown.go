@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// A reader that accepts clients and can stop accepting new clients while still reading from its current clients,
+// e.g. [socket.TCPTimeoutReader].
+type acceptStopper interface {
+	StopAccepting() error
+}
+
+// A writer that buffers data, e.g. [bidetwriter.BidetWriter].
+type flusher interface {
+	Flush() error
+}
+
+// Shuts down the flow once [applyConfig] has returned. New clients are no longer accepted, the data still available
+// from the readers is copied to their writers for up to the provided timeout, the compressed streams are ended, the
+// writers are flushed and then the readers are closed before the writers. The nodes are stopped, flushed and closed in
+// the order of their IDs.
+func (c Config) Shutdown(drainTimeout time.Duration) error {
+	for _, id := range slices.Sorted(maps.Keys(c.readers)) {
+		if stopper, ok := c.readers[id].(acceptStopper); ok {
+			err := stopper.StopAccepting()
+			if err != nil {
+				fmt.Printf("Failed to stop accepting clients on reader [%s]. Error: [%s].\n", id, err.Error())
+			}
+		}
+	}
+
+	c.drain(drainTimeout)
+
+	for _, id := range slices.Sorted(maps.Keys(c.writers)) {
+		if f, ok := c.writers[id].(flusher); ok {
+			err := f.Flush()
+			if err != nil {
+				fmt.Printf("Failed to flush writer [%s]. Error: [%s].\n", id, err.Error())
+			}
+		}
+	}
+
+	return c.Close()
+}
+
+// Copies the data still available from each reader to its writers, until no reader has any more data and no data is
+// queued by a rate limit or delay, or the timeout is reached. Once no reader has any more data the readers that hold
+// back data (e.g. a [merge.Merger]) are flushed and drained, then the compression stages are closed and the end of
+// their compressed streams is written before returning.
+func (c Config) drain(timeout time.Duration) {
+	if timeout <= 0 {
+		c.closeCodecs()
+		return
+	}
+
+	deadline := time.Now().Add(timeout)
+	readersFlushed := false
+	codecsClosed := false
+	for time.Now().Before(deadline) {
+		drained := int64(0)
+		pending := false
+		for _, connection := range c.Conns {
+			_, queued, err := connection.release()
+			if err != nil {
+				fmt.Printf("Error occurred when writing queued content from reader [%s] to writer(s) [%s]. Error: [%s]\n", connection.ReaderId, connection.WriterIds, err.Error())
+			}
+			pending = pending || queued
+			if codecsClosed {
+				continue
+			}
+
+			written, _, err := connection.copy()
+			if err != nil {
+				fmt.Printf("Error occurred when draining content from reader [%s] to writer(s) [%s]. Error: [%s]\n", connection.ReaderId, connection.WriterIds, err.Error())
+			}
+			if written > 0 {
+				fmt.Printf("Drained [%d] bytes from reader [%s] to writer(s) [%s].\n", written, connection.ReaderId, connection.WriterIds)
+			}
+			drained += written
+		}
+
+		if drained == 0 && !pending {
+			if codecsClosed {
+				return
+			}
+			if !readersFlushed {
+				c.flushReaders()
+				readersFlushed = true
+				continue
+			}
+			c.closeCodecs()
+			codecsClosed = true
+			continue
+		}
+		// Wait for more of the queued data to become due
+		if drained == 0 {
+			time.Sleep(minPollInterval)
+		}
+	}
+	fmt.Printf("Drain timeout of [%s] reached with data still available.\n", timeout)
+	if !codecsClosed {
+		c.closeCodecs()
+	}
+}
+
+// Flushes the readers that hold back data until more is written to them, so it can be drained.
+func (c Config) flushReaders() {
+	for _, id := range slices.Sorted(maps.Keys(c.readers)) {
+		if f, ok := c.readers[id].(flusher); ok {
+			err := f.Flush()
+			if err != nil {
+				fmt.Printf("Failed to flush reader [%s]. Error: [%s].\n", id, err.Error())
+			}
+		}
+	}
+}
+
+// Closes the compression stages of each connection, see [Connection.closeCodecs].
+func (c Config) closeCodecs() {
+	for _, connection := range c.Conns {
+		err := connection.closeCodecs()
+		if err != nil {
+			fmt.Printf("Failed to end the compressed content from reader [%s] to writer(s) [%s]. Error: [%s].\n", connection.ReaderId, connection.WriterIds, err.Error())
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that the data sent by accepted clients before the shutdown is drained to the writers, while new clients are
+// no longer accepted.
+func TestShutdown_DrainsAcceptedClients(t *testing.T) {
+	socketPort := uint16(64624)
+	output := filepath.Join(t.TempDir(), "output.txt")
+	config := Config{
+		Connections: []ConfigConnection{{ReaderID: "tcp", WriterID: "file"}},
+		Nodes: ConfigNodes{
+			Sockets: []ConfigSocket{
+				{
+					ID:       "tcp",
+					Protocol: "tcp",
+					Port:     socketPort,
+					Address:  "127.0.0.1",
+				},
+			},
+			Files: []ConfigFile{{ID: "file", Path: output}},
+		},
+	}
+	require.NoError(t, config.Initialise())
+
+	client, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", socketPort))
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write([]byte("first"))
+	require.NoError(t, err)
+
+	// Accept the client and read the data it has sent so far
+	written, err := io.Copy(config.Conns[0].Writer, config.Conns[0].Reader)
+	require.NoError(t, err)
+	require.Equal(t, int64(len("first")), written)
+
+	_, err = client.Write([]byte("second"))
+	require.NoError(t, err)
+	// The error of closing the nodes is ignored, since stdin may have already been closed by another test
+	config.Shutdown(time.Second)
+
+	_, err = net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", socketPort))
+	require.Error(t, err)
+
+	read, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "firstsecond", string(read))
+}
+
+func TestConfigSettings_DrainTimeout(t *testing.T) {
+	require.Equal(t, DefaultDrainTimeout, ConfigSettings{}.drainTimeout())
+	require.Equal(t, 2*time.Second, ConfigSettings{DrainTimeout: 2}.drainTimeout())
+	require.Equal(t, time.Duration(0), ConfigSettings{DrainTimeout: -1}.drainTimeout())
+}
+
+type orderedCloser struct {
+	id     string
+	closed *[]string
+}
+
+func (c orderedCloser) Read(b []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (c orderedCloser) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (c orderedCloser) Close() error {
+	*c.closed = append(*c.closed, c.id)
+	return nil
+}
+
+// Ensure that the readers are closed before the writers, each in the order of their IDs.
+func TestConfig_CloseOrder(t *testing.T) {
+	closed := []string{}
+	config := Config{
+		readers: map[string]io.ReadCloser{},
+		writers: map[string]io.WriteCloser{},
+	}
+	for _, id := range []string{"c", "a", "d", "b"} {
+		config.readers["reader-"+id] = orderedCloser{id: "reader-" + id, closed: &closed}
+		config.writers["writer-"+id] = orderedCloser{id: "writer-" + id, closed: &closed}
+	}
+
+	require.NoError(t, config.Shutdown(0))
+	require.Equal(t, []string{"reader-a", "reader-b", "reader-c", "reader-d", "writer-a", "writer-b", "writer-c", "writer-d"}, closed)
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Kilemonn/flow/sink"
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that the null and counter writers are accessible without defining any nodes, and count the data written.
+func TestApplyConfig_NullAndCounter(t *testing.T) {
+	config := Config{
+		Connections: []ConfigConnection{
+			{ReaderID: "generator", WriterID: Null},
+			{ReaderID: "generator", WriterID: Counter},
+		},
+		Nodes: ConfigNodes{
+			Generators: []ConfigGenerator{{ID: "generator", Mode: "repeat", Data: "0123456789", Count: 5}},
+		},
+		Settings: ConfigSettings{
+			ExitConditions: []ConfigExitCondition{{ReaderID: "generator", EOF: true}},
+		},
+	}
+	require.NoError(t, config.Initialise())
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	require.NoError(t, applyConfig(ctx, cancelFunc, config.Conns, config.Settings))
+
+	require.Equal(t, int64(50), config.writers[Null].(*sink.NullWriter).Bytes())
+	counter := config.writers[Counter].(*sink.CounterWriter)
+	require.Equal(t, []string{"generator"}, counter.ReaderIDs())
+	require.Equal(t, int64(50), counter.Totals("generator").Bytes)
+	require.Equal(t, int64(5), counter.Totals("generator").Messages)
+	// The error of closing the nodes is ignored, since stdin may have already been closed by another test
+	config.Close()
+}
+
+// Ensure that the null and counter IDs are reserved, and can only be written to.
+func TestConfig_NullAndCounterReserved(t *testing.T) {
+	for _, config := range []Config{
+		{Nodes: ConfigNodes{Files: []ConfigFile{{ID: Null, Path: "null.txt"}}}},
+		{Nodes: ConfigNodes{Monitors: []ConfigMonitor{{ID: Counter}}}},
+		{Connections: []ConfigConnection{{ReaderID: Counter, WriterID: StdOut}}},
+		{Connections: []ConfigConnection{{ReaderID: StdIn, WriterID: Null, Bridge: true}}},
+	} {
+		err := config.Initialise()
+		require.Error(t, err)
+		require.Equal(t, ExitCodeConfigError, ExitCode(err))
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Kilemonn/flow/framing"
+)
+
+// The formats of the timestamp added by a [ConfigTimestamp]
+const (
+	// The local time the line started to be read, e.g. "2024-05-01T13:04:05.123+10:00"
+	TimestampFormatISO8601 = "iso8601"
+	// The seconds since the flow started when the line started to be read, e.g. "12.345"
+	TimestampFormatRelative = "relative"
+)
+
+// The layout of [TimestampFormatISO8601] timestamps
+const iso8601Layout = "2006-01-02T15:04:05.000Z07:00"
+
+// Adds a timestamp, and optionally the reader ID, to the start of each line of data read from the reader of a
+// [ConfigConnection] before it is written to the writer.
+type ConfigTimestamp struct {
+	// Either "iso8601" (the default) or "relative", see [TimestampFormatISO8601] and [TimestampFormatRelative]
+	Format string
+	// Adds the ID of the reader after the timestamp, e.g. "[Serial1]"
+	ReaderID bool
+}
+
+func (c ConfigTimestamp) validate(connection ConfigConnection) error {
+	if c.Format != "" && c.Format != TimestampFormatISO8601 && c.Format != TimestampFormatRelative {
+		return fmt.Errorf("connection from [%s] to [%s] has an invalid timestamp format [%s], expected one of [%s, %s]", connection.ReaderID, connection.WriterID, c.Format, TimestampFormatISO8601, TimestampFormatRelative)
+	}
+	return nil
+}
+
+// Returns the prefix of a line that started to be read at the provided time.
+func (c ConfigTimestamp) prefix(readerID string, start time.Time, read time.Time) []byte {
+	var prefix string
+	if c.Format == TimestampFormatRelative {
+		prefix = fmt.Sprintf("%.3f", read.Sub(start).Seconds())
+	} else {
+		prefix = read.Format(iso8601Layout)
+	}
+	if c.ReaderID {
+		prefix += fmt.Sprintf(" [%s]", readerID)
+	}
+	return []byte(prefix + " ")
+}
+
+// Writes each line written to it with the prefix of its [ConfigTimestamp]. The lines are buffered until they are
+// complete, so a line that is split across multiple reads is prefixed once with the time its first part was read, and
+// is written to the writer in a single write. A partial line is written once the reader is idle, and the rest of the
+// line is written without a prefix.
+type linePrefixWriter struct {
+	timestamp ConfigTimestamp
+	readerID  string
+	writer    io.Writer
+	start     time.Time
+	now       func() time.Time
+	lines     framing.LineFramer
+	// When the partial line started to be read
+	read time.Time
+}
+
+// Returns a writer that prefixes the lines read from the reader with the provided ID.
+func newLinePrefixWriter(timestamp ConfigTimestamp, readerID string, writer io.Writer) *linePrefixWriter {
+	return &linePrefixWriter{timestamp: timestamp, readerID: readerID, writer: writer, start: time.Now(), now: time.Now}
+}
+
+// [io.Writer.Write]
+func (w *linePrefixWriter) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	now := w.now()
+	if !w.lines.Started() {
+		w.read = now
+	}
+
+	for _, line := range w.lines.Write(b) {
+		err := w.writeLine(line)
+		if err != nil {
+			return len(b), err
+		}
+		w.read = now
+	}
+	return len(b), nil
+}
+
+// Writes the line with its prefix, unless the start of the line has already been written.
+func (w *linePrefixWriter) writeLine(line framing.Line) error {
+	data := line.Data
+	if !line.Continued {
+		data = append(w.timestamp.prefix(w.readerID, w.start, w.read), data...)
+	}
+	_, err := w.writer.Write(data)
+	return err
+}
+
+// Writes the buffered partial line with the time it started to be read, this is called while the reader is idle so the
+// line is not held back until the rest of it is read.
+func (w *linePrefixWriter) Flush() error {
+	line, ok := w.lines.Flush()
+	if !ok {
+		return nil
+	}
+	return w.writeLine(line)
+}
+
+// [io.Closer.Close], writes the buffered partial line.
+func (w *linePrefixWriter) Close() error {
+	return w.Flush()
+}
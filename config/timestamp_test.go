@@ -0,0 +1,104 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Kilemonn/flow/framing"
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that a line split across writes is prefixed once with the time its first part was written, and is written
+// as a whole.
+func TestLinePrefixWriter_Relative(t *testing.T) {
+	output := &chunkRecorder{}
+	w := newLinePrefixWriter(ConfigTimestamp{Format: TimestampFormatRelative, ReaderID: true}, "Serial1", output)
+	now := w.start
+	w.now = func() time.Time { return now }
+
+	for _, chunk := range []struct {
+		data  string
+		after time.Duration
+	}{
+		{"boo", 1500 * time.Millisecond},
+		{"t\nrea", 500 * time.Millisecond},
+		{"dy\n", 250 * time.Millisecond},
+		{"prompt> ", 0},
+	} {
+		now = now.Add(chunk.after)
+		n, err := w.Write([]byte(chunk.data))
+		require.NoError(t, err)
+		require.Equal(t, len(chunk.data), n)
+	}
+	require.Equal(t, []string{"1.500 [Serial1] boot\n", "2.000 [Serial1] ready\n"}, output.chunks)
+
+	// The partial line is written on the idle flush with the time it started to be read, and the rest of it is not
+	// prefixed again
+	now = now.Add(time.Second)
+	require.NoError(t, w.Flush())
+	require.Equal(t, "2.250 [Serial1] prompt> ", output.chunks[2])
+	_, err := w.Write([]byte("ls\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.Equal(t, []string{"1.500 [Serial1] boot\n", "2.000 [Serial1] ready\n", "2.250 [Serial1] prompt> ", "ls\n"}, output.chunks)
+}
+
+// Ensure that a line that is too long to buffer is prefixed once.
+func TestLinePrefixWriter_LongLine(t *testing.T) {
+	output := &chunkRecorder{}
+	w := newLinePrefixWriter(ConfigTimestamp{Format: TimestampFormatRelative}, "in", output)
+	w.now = func() time.Time { return w.start }
+
+	long := make([]byte, framing.MaxLine)
+	for i := range long {
+		long[i] = 'a'
+	}
+	_, err := w.Write(long)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("b\nc\n"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"0.000 " + string(long), "b\n", "0.000 c\n"}, output.chunks)
+}
+
+// Ensure that each line of a file is prefixed with an ISO8601 timestamp.
+func TestApplyConfig_Timestamp(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	output := filepath.Join(dir, "output.txt")
+	require.NoError(t, os.WriteFile(input, []byte("first\nsecond\n"), 0666))
+
+	config := Config{
+		Connections: []ConfigConnection{{ReaderID: "in", WriterID: "out", Timestamp: &ConfigTimestamp{ReaderID: true}}},
+		Nodes: ConfigNodes{
+			Files: []ConfigFile{{ID: "in", Path: input}, {ID: "out", Path: output}},
+		},
+		Settings: ConfigSettings{
+			ExitConditions: []ConfigExitCondition{{ReaderID: "in", EOF: true}},
+		},
+	}
+	require.NoError(t, config.Initialise())
+	defer config.Close()
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	require.NoError(t, applyConfig(ctx, cancelFunc, config.Conns, config.Settings))
+
+	read, err := os.ReadFile(output)
+	require.NoError(t, err)
+	matches := regexp.MustCompile(`(?m)^(\S+) \[in\] (first|second)$`).FindAllStringSubmatch(string(read), -1)
+	require.Len(t, matches, 2, string(read))
+	for _, match := range matches {
+		_, err = time.Parse(iso8601Layout, match[1])
+		require.NoError(t, err)
+	}
+}
+
+func TestConfigTimestamp_Validate(t *testing.T) {
+	connection := ConfigConnection{ReaderID: "in", WriterID: "out"}
+	require.NoError(t, ConfigTimestamp{}.validate(connection))
+	require.NoError(t, ConfigTimestamp{Format: TimestampFormatISO8601}.validate(connection))
+	require.NoError(t, ConfigTimestamp{Format: TimestampFormatRelative}.validate(connection))
+	require.Error(t, ConfigTimestamp{Format: "unix"}.validate(connection))
+}
@@ -0,0 +1,81 @@
+// Package encryption provides authenticated encryption of the data flowing through a connection. Each chunk of data is
+// sealed into its own frame, so the frames can be delimited when they are read back from a stream or datagrams.
+//
+// Each frame is a 4 byte big endian length of the rest of the frame, followed by the 8 byte random ID of its sender, the
+// 8 byte big endian sequence number of the frame, the random nonce and the sealed data. The length, sender and sequence
+// number are also authenticated as additional data.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// The supported encryption algorithms
+const (
+	AESGCM           = "aes-gcm"
+	ChaCha20Poly1305 = "chacha20-poly1305"
+)
+
+const (
+	// The size of the length at the start of each frame
+	headerSize = 4
+	// The size of the ID of the sender and the sequence number that follow the length
+	senderSize   = 8
+	sequenceSize = 8
+	// The size of the authenticated additional data at the start of each frame
+	prefixSize = headerSize + senderSize + sequenceSize
+	// The largest amount of data sealed into a single frame, larger writes are split into multiple frames
+	MaxFrameData = 64 * 1024
+)
+
+// Returns an error if the algorithm is not supported.
+func Validate(algorithm string) error {
+	switch strings.ToLower(algorithm) {
+	case AESGCM, ChaCha20Poly1305:
+		return nil
+	default:
+		return fmt.Errorf("unsupported encryption algorithm [%s], expected one of [%s, %s]", algorithm, AESGCM, ChaCha20Poly1305)
+	}
+}
+
+// ReadKeyFile reads the key from the file at the provided path. The file contains the key encoded as hex (surrounding
+// whitespace is ignored), otherwise its content is used as the raw key.
+func ReadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file [%s] with error: [%s]", path, err.Error())
+	}
+
+	if key, err := hex.DecodeString(strings.TrimSpace(string(data))); err == nil {
+		return key, nil
+	}
+	return data, nil
+}
+
+// NewAEAD returns the cipher for the algorithm with the provided key. AES-GCM accepts 16, 24 or 32 byte keys and
+// ChaCha20-Poly1305 accepts 32 byte keys.
+func NewAEAD(algorithm string, key []byte) (cipher.AEAD, error) {
+	switch strings.ToLower(algorithm) {
+	case AESGCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid [%s] key with error: [%s]", algorithm, err.Error())
+		}
+		return cipher.NewGCM(block)
+	case ChaCha20Poly1305:
+		aead, err := chacha20poly1305.New(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid [%s] key with error: [%s]", algorithm, err.Error())
+		}
+		return aead, nil
+	default:
+		return nil, Validate(algorithm)
+	}
+}
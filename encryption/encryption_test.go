@@ -0,0 +1,189 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Kilemonn/flow/socket"
+	"github.com/stretchr/testify/require"
+)
+
+var algorithms = []string{AESGCM, ChaCha20Poly1305}
+
+var key = []byte("\x00\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a\x0b\x0c\x0d\x0e\x0f0123456789abcdef")
+
+// Ensure that each write is sealed into its own frame, and the frames can be decrypted even when split across writes.
+func TestEncryptAndDecryptWriter(t *testing.T) {
+	for _, algorithm := range algorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			frames := &frameRecorder{}
+			encrypter, err := NewEncryptWriter(algorithm, key, nil, frames)
+			require.NoError(t, err)
+			for _, chunk := range []string{"first", "second"} {
+				_, err = encrypter.Write([]byte(chunk))
+				require.NoError(t, err)
+			}
+			require.Len(t, frames.frames, 2)
+			require.NotContains(t, string(frames.frames[0]), "first")
+
+			var decrypted bytes.Buffer
+			decrypter, err := NewDecryptWriter(algorithm, key, nil, &decrypted)
+			require.NoError(t, err)
+			stream := bytes.Join(frames.frames, nil)
+			for _, b := range stream {
+				_, err = decrypter.Write([]byte{b})
+				require.NoError(t, err)
+			}
+			require.NoError(t, decrypter.Close())
+			require.Equal(t, "firstsecond", decrypted.String())
+		})
+	}
+}
+
+// Ensure that writes larger than the maximum frame data are split into multiple frames.
+func TestEncryptWriter_LargeWrite(t *testing.T) {
+	frames := &frameRecorder{}
+	encrypter, err := NewEncryptWriter(AESGCM, key, nil, frames)
+	require.NoError(t, err)
+	content := strings.Repeat("a", MaxFrameData+10)
+	n, err := encrypter.Write([]byte(content))
+	require.NoError(t, err)
+	require.Equal(t, len(content), n)
+	require.Len(t, frames.frames, 2)
+
+	var decrypted bytes.Buffer
+	decrypter, err := NewDecryptWriter(AESGCM, key, nil, &decrypted)
+	require.NoError(t, err)
+	_, err = decrypter.Write(bytes.Join(frames.frames, nil))
+	require.NoError(t, err)
+	require.Equal(t, content, decrypted.String())
+}
+
+// Ensure that frames that fail authentication, or a partial frame at the end of the stream, return an error.
+func TestDecryptWriter_Invalid(t *testing.T) {
+	frames := &frameRecorder{}
+	encrypter, err := NewEncryptWriter(ChaCha20Poly1305, key, nil, frames)
+	require.NoError(t, err)
+	_, err = encrypter.Write([]byte("TestDecryptWriter_Invalid"))
+	require.NoError(t, err)
+	frame := frames.frames[0]
+
+	otherKey := bytes.Repeat([]byte{0x24}, 32)
+	decrypter, err := NewDecryptWriter(ChaCha20Poly1305, otherKey, nil, &bytes.Buffer{})
+	require.NoError(t, err)
+	_, err = decrypter.Write(frame)
+	require.Error(t, err)
+	_, err = decrypter.Write(frame)
+	require.Error(t, err)
+	require.Error(t, decrypter.Close())
+
+	decrypter, err = NewDecryptWriter(ChaCha20Poly1305, key, nil, &bytes.Buffer{})
+	require.NoError(t, err)
+	_, err = decrypter.Write(frame[:len(frame)-1])
+	require.NoError(t, err)
+	require.Error(t, decrypter.Close())
+
+	decrypter, err = NewDecryptWriter(ChaCha20Poly1305, key, nil, &bytes.Buffer{})
+	require.NoError(t, err)
+	_, err = decrypter.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	require.Error(t, err)
+}
+
+// Ensure that a replayed or reordered frame, or a frame sent by one of the senders of the decrypter, is rejected.
+func TestDecryptWriter_ReplayAndReflection(t *testing.T) {
+	frames := &frameRecorder{}
+	senders := NewSenders()
+	encrypter, err := NewEncryptWriter(AESGCM, key, senders, frames)
+	require.NoError(t, err)
+	for _, chunk := range []string{"first", "second"} {
+		_, err = encrypter.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+
+	for _, stream := range [][][]byte{
+		{frames.frames[0], frames.frames[0]},
+		{frames.frames[1], frames.frames[0]},
+	} {
+		decrypter, err := NewDecryptWriter(AESGCM, key, nil, &bytes.Buffer{})
+		require.NoError(t, err)
+		_, err = decrypter.Write(stream[0])
+		require.NoError(t, err)
+		_, err = decrypter.Write(stream[1])
+		require.ErrorContains(t, err, "replayed or reordered")
+	}
+
+	decrypter, err := NewDecryptWriter(AESGCM, key, senders, &bytes.Buffer{})
+	require.NoError(t, err)
+	_, err = decrypter.Write(frames.frames[0])
+	require.ErrorContains(t, err, "sent by this side of the bridge")
+}
+
+// Ensure that a datagram that fails to be decrypted is dropped, and the following datagrams are still decrypted.
+func TestDecryptWriter_Datagrams(t *testing.T) {
+	frames := &frameRecorder{}
+	encrypter, err := NewEncryptWriter(ChaCha20Poly1305, key, nil, frames)
+	require.NoError(t, err)
+	for _, chunk := range []string{"first", "second", "third"} {
+		_, err = encrypter.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+
+	var decrypted bytes.Buffer
+	decrypter, err := NewDecryptWriter(ChaCha20Poly1305, key, nil, &decrypted)
+	require.NoError(t, err)
+	corrupted := append([]byte(nil), frames.frames[1]...)
+	corrupted[len(corrupted)-1] ^= 0xff
+	for _, datagram := range [][]byte{frames.frames[0], corrupted, frames.frames[0], frames.frames[1][:10], frames.frames[2]} {
+		n, err := decrypter.WriteDatagram(socket.Datagram{Data: datagram})
+		require.NoError(t, err)
+		require.Equal(t, len(datagram), n)
+	}
+	require.Equal(t, "firstthird", decrypted.String())
+	require.NoError(t, decrypter.Close())
+}
+
+func TestReadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "raw.key")
+	encoded := filepath.Join(dir, "hex.key")
+	require.NoError(t, os.WriteFile(raw, key, 0600))
+	require.NoError(t, os.WriteFile(encoded, []byte(hex.EncodeToString(key)+"\n"), 0600))
+
+	for _, path := range []string{raw, encoded} {
+		read, err := ReadKeyFile(path)
+		require.NoError(t, err)
+		require.Equal(t, key, read)
+	}
+
+	_, err := ReadKeyFile(filepath.Join(dir, "missing.key"))
+	require.Error(t, err)
+}
+
+func TestNewAEAD(t *testing.T) {
+	for _, algorithm := range append(algorithms, "AES-GCM") {
+		require.NoError(t, Validate(algorithm))
+		_, err := NewAEAD(algorithm, key)
+		require.NoError(t, err)
+	}
+	require.Error(t, Validate("des"))
+	_, err := NewAEAD("des", key)
+	require.Error(t, err)
+	_, err = NewAEAD(AESGCM, key[:10])
+	require.Error(t, err)
+	_, err = NewAEAD(ChaCha20Poly1305, key[:16])
+	require.Error(t, err)
+}
+
+// Records each write as a separate frame.
+type frameRecorder struct {
+	frames [][]byte
+}
+
+func (f *frameRecorder) Write(b []byte) (int, error) {
+	f.frames = append(f.frames, append([]byte(nil), b...))
+	return len(b), nil
+}
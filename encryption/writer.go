@@ -0,0 +1,226 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/Kilemonn/flow/socket"
+)
+
+// The IDs of the encrypters on one side of a bridge. A [DecryptWriter] with the same senders rejects the frames of
+// these encrypters, so frames that are sent back to the side that encrypted them are not accepted.
+type Senders struct {
+	mu  sync.Mutex
+	ids map[uint64]bool
+}
+
+// NewSenders returns an empty set of senders.
+func NewSenders() *Senders {
+	return &Senders{ids: make(map[uint64]bool)}
+}
+
+func (s *Senders) add(id uint64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids[id] = true
+}
+
+func (s *Senders) contains(id uint64) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ids[id]
+}
+
+// Seals each chunk of data written to it into a frame, and writes each frame to [EncryptWriter.Writer] in a single
+// write so that frames are not split across datagrams. Each frame carries the random ID of the writer and the next
+// number of its sequence, so that a [DecryptWriter] can reject replayed and reordered frames.
+type EncryptWriter struct {
+	Writer   io.Writer
+	aead     cipher.AEAD
+	sender   uint64
+	sequence uint64
+}
+
+// NewEncryptWriter returns a writer that encrypts the data written to it with the algorithm and key. The ID of the
+// writer is added to the senders, which can be nil.
+func NewEncryptWriter(algorithm string, key []byte, senders *Senders, w io.Writer) (*EncryptWriter, error) {
+	aead, err := NewAEAD(algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+	var id [senderSize]byte
+	// This never returns an error, see [rand.Read]
+	rand.Read(id[:])
+	sender := binary.BigEndian.Uint64(id[:])
+	senders.add(sender)
+	return &EncryptWriter{Writer: w, aead: aead, sender: sender}, nil
+}
+
+// [io.Writer.Write], data larger than [MaxFrameData] is split into multiple frames.
+func (w *EncryptWriter) Write(b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		data := b[written:min(written+MaxFrameData, len(b))]
+		_, err := w.Writer.Write(w.seal(data))
+		if err != nil {
+			return written, err
+		}
+		written += len(data)
+	}
+	return written, nil
+}
+
+// Returns the frame of the sealed data.
+func (w *EncryptWriter) seal(data []byte) []byte {
+	w.sequence++
+	size := senderSize + sequenceSize + w.aead.NonceSize() + len(data) + w.aead.Overhead()
+	frame := make([]byte, prefixSize+w.aead.NonceSize(), headerSize+size)
+	binary.BigEndian.PutUint32(frame, uint32(size))
+	binary.BigEndian.PutUint64(frame[headerSize:], w.sender)
+	binary.BigEndian.PutUint64(frame[headerSize+senderSize:], w.sequence)
+	nonce := frame[prefixSize:]
+	// This never returns an error, see [rand.Read]
+	rand.Read(nonce)
+	return w.aead.Seal(frame, nonce, data, frame[:prefixSize])
+}
+
+// Each frame is written once it is sealed, so there is nothing to flush.
+func (w *EncryptWriter) Flush() error {
+	return nil
+}
+
+// [io.Closer.Close], [EncryptWriter.Writer] is not closed.
+func (w *EncryptWriter) Close() error {
+	return nil
+}
+
+// Opens the frames written to it and writes the decrypted data of each frame to [DecryptWriter.Writer]. Frames can be
+// split across writes, the partial frame is buffered until the rest of it is written. A frame is rejected if its
+// sender has already sent a frame with the same or a later sequence number, or if it was sent by one of the senders
+// of the writer.
+type DecryptWriter struct {
+	Writer  io.Writer
+	aead    cipher.AEAD
+	senders *Senders
+	// The sequence number of the last frame opened from each sender
+	sequences map[uint64]uint64
+	buffer    []byte
+	err       error
+}
+
+// NewDecryptWriter returns a writer that decrypts the frames written to it with the algorithm and key, rejecting the
+// frames of the senders, which can be nil.
+func NewDecryptWriter(algorithm string, key []byte, senders *Senders, w io.Writer) (*DecryptWriter, error) {
+	aead, err := NewAEAD(algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+	return &DecryptWriter{Writer: w, aead: aead, senders: senders, sequences: make(map[uint64]uint64)}, nil
+}
+
+// [io.Writer.Write], once a frame fails to be opened every following write returns the same error, since the start
+// of the next frame is unknown.
+func (w *DecryptWriter) Write(b []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	w.buffer = append(w.buffer, b...)
+	for {
+		data, size, err := w.open(w.buffer)
+		if err != nil {
+			w.err = err
+			return 0, w.err
+		}
+		if size == 0 {
+			break
+		}
+		w.buffer = w.buffer[size:]
+
+		_, err = w.Writer.Write(data)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// [socket.DatagramWriter.WriteDatagram], the datagram must contain whole frames. Unlike a stream, the next datagram
+// starts with a new frame, so a datagram with a frame that fails to be opened is dropped and the following datagrams
+// are still decrypted.
+func (w *DecryptWriter) WriteDatagram(d socket.Datagram) (int, error) {
+	data := d.Data
+	for len(data) > 0 {
+		decrypted, size, err := w.open(data)
+		if err == nil && size == 0 {
+			err = fmt.Errorf("datagram ended with a partial frame of [%d] bytes", len(data))
+		}
+		if err != nil {
+			fmt.Printf("Dropped datagram from [%s] that failed to be decrypted. Error: [%s].\n", d.Source, err.Error())
+			break
+		}
+		data = data[size:]
+
+		_, err = w.Writer.Write(decrypted)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(d.Data), nil
+}
+
+// Opens the frame at the start of the data, returning its decrypted data and the size of the frame. The size is 0 if
+// the data only contains part of a frame.
+func (w *DecryptWriter) open(b []byte) ([]byte, int, error) {
+	if len(b) < headerSize {
+		return nil, 0, nil
+	}
+	size := int(binary.BigEndian.Uint32(b))
+	overhead := senderSize + sequenceSize + w.aead.NonceSize() + w.aead.Overhead()
+	if size < overhead || size > overhead+MaxFrameData {
+		return nil, 0, fmt.Errorf("invalid encrypted frame size [%d]", size)
+	}
+	if len(b) < headerSize+size {
+		return nil, 0, nil
+	}
+
+	frame := b[:headerSize+size]
+	sender := binary.BigEndian.Uint64(frame[headerSize:])
+	sequence := binary.BigEndian.Uint64(frame[headerSize+senderSize:])
+	nonce := frame[prefixSize : prefixSize+w.aead.NonceSize()]
+	data, err := w.aead.Open(nil, nonce, frame[prefixSize+w.aead.NonceSize():], frame[:prefixSize])
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decrypt frame with error: [%s]", err.Error())
+	}
+	if w.senders.contains(sender) {
+		return nil, 0, fmt.Errorf("frame [%d] was sent by this side of the bridge", sequence)
+	}
+	if last, exists := w.sequences[sender]; exists && sequence <= last {
+		return nil, 0, fmt.Errorf("frame [%d] was replayed or reordered after frame [%d]", sequence, last)
+	}
+	w.sequences[sender] = sequence
+	return data, len(frame), nil
+}
+
+// Each frame is written once it is opened, so there is nothing to flush.
+func (w *DecryptWriter) Flush() error {
+	return nil
+}
+
+// [io.Closer.Close], returns an error if a partial frame was written. [DecryptWriter.Writer] is not closed.
+func (w *DecryptWriter) Close() error {
+	if w.err == nil && len(w.buffer) > 0 {
+		w.err = fmt.Errorf("encrypted stream ended with a partial frame of [%d] bytes", len(w.buffer))
+	}
+	return w.err
+}
@@ -52,7 +52,11 @@ func main() {
 	case MENU_OPTION_SERIAL_LS:
 		serial.SerialList()
 	case MENU_OPTION_CONFIG_APPLY:
-		config.ApplyConfigurationFromFile(configFilePath)
+		err := config.ApplyConfigurationFromFile(configFilePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
+		os.Exit(config.ExitCode(err))
 	default:
 		printHelp()
 	}
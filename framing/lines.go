@@ -0,0 +1,73 @@
+// Package framing splits the data read from a stream into lines, for the nodes and stages that handle each line on its
+// own.
+package framing
+
+import "bytes"
+
+// The longest partial line that is buffered by a [LineFramer], a longer line is returned before its newline is written
+const MaxLine = 64 * 1024
+
+// A line, or part of a line, returned by a [LineFramer].
+type Line struct {
+	Data []byte
+	// Whether the start of the line has already been returned, because it was too long to buffer or was flushed
+	Continued bool
+}
+
+// Complete returns true if this is the end of the line, i.e. it ends with a newline.
+func (l Line) Complete() bool {
+	return len(l.Data) > 0 && l.Data[len(l.Data)-1] == '\n'
+}
+
+// Splits the data written to it into lines. A partial line is buffered until the rest of it is written, or it reaches
+// [MaxLine] or is flushed. The rest of a line whose start was already returned is returned as a continued [Line].
+type LineFramer struct {
+	partial   []byte
+	continued bool
+}
+
+// Started returns true if part of a line has been written but its newline has not.
+func (f *LineFramer) Started() bool {
+	return len(f.partial) > 0 || f.continued
+}
+
+// Buffered returns true if a partial line is buffered.
+func (f *LineFramer) Buffered() bool {
+	return len(f.partial) > 0
+}
+
+// Write adds the data to the partial line, and returns each complete line including its newline. The partial line is
+// also returned once it reaches [MaxLine].
+func (f *LineFramer) Write(b []byte) []Line {
+	lines := []Line{}
+	f.partial = append(f.partial, b...)
+	for {
+		end := bytes.IndexByte(f.partial, '\n')
+		if end < 0 {
+			break
+		}
+		lines = append(lines, Line{Data: f.partial[:end+1], Continued: f.continued})
+		f.partial = f.partial[end+1:]
+		f.continued = false
+	}
+
+	if len(f.partial) >= MaxLine {
+		line, _ := f.Flush()
+		return append(lines, line)
+	}
+	// Copy the partial line so the returned lines before it are not kept in memory
+	f.partial = append([]byte(nil), f.partial...)
+	return lines
+}
+
+// Flush returns the buffered partial line, and false if there is none. The rest of the line is returned as a
+// continued [Line] once it is written.
+func (f *LineFramer) Flush() (Line, bool) {
+	if len(f.partial) == 0 {
+		return Line{}, false
+	}
+	line := Line{Data: f.partial, Continued: f.continued}
+	f.partial = nil
+	f.continued = true
+	return line, true
+}
@@ -0,0 +1,39 @@
+package framing
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that lines split across writes are returned once complete, and a flushed line is continued by the rest of it.
+func TestLineFramer_Write(t *testing.T) {
+	f := &LineFramer{}
+	require.False(t, f.Started())
+	require.Equal(t, []Line{{Data: []byte("first\n")}}, f.Write([]byte("first\nsec")))
+	require.True(t, f.Started())
+	require.Equal(t, []Line{{Data: []byte("second\n")}, {Data: []byte("third\n")}}, f.Write([]byte("ond\nthird\n")))
+	require.False(t, f.Started())
+
+	_, flushed := f.Flush()
+	require.False(t, flushed)
+	require.Empty(t, f.Write([]byte("par")))
+	line, flushed := f.Flush()
+	require.True(t, flushed)
+	require.Equal(t, Line{Data: []byte("par")}, line)
+	require.False(t, line.Complete())
+	require.True(t, f.Started())
+
+	lines := f.Write([]byte("tial\nnext\n"))
+	require.Equal(t, []Line{{Data: []byte("tial\n"), Continued: true}, {Data: []byte("next\n")}}, lines)
+	require.True(t, lines[0].Complete())
+}
+
+// Ensure that the start of a line longer than the maximum is returned before its newline is written.
+func TestLineFramer_LongLine(t *testing.T) {
+	f := &LineFramer{}
+	long := bytes.Repeat([]byte("a"), MaxLine)
+	require.Equal(t, []Line{{Data: long}}, f.Write(long))
+	require.Equal(t, []Line{{Data: []byte("end\n"), Continued: true}}, f.Write([]byte("end\n")))
+}
@@ -0,0 +1,172 @@
+// Package generator generates messages of test data at a configured rate, e.g. for load testing writers.
+package generator
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// The modes of a [Generator]
+const (
+	// Each message is the same data
+	ModeRepeat = "repeat"
+	// Each message is a line with the sequence number of the message, starting from 1
+	ModeCounter = "counter"
+	// Each message is random bytes generated from a seed
+	ModeRandom = "random"
+	// Each message is a text/template evaluated with the [TemplateData] of the message
+	ModeTemplate = "template"
+)
+
+// The amount of bytes, or messages, that are generated by each [Generator.WriteTo] when the rate is unlimited. The
+// messages are also counted so that a generator of empty messages does not generate forever.
+const unlimitedBatch = 64 * 1024
+
+// The most missed messages that are generated at once after the generator was not read from for a while, as the
+// messages of this duration at the rate
+const maxCatchUp = time.Second
+
+// The data that the template of a [ModeTemplate] generator is evaluated with.
+type TemplateData struct {
+	// The sequence number of the message, starting from 1
+	Sequence int
+	// When the message was generated
+	Time time.Time
+}
+
+// Generates messages as it is read from. Each message is generated once it is due according to the rate, so the
+// generator returns [io.EOF] while no message is due or once all the messages have been generated.
+type Generator struct {
+	// The messages generated per second, unlimited when 0
+	Rate float64
+	// The amount of messages generated, unlimited when 0
+	Count int
+
+	message   func(sequence int) ([]byte, error)
+	start     time.Time
+	generated int
+	// The rest of a message that did not fit in the buffer of a read
+	pending []byte
+}
+
+// NewRepeat returns a generator of messages that are all the provided data.
+func NewRepeat(data []byte) *Generator {
+	return &Generator{message: func(int) ([]byte, error) {
+		return bytes.Clone(data), nil
+	}}
+}
+
+// NewCounter returns a generator of lines with the sequence number of each message, e.g. "1\n".
+func NewCounter() *Generator {
+	return &Generator{message: func(sequence int) ([]byte, error) {
+		return append(strconv.AppendInt(nil, int64(sequence), 10), '\n'), nil
+	}}
+}
+
+// NewRandom returns a generator of messages of random bytes with the provided size, the same seed always generates
+// the same messages.
+func NewRandom(size int, seed int64) *Generator {
+	random := rand.New(rand.NewPCG(uint64(seed), uint64(seed)))
+	return &Generator{message: func(int) ([]byte, error) {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(random.Uint32())
+		}
+		return data, nil
+	}}
+}
+
+// NewTemplate returns a generator of messages that evaluate the template with the [TemplateData] of each message.
+func NewTemplate(tmpl *template.Template) *Generator {
+	return &Generator{message: func(sequence int) ([]byte, error) {
+		var data bytes.Buffer
+		err := tmpl.Execute(&data, TemplateData{Sequence: sequence, Time: time.Now()})
+		return data.Bytes(), err
+	}}
+}
+
+// Returns true if the next message is due to be generated, the rate is measured from the first time this is called.
+func (g *Generator) due() bool {
+	if g.start.IsZero() {
+		g.start = time.Now()
+	}
+	if g.Count > 0 && g.generated >= g.Count {
+		return false
+	}
+	if g.Rate <= 0 {
+		return true
+	}
+
+	behind := time.Since(g.start).Seconds()*g.Rate - float64(g.generated)
+	if limit := max(maxCatchUp.Seconds()*g.Rate, 1); behind > limit {
+		// The rest of the missed messages are skipped by moving the start of the rate forward
+		g.start = g.start.Add(time.Duration((behind - limit) / g.Rate * float64(time.Second)))
+		behind = limit
+	}
+	return behind >= 0
+}
+
+// Generates the next message.
+func (g *Generator) next() ([]byte, error) {
+	g.generated++
+	return g.message(g.generated)
+}
+
+// [io.Reader.Read], returns [io.EOF] when no message is due. A message that does not fit in the buffer is returned
+// over multiple reads, see [Generator.WriteTo] to keep each message in a single write.
+func (g *Generator) Read(b []byte) (int, error) {
+	if len(g.pending) == 0 {
+		if !g.due() {
+			return 0, io.EOF
+		}
+		message, err := g.next()
+		if err != nil {
+			return 0, err
+		}
+		g.pending = message
+	}
+	n := copy(b, g.pending)
+	g.pending = g.pending[n:]
+	return n, nil
+}
+
+// [io.WriterTo.WriteTo], writes each message that is due with a single write. When the rate is unlimited, around
+// 64KiB of messages, or at most 65536 messages, are written on each call.
+func (g *Generator) WriteTo(w io.Writer) (int64, error) {
+	written := int64(0)
+	if len(g.pending) > 0 {
+		n, err := w.Write(g.pending)
+		written += int64(n)
+		g.pending = nil
+		if err != nil {
+			return written, err
+		}
+	}
+
+	for batch := 0; g.due() && (g.Rate > 0 || (written < unlimitedBatch && batch < unlimitedBatch)); batch++ {
+		message, err := g.next()
+		if err != nil {
+			return written, err
+		}
+		n, err := w.Write(message)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Returns true once [Generator.Count] messages have been generated and read, a generator without a count never ends.
+func (g *Generator) Ended() bool {
+	return g.Count > 0 && g.generated >= g.Count && len(g.pending) == 0
+}
+
+// [io.Closer.Close]
+func (g *Generator) Close() error {
+	return nil
+}
@@ -0,0 +1,137 @@
+package generator
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type messageRecorder struct {
+	messages []string
+}
+
+func (r *messageRecorder) Write(b []byte) (int, error) {
+	r.messages = append(r.messages, string(b))
+	return len(b), nil
+}
+
+// Ensure that each message is written in a single write, and the generator ends after its count.
+func TestGenerator_Counter(t *testing.T) {
+	g := NewCounter()
+	g.Count = 3
+	output := &messageRecorder{}
+	n, err := g.WriteTo(output)
+	require.NoError(t, err)
+	require.Equal(t, int64(6), n)
+	require.Equal(t, []string{"1\n", "2\n", "3\n"}, output.messages)
+	require.True(t, g.Ended())
+
+	_, err = g.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+	require.NoError(t, g.Close())
+}
+
+// Ensure that messages are only generated once they are due.
+func TestGenerator_Rate(t *testing.T) {
+	g := NewRepeat([]byte("ping"))
+	g.Rate = 10
+	output := &messageRecorder{}
+	_, err := g.WriteTo(output)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ping"}, output.messages)
+	_, err = g.WriteTo(output)
+	require.NoError(t, err)
+	require.Len(t, output.messages, 1)
+	require.False(t, g.Ended())
+
+	time.Sleep(150 * time.Millisecond)
+	_, err = g.WriteTo(output)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ping", "ping"}, output.messages)
+}
+
+// Ensure that an unlimited generator writes a batch of messages on each call.
+func TestGenerator_Unlimited(t *testing.T) {
+	g := NewRepeat([]byte("0123456789"))
+	n, err := g.WriteTo(io.Discard)
+	require.NoError(t, err)
+	// The batch ends with the message that reaches its size
+	require.Equal(t, int64(unlimitedBatch+4), n)
+}
+
+// Ensure that an unlimited generator of empty messages ends each batch after a bounded amount of messages.
+func TestGenerator_UnlimitedEmpty(t *testing.T) {
+	g := NewRepeat(nil)
+	output := &messageRecorder{}
+	n, err := g.WriteTo(output)
+	require.NoError(t, err)
+	require.Zero(t, n)
+	require.Len(t, output.messages, unlimitedBatch)
+}
+
+// Ensure that only a second's worth of the missed messages are generated after the generator was not read from.
+func TestGenerator_CatchUp(t *testing.T) {
+	g := NewRepeat([]byte("ping"))
+	g.Rate = 10
+	output := &messageRecorder{}
+	_, err := g.WriteTo(output)
+	require.NoError(t, err)
+	require.Len(t, output.messages, 1)
+
+	g.start = g.start.Add(-10 * time.Second)
+	_, err = g.WriteTo(output)
+	require.NoError(t, err)
+	require.Len(t, output.messages, 12)
+	_, err = g.WriteTo(output)
+	require.NoError(t, err)
+	require.Len(t, output.messages, 12)
+}
+
+// Ensure that the same seed generates the same random messages, which are split across reads that are too small.
+func TestGenerator_Random(t *testing.T) {
+	first := NewRandom(16, 42)
+	first.Count = 2
+	second := NewRandom(16, 42)
+	second.Count = 2
+
+	var firstOutput bytes.Buffer
+	_, err := first.WriteTo(&firstOutput)
+	require.NoError(t, err)
+	require.Equal(t, 32, firstOutput.Len())
+
+	b := make([]byte, 10)
+	secondOutput := []byte{}
+	for {
+		n, err := second.Read(b)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.LessOrEqual(t, n, 10)
+		secondOutput = append(secondOutput, b[:n]...)
+	}
+	require.Equal(t, firstOutput.Bytes(), secondOutput)
+	require.NotEqual(t, firstOutput.Bytes()[:16], firstOutput.Bytes()[16:])
+}
+
+func TestGenerator_Template(t *testing.T) {
+	g := NewTemplate(template.Must(template.New("message").Parse(`{"id": {{.Sequence}}, "year": {{.Time.Year}}}` + "\n")))
+	g.Count = 2
+	output := &messageRecorder{}
+	_, err := g.WriteTo(output)
+	require.NoError(t, err)
+	year := time.Now().Year()
+	require.Equal(t, []string{
+		`{"id": 1, "year": ` + strconv.Itoa(year) + "}\n",
+		`{"id": 2, "year": ` + strconv.Itoa(year) + "}\n",
+	}, output.messages)
+
+	g = NewTemplate(template.Must(template.New("message").Parse(`{{.Missing}}`)))
+	_, err = g.WriteTo(output)
+	require.Error(t, err)
+}
@@ -1,21 +1,38 @@
 package ipc
 
 import (
+	"fmt"
 	"io"
+	"net"
+	"slices"
 	"time"
 
+	"github.com/Kilemonn/flow/clientinfo"
 	"github.com/Kilemonn/flow/queuedreader"
 	ipcClient "github.com/Kilemonn/go-ipc/client"
 	ipcServer "github.com/Kilemonn/go-ipc/server"
 )
 
 const (
+	// The default read and accept deadline of the [IPCReader], see [IPCReader.ReadDeadline]
 	IPCReadDeadline = 10 * time.Millisecond
+	// The deadline of each write to the clients accepted by an [IPCReader], so a client that stops reading can't
+	// block the flow
+	IPCWriteDeadline = time.Second
 )
 
 type IPCReader struct {
-	server  ipcServer.IPCServer
-	clients []ipcClient.IPCClient
+	// The deadline of each accept and read attempt, [IPCReadDeadline] is used when 0
+	ReadDeadline time.Duration
+	server       ipcServer.IPCServer
+	clients      []ipcClient.IPCClient
+	// The client information of each client in [IPCReader.clients], at the same index
+	infos        []clientinfo.Info
+	nextClientID int
+	// The indicies of the clients that have disconnected, see [IPCReader.removeClosedClients]
+	indicies []int
+	// Set once [IPCReader.StopAccepting] has closed the server
+	stopped bool
 }
 
 func (r IPCReader) Close() (err error) {
@@ -26,47 +43,218 @@ func (r IPCReader) Close() (err error) {
 		}
 	}
 
-	e := r.server.Close()
-	if e != nil && err == nil {
-		err = e
+	if !r.stopped {
+		e := r.server.Close()
+		if e != nil && err == nil {
+			err = e
+		}
 	}
 	return err
 }
 
+// Closes the server so no new clients are accepted, while data can still be read from and written to the clients
+// that are already accepted.
+func (r *IPCReader) StopAccepting() error {
+	if r.stopped {
+		return nil
+	}
+	r.stopped = true
+	return r.server.Close()
+}
+
 // Get the amount of active connections
 func (r IPCReader) connectionCount() int {
 	return len(r.clients)
 }
 
+// Returns true once at least one client has connected and every client has since disconnected.
+func (r *IPCReader) Ended() bool {
+	return r.nextClientID > 0 && len(r.clients) == 0
+}
+
 // Check if any incoming connections are pending to be accepted.
-// This is naturally blocking, so there is a deadline set for [IPCReadDeadline]
+// This is naturally blocking, so there is a deadline set for [IPCReader.ReadDeadline]
 // before this function returns with no accepted connections.
 func (r *IPCReader) acceptWaitingConnections() {
-	for {
-		client, err := r.server.Accept(IPCReadDeadline)
+	for !r.stopped {
+		client, err := r.server.Accept(r.readDeadline())
 		if err != nil {
 			return
 		}
-		client.ReadTimeout = IPCReadDeadline
+		r.nextClientID++
 		r.clients = append(r.clients, client)
+		r.infos = append(r.infos, clientinfo.Info{
+			ID:         r.nextClientID,
+			RemoteAddr: client.Conn.RemoteAddr().String(),
+			AcceptTime: time.Now(),
+		})
+	}
+}
+
+// Marks the client at the provided index to be removed, if it is not already marked.
+func (r *IPCReader) markClosed(i int) {
+	if !slices.Contains(r.indicies, i) {
+		r.indicies = append(r.indicies, i)
+	}
+}
+
+// Closes and removes the clients that have been marked for removal, and returns their client information.
+func (r *IPCReader) removeClosedClients() []clientinfo.Info {
+	if len(r.indicies) == 0 {
+		return nil
+	}
+
+	removed := []clientinfo.Info{}
+	slices.Sort(r.indicies)
+	// Reverse iterate so the indicies of further forward elements don't change when they are removed
+	for _, i := range slices.Backward(r.indicies) {
+		r.clients[i].Close()
+		removed = append(removed, r.infos[i])
+		r.clients = append(r.clients[:i], r.clients[i+1:]...)
+		r.infos = append(r.infos[:i], r.infos[i+1:]...)
 	}
+	r.indicies = []int(nil)
+	return removed
 }
 
 // Initially calls [IPCReader.acceptWaitingConnections] to accept pending incoming
 // connections before then wrapping all accepted clients in a
 // [queuedreader.QueuedReader] and calling [io.Read].
+// Removes any clients that have disconnected.
 func (r *IPCReader) Read(b []byte) (n int, err error) {
+	n, _, err = r.readClient(b)
+	r.removeClosedClients()
+	return n, err
+}
+
+// [io.Writer.Write], writes the data to all currently accepted clients. This allows replying to the clients that have
+// connected to this reader. If there are no accepted clients the data is discarded.
+// Clients that fail to be written to are logged and removed on the next read.
+func (r *IPCReader) Write(b []byte) (int, error) {
+	for i := range r.clients {
+		if !slices.Contains(r.indicies, i) {
+			r.writeClient(i, b)
+		}
+	}
+	return len(b), nil
+}
+
+// [clientinfo.Writer.WriteClient], writes the data to the provided client only. If the client is no longer connected
+// the data is discarded.
+func (r *IPCReader) WriteClient(info clientinfo.Info, b []byte) (int, error) {
+	i := r.clientIndex(info)
+	if i >= 0 && !slices.Contains(r.indicies, i) {
+		r.writeClient(i, b)
+	}
+	return len(b), nil
+}
+
+// [clientinfo.Writer.CloseClient], closes the provided client on the next read.
+func (r *IPCReader) CloseClient(info clientinfo.Info) error {
+	if i := r.clientIndex(info); i >= 0 {
+		r.markClosed(i)
+	}
+	return nil
+}
+
+// Returns the index of the provided client, or -1 if it is no longer connected.
+func (r *IPCReader) clientIndex(info clientinfo.Info) int {
+	return slices.IndexFunc(r.infos, func(client clientinfo.Info) bool {
+		return client.ID == info.ID
+	})
+}
+
+// Writes the data to the client at the provided index within the [IPCWriteDeadline]. If the write fails the client
+// is marked for removal.
+func (r *IPCReader) writeClient(i int, b []byte) {
+	r.clients[i].Conn.SetWriteDeadline(time.Now().Add(IPCWriteDeadline))
+	_, err := r.clients[i].Write(b)
+	if err != nil {
+		fmt.Printf("Failed to write to IPC client [%d]. Error: [%s].\n", r.infos[i].ID, err.Error())
+		r.markClosed(i)
+	}
+}
+
+// Performs the same as [IPCReader.Read] but also returns the information of the client that was read from.
+// Disconnected clients, and clients that fail to be read from, are marked for removal but not removed.
+func (r *IPCReader) readClient(b []byte) (int, clientinfo.Info, error) {
 	r.acceptWaitingConnections()
 
-	q := queuedreader.NewQueuedReader(r.clients)
-	return q.Read(b)
+	// The connections are read from directly, since [ipcClient.IPCClient.Read] returns an EOF on a timeout and a
+	// disconnected client could not be told apart from one that has no data
+	conns := make([]net.Conn, len(r.clients))
+	for i, client := range r.clients {
+		conns[i] = client.Conn
+	}
+	q := queuedreader.NewQueuedReader(conns)
+	q.SetPreReadHandlerFunc(func(conn net.Conn) {
+		conn.SetReadDeadline(time.Now().Add(r.readDeadline()))
+	})
+	q.SetEOFHandlerFunc(func(i int, conn net.Conn) {
+		r.markClosed(i)
+	})
+
+	n, i, err := q.ReadWithIndex(b)
+	if i < 0 {
+		return n, clientinfo.Info{}, err
+	}
+	if err != nil {
+		// A failed read only ends this client, not the whole reader
+		fmt.Printf("Failed to read from IPC client [%d]. Error: [%s].\n", r.infos[i].ID, err.Error())
+		r.markClosed(i)
+		err = nil
+		if n == 0 {
+			err = io.EOF
+		}
+	}
+	return n, r.infos[i], err
+}
+
+// [io.WriterTo], this is preferred by [io.Copy] over [IPCReader.Read].
+// If the provided [io.Writer] is a [clientinfo.Writer], the data of each client is written along with its
+// [clientinfo.Info] and the writer is notified once a client disconnects.
+// Returns once no client has any data to be read.
+func (r *IPCReader) WriteTo(w io.Writer) (n int64, err error) {
+	b := make([]byte, 32*1024)
+	for {
+		read, info, err := r.readClient(b)
+		if closeErr := clientinfo.Close(w, r.removeClosedClients()); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			if err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+
+		written, err := clientinfo.Write(w, info, b[:read])
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// Returns the [IPCReader.ReadDeadline], or [IPCReadDeadline] if it is not set.
+func (r IPCReader) readDeadline() time.Duration {
+	if r.ReadDeadline <= 0 {
+		return IPCReadDeadline
+	}
+	return r.ReadDeadline
 }
 
 func NewIPCReader(ipcChannelName string) (io.ReadCloser, error) {
+	return NewIPCReaderWithDeadline(ipcChannelName, 0)
+}
+
+// NewIPCReaderWithDeadline creates an [IPCReader] that waits up to the provided deadline for each accept and read
+// attempt, [IPCReadDeadline] is used when the deadline is 0.
+func NewIPCReaderWithDeadline(ipcChannelName string, deadline time.Duration) (io.ReadCloser, error) {
 	server, err := ipcServer.NewIPCServer(ipcChannelName, &ipcServer.IPCServerConfig{Override: true})
 	if err != nil {
 		return nil, err
 	}
 
-	return &IPCReader{server: *server}, nil
+	return &IPCReader{server: *server, ReadDeadline: deadline}, nil
 }
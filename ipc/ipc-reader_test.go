@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Kilemonn/flow/clientinfo"
 	"github.com/Kilemonn/flow/testutil"
 	"github.com/stretchr/testify/require"
 )
@@ -117,3 +118,118 @@ func TestIPCRead_DataFromMultipleSockets(t *testing.T) {
 	require.Equal(t, io.EOF, err)
 	require.Equal(t, 0, n)
 }
+
+type recordingClientWriter struct {
+	clients []clientinfo.Info
+	writes  []string
+	closed  []clientinfo.Info
+}
+
+func (w *recordingClientWriter) Write(b []byte) (int, error) {
+	return w.WriteClient(clientinfo.Info{}, b)
+}
+
+func (w *recordingClientWriter) WriteClient(info clientinfo.Info, b []byte) (int, error) {
+	w.clients = append(w.clients, info)
+	w.writes = append(w.writes, string(b))
+	return len(b), nil
+}
+
+func (w *recordingClientWriter) CloseClient(info clientinfo.Info) error {
+	w.closed = append(w.closed, info)
+	return nil
+}
+
+// Ensure that the data of each IPC client is written along with the client it was received from.
+func TestIPCWriteTo_ClientWriter(t *testing.T) {
+	reader, err := NewIPCReader("TestIPCWriteTo_ClientWriter")
+	require.NoError(t, err)
+	defer reader.Close()
+
+	content := "TestIPCWriteTo_ClientWriter"
+	for range 2 {
+		writer, err := NewIPCWriter("TestIPCWriteTo_ClientWriter")
+		require.NoError(t, err)
+		defer writer.Close()
+
+		_, err = writer.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	w := &recordingClientWriter{}
+	_, err = reader.(io.WriterTo).WriteTo(w)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{content, content}, w.writes)
+	ids := []int{w.clients[0].ID, w.clients[1].ID}
+	require.ElementsMatch(t, []int{1, 2}, ids)
+}
+
+// Ensure that the provided deadline is used instead of the default when there are no new connections.
+func TestIPCRead_ReadDeadline(t *testing.T) {
+	deadline := 5 * IPCReadDeadline
+	reader, err := NewIPCReaderWithDeadline("TestIPCRead_ReadDeadline", deadline)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	testutil.TakesAtleast(t, deadline, func() {
+		reader.(*IPCReader).acceptWaitingConnections()
+	})
+}
+
+// Ensure that once the reader stops accepting, the accepted clients can still be read from.
+func TestIPCStopAccepting(t *testing.T) {
+	channel := "TestIPCStopAccepting"
+	reader, err := NewIPCReader(channel)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	w, err := NewIPCWriter(channel)
+	require.NoError(t, err)
+	defer w.Close()
+	reader.(*IPCReader).acceptWaitingConnections()
+	require.Equal(t, 1, reader.(*IPCReader).connectionCount())
+
+	require.NoError(t, reader.(*IPCReader).StopAccepting())
+	_, err = NewIPCWriter(channel)
+	require.Error(t, err)
+
+	content := "TestIPCStopAccepting"
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	b := make([]byte, len(content))
+	n, err := reader.Read(b)
+	require.NoError(t, err)
+	require.Equal(t, content, string(b[:n]))
+	require.NoError(t, reader.Close())
+}
+
+// Ensure that a client that disconnects is removed, and the writer is notified.
+func TestIPCWriteTo_ClientDisconnects(t *testing.T) {
+	channel := "TestIPCWriteTo_ClientDisconnects"
+	reader, err := NewIPCReader(channel)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	writer, err := NewIPCWriter(channel)
+	require.NoError(t, err)
+	content := "TestIPCWriteTo_ClientDisconnects"
+	_, err = writer.Write([]byte(content))
+	require.NoError(t, err)
+
+	w := &recordingClientWriter{}
+	_, err = reader.(io.WriterTo).WriteTo(w)
+	require.NoError(t, err)
+	require.Equal(t, []string{content}, w.writes)
+	require.Equal(t, 1, reader.(*IPCReader).connectionCount())
+	require.Empty(t, w.closed)
+	require.False(t, reader.(*IPCReader).Ended())
+
+	require.NoError(t, writer.Close())
+	_, err = reader.(io.WriterTo).WriteTo(w)
+	require.NoError(t, err)
+	require.Equal(t, 0, reader.(*IPCReader).connectionCount())
+	require.Len(t, w.closed, 1)
+	require.Equal(t, 1, w.closed[0].ID)
+	require.True(t, reader.(*IPCReader).Ended())
+}
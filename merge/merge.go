@@ -0,0 +1,313 @@
+// Package merge combines the data of multiple readers into a single stream, without interleaving partial lines or
+// frames of different readers.
+package merge
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Kilemonn/flow/clientinfo"
+	"github.com/Kilemonn/flow/framing"
+)
+
+// The framing of the data merged by a [Merger]
+const (
+	// Each line is merged as a whole, the newline is kept at the end of the line
+	FramingLine = "line"
+	// Each chunk of data (or datagram) written to the merger is merged as a whole
+	FramingChunk = "chunk"
+)
+
+// The order of the data merged by a [Merger]
+const (
+	// Each frame is merged as soon as it is complete
+	OrderArrival = "arrival"
+	// Each frame is held for up to the window and merged in the order of its timestamp, see [Merger.WriteAt]
+	OrderTimestamp = "timestamp"
+)
+
+// Identifies a source, the data of each client of a source is merged as its own source. The client is 0 for data
+// that was not written by a client.
+type sourceKey struct {
+	id     string
+	client int
+}
+
+// A complete line or chunk of data from a single source, or part of a line that was held for the window.
+type frame struct {
+	data      []byte
+	timestamp time.Duration
+	arrived   time.Time
+	source    sourceKey
+	// Whether the start of the line was already merged, so the frame is not tagged
+	continued bool
+	// Whether the frame ends its line, otherwise the frames of other sources are not merged until the line is finished
+	complete bool
+}
+
+// The partial line of a source.
+type source struct {
+	lines     framing.LineFramer
+	timestamp time.Duration
+	// When the source last wrote data
+	written time.Time
+}
+
+// Merges the data written to it from multiple sources, the merged data is read back from it. The data of each source
+// is split into frames (lines or chunks), and each frame is read as a whole without any data of other sources in it.
+type Merger struct {
+	// Either [FramingLine] or [FramingChunk]
+	Framing string
+	// Either [OrderArrival] or [OrderTimestamp]
+	Order string
+	// Prefixes each frame with the ID of its source, e.g. "[Serial1] "
+	Tag bool
+	// How long a partial line is held waiting for the rest of the line, and how long a frame is held waiting for the
+	// frames of other sources with an earlier timestamp
+	Window time.Duration
+
+	mu      sync.Mutex
+	start   time.Time
+	sources map[sourceKey]*source
+	// The complete frames waiting to be merged, in the order of their timestamp
+	held []frame
+	// The source whose partial line has been merged without the rest of the line
+	open *sourceKey
+	// The frames waiting for the line of the open source to be finished, so they are not merged into the middle of it
+	pending []frame
+	// The merged frames waiting to be read
+	output [][]byte
+}
+
+// NewMerger returns a merger with the provided framing and order, the timestamps of [Merger.WriteFrom] are measured
+// from when this is called.
+func NewMerger(framing string, order string, window time.Duration) *Merger {
+	return &Merger{
+		Framing: framing,
+		Order:   order,
+		Window:  window,
+		start:   time.Now(),
+		sources: make(map[sourceKey]*source),
+	}
+}
+
+// [io.Writer.Write], merges the data without a source. See [Merger.WriteFrom].
+func (m *Merger) Write(b []byte) (int, error) {
+	return m.WriteFrom("", b)
+}
+
+// Merges the data written from the source with the provided ID, the timestamp of the data is when it was written.
+func (m *Merger) WriteFrom(sourceID string, b []byte) (int, error) {
+	return m.WriteAt(sourceID, time.Since(m.start), b)
+}
+
+// Merges the data written from the source with the provided ID, with the provided timestamp. The timestamps of each
+// source must not decrease, e.g. the offsets of the records of a capture.
+func (m *Merger) WriteAt(sourceID string, timestamp time.Duration, b []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.write(sourceKey{id: sourceID}, timestamp, b)
+	return len(b), nil
+}
+
+// Merges the data written by the client of the source with the provided ID, the partial lines of each client are
+// kept apart. The timestamp of the data is when it was written.
+func (m *Merger) WriteClientFrom(sourceID string, info clientinfo.Info, b []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.write(sourceKey{id: sourceID, client: info.ID}, time.Since(m.start), b)
+	return len(b), nil
+}
+
+// Merges the partial line of the client of the source with the provided ID straight away, since the client has
+// disconnected and the rest of the line will not be written.
+func (m *Merger) CloseClientFrom(sourceID string, info clientinfo.Info) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := sourceKey{id: sourceID, client: info.ID}
+	if s, exists := m.sources[key]; exists {
+		delete(m.sources, key)
+		if line, ok := s.lines.Flush(); ok {
+			m.add(frame{data: line.Data, timestamp: s.timestamp, arrived: s.written, source: key, continued: line.Continued, complete: true})
+			return nil
+		}
+	}
+	if m.open != nil && *m.open == key {
+		m.open = nil
+		m.mergePending()
+	}
+	return nil
+}
+
+// Splits the data of the source into frames, the lock must be held.
+func (m *Merger) write(key sourceKey, timestamp time.Duration, b []byte) {
+	now := time.Now()
+	s, exists := m.sources[key]
+	if !exists {
+		s = &source{}
+		m.sources[key] = s
+	}
+	s.written = now
+
+	if m.Framing == FramingChunk {
+		m.add(frame{data: append([]byte(nil), b...), timestamp: timestamp, arrived: now, source: key, complete: true})
+		return
+	}
+
+	if !s.lines.Buffered() {
+		s.timestamp = timestamp
+	}
+	for _, line := range s.lines.Write(b) {
+		m.add(frame{data: line.Data, timestamp: s.timestamp, arrived: now, source: key, continued: line.Continued, complete: line.Complete()})
+		s.timestamp = timestamp
+	}
+}
+
+// Adds a frame, which is merged straight away unless it is ordered by its timestamp. Only the start of a line is
+// tagged.
+func (m *Merger) add(f frame) {
+	if m.Tag && f.source.id != "" && !f.continued {
+		f.data = append([]byte(fmt.Sprintf("[%s] ", f.source.id)), f.data...)
+	}
+	if m.Order != OrderTimestamp {
+		m.merge(f)
+		return
+	}
+
+	i := len(m.held)
+	for i > 0 && m.held[i-1].timestamp > f.timestamp {
+		i--
+	}
+	m.held = slices.Insert(m.held, i, f)
+}
+
+// Merges the partial lines that have not been written to for the window, and the held frames that are known to be
+// next in order. A frame is known to be next once every source has a later frame held, or it has been held for the
+// window.
+func (m *Merger) release(now time.Time) {
+	for key, s := range m.sources {
+		if now.Sub(s.written) < m.Window {
+			continue
+		}
+		if line, ok := s.lines.Flush(); ok {
+			m.add(frame{data: line.Data, timestamp: s.timestamp, arrived: s.written, source: key, continued: line.Continued})
+		}
+	}
+
+	for len(m.held) > 0 {
+		next := m.held[0]
+		if now.Sub(next.arrived) < m.Window && !m.allSourcesHeld() {
+			return
+		}
+		m.held = m.held[1:]
+		m.merge(next)
+	}
+}
+
+// Queues the frame to be read, unless the partial line of another source has been merged and is not finished yet. The
+// frame is then held until that line is finished.
+func (m *Merger) merge(f frame) {
+	m.pending = append(m.pending, f)
+	m.mergePending()
+}
+
+// Queues the pending frames to be read in order, the frames of the open source are merged first until its line is
+// finished. The lock must be held.
+func (m *Merger) mergePending() {
+	for len(m.pending) > 0 {
+		i := 0
+		if m.open != nil {
+			i = slices.IndexFunc(m.pending, func(f frame) bool { return f.source == *m.open })
+			if i < 0 {
+				return
+			}
+		}
+		next := m.pending[i]
+		m.pending = slices.Delete(m.pending, i, i+1)
+		m.output = append(m.output, next.data)
+		m.open = nil
+		if !next.complete {
+			m.open = &next.source
+		}
+	}
+}
+
+// Whether every source has a frame held.
+func (m *Merger) allSourcesHeld() bool {
+	for key := range m.sources {
+		if !slices.ContainsFunc(m.held, func(f frame) bool { return f.source == key }) {
+			return false
+		}
+	}
+	return true
+}
+
+// Merges all the partial lines and held frames without waiting for the window or for an unfinished line, e.g. once no
+// more data will be written.
+func (m *Merger) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.release(time.Now().Add(m.Window))
+	for len(m.pending) > 0 {
+		m.open = nil
+		m.mergePending()
+	}
+	return nil
+}
+
+// Returns up to max bytes of the next merged frame, or nil if there is none. The rest of the frame is returned next.
+func (m *Merger) next(max int) []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.release(time.Now())
+	if len(m.output) == 0 {
+		return nil
+	}
+	data := m.output[0]
+	if len(data) > max {
+		m.output[0] = data[max:]
+		return data[:max]
+	}
+	m.output = m.output[1:]
+	return data
+}
+
+// [io.Reader.Read], returns [io.EOF] when no merged data is available. A frame that does not fit in the buffer is
+// returned over multiple reads, see [Merger.WriteTo] to keep each frame in a single write.
+func (m *Merger) Read(b []byte) (int, error) {
+	data := m.next(len(b))
+	if data == nil {
+		return 0, io.EOF
+	}
+	return copy(b, data), nil
+}
+
+// [io.WriterTo.WriteTo], writes each merged frame with a single write.
+func (m *Merger) WriteTo(w io.Writer) (int64, error) {
+	written := int64(0)
+	for {
+		data := m.next(math.MaxInt)
+		if data == nil {
+			return written, nil
+		}
+		n, err := w.Write(data)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
+// [io.Closer.Close], the merger is both a reader and a writer so this can be called more than once.
+func (m *Merger) Close() error {
+	return nil
+}
@@ -0,0 +1,198 @@
+package merge
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Kilemonn/flow/clientinfo"
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that the partial lines of each source are not interleaved, and each line is tagged with its source.
+func TestMerger_Lines(t *testing.T) {
+	m := NewMerger(FramingLine, OrderArrival, time.Hour)
+	m.Tag = true
+	for _, write := range []struct{ source, data string }{
+		{"first", "hello "},
+		{"second", "other\npart"},
+		{"first", "world\n"},
+		{"", "untagged\n"},
+	} {
+		n, err := m.WriteFrom(write.source, []byte(write.data))
+		require.NoError(t, err)
+		require.Equal(t, len(write.data), n)
+	}
+
+	read, err := io.ReadAll(m)
+	require.NoError(t, err)
+	require.Equal(t, "[second] other\n[first] hello world\nuntagged\n", string(read))
+
+	// The partial line is merged once flushed
+	require.NoError(t, m.Flush())
+	read, err = io.ReadAll(m)
+	require.NoError(t, err)
+	require.Equal(t, "[second] part", string(read))
+}
+
+// Ensure that the partial lines of each client of a source are not interleaved, and the partial line of a client is
+// merged once it disconnects.
+func TestMerger_Clients(t *testing.T) {
+	m := NewMerger(FramingLine, OrderArrival, time.Hour)
+	m.Tag = true
+	first, second := clientinfo.Info{ID: 1}, clientinfo.Info{ID: 2}
+	for _, write := range []struct {
+		info clientinfo.Info
+		data string
+	}{{first, "hello "}, {second, "other\npart"}, {first, "world\n"}} {
+		n, err := m.WriteClientFrom("tcp", write.info, []byte(write.data))
+		require.NoError(t, err)
+		require.Equal(t, len(write.data), n)
+	}
+	require.NoError(t, m.CloseClientFrom("tcp", second))
+
+	read, err := io.ReadAll(m)
+	require.NoError(t, err)
+	require.Equal(t, "[tcp] other\n[tcp] hello world\n[tcp] part", string(read))
+}
+
+// Ensure that a partial line is merged once its source has not written for the window.
+func TestMerger_PartialLineWindow(t *testing.T) {
+	m := NewMerger(FramingLine, OrderArrival, 50*time.Millisecond)
+	_, err := m.WriteFrom("first", []byte("prompt> "))
+	require.NoError(t, err)
+
+	read, err := io.ReadAll(m)
+	require.NoError(t, err)
+	require.Empty(t, read)
+
+	time.Sleep(60 * time.Millisecond)
+	read, err = io.ReadAll(m)
+	require.NoError(t, err)
+	require.Equal(t, "prompt> ", string(read))
+}
+
+// Ensure that once a partial line is merged after the window, the rest of the line is not tagged again and the lines
+// of other sources are held until the line is finished.
+func TestMerger_PartialLineHoldsOtherSources(t *testing.T) {
+	m := NewMerger(FramingLine, OrderArrival, 20*time.Millisecond)
+	m.Tag = true
+	_, err := m.WriteFrom("first", []byte("hello "))
+	require.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+
+	read, err := io.ReadAll(m)
+	require.NoError(t, err)
+	require.Equal(t, "[first] hello ", string(read))
+
+	_, err = m.WriteFrom("second", []byte("other\n"))
+	require.NoError(t, err)
+	read, err = io.ReadAll(m)
+	require.NoError(t, err)
+	require.Empty(t, read)
+
+	_, err = m.WriteFrom("first", []byte("world\n"))
+	require.NoError(t, err)
+	read, err = io.ReadAll(m)
+	require.NoError(t, err)
+	require.Equal(t, "world\n[second] other\n", string(read))
+
+	// The held lines are merged on flush even if the line is never finished
+	_, err = m.WriteFrom("first", []byte("prompt> "))
+	require.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+	read, err = io.ReadAll(m)
+	require.NoError(t, err)
+	require.Equal(t, "[first] prompt> ", string(read))
+	_, err = m.WriteFrom("second", []byte("next\n"))
+	require.NoError(t, err)
+	require.NoError(t, m.Flush())
+	read, err = io.ReadAll(m)
+	require.NoError(t, err)
+	require.Equal(t, "[second] next\n", string(read))
+}
+
+// Ensure that a client disconnecting in the middle of a merged partial line releases the held lines of other clients.
+func TestMerger_PartialLineClientCloses(t *testing.T) {
+	m := NewMerger(FramingLine, OrderArrival, 20*time.Millisecond)
+	first, second := clientinfo.Info{ID: 1}, clientinfo.Info{ID: 2}
+	_, err := m.WriteClientFrom("tcp", first, []byte("partial"))
+	require.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+	read, err := io.ReadAll(m)
+	require.NoError(t, err)
+	require.Equal(t, "partial", string(read))
+
+	_, err = m.WriteClientFrom("tcp", second, []byte("line\n"))
+	require.NoError(t, err)
+	read, err = io.ReadAll(m)
+	require.NoError(t, err)
+	require.Empty(t, read)
+
+	require.NoError(t, m.CloseClientFrom("tcp", first))
+	read, err = io.ReadAll(m)
+	require.NoError(t, err)
+	require.Equal(t, "line\n", string(read))
+}
+
+// Ensure that frames are merged in the order of their timestamps once every source has a frame held, or once they
+// have been held for the window.
+func TestMerger_TimestampOrder(t *testing.T) {
+	m := NewMerger(FramingChunk, OrderTimestamp, 50*time.Millisecond)
+	var output bytes.Buffer
+	for _, write := range []struct {
+		source    string
+		timestamp time.Duration
+		data      string
+	}{
+		{"first", 10 * time.Millisecond, "a"},
+		{"first", 30 * time.Millisecond, "c"},
+		{"second", 20 * time.Millisecond, "b"},
+	} {
+		_, err := m.WriteAt(write.source, write.timestamp, []byte(write.data))
+		require.NoError(t, err)
+	}
+
+	// The last frame of "first" is held since "second" may still write an earlier frame
+	_, err := m.WriteTo(&output)
+	require.NoError(t, err)
+	require.Equal(t, "ab", output.String())
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = m.WriteTo(&output)
+	require.NoError(t, err)
+	require.Equal(t, "abc", output.String())
+}
+
+// Ensure that each chunk is read whole by WriteTo, and split across reads that are too small for it.
+func TestMerger_Chunks(t *testing.T) {
+	m := NewMerger(FramingChunk, OrderArrival, time.Hour)
+	for _, chunk := range []string{"first", "second"} {
+		_, err := m.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+
+	b := make([]byte, 3)
+	n, err := m.Read(b)
+	require.NoError(t, err)
+	require.Equal(t, "fir", string(b[:n]))
+
+	writes := [][]byte{}
+	_, err = m.WriteTo(writerFunc(func(b []byte) (int, error) {
+		writes = append(writes, b)
+		return len(b), nil
+	}))
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("st"), []byte("second")}, writes)
+
+	_, err = m.Read(b)
+	require.ErrorIs(t, err, io.EOF)
+	require.NoError(t, m.Close())
+}
+
+type writerFunc func(b []byte) (int, error)
+
+func (f writerFunc) Write(b []byte) (int, error) {
+	return f(b)
+}
@@ -0,0 +1,96 @@
+// Package monitor renders the data passing through a flow as an annotated hex dump, for inspecting binary streams.
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// The amount of bytes rendered on each line of the dump
+	bytesPerLine = 16
+	// The format of the timestamp of each chunk of data
+	TimestampFormat = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// Renders each chunk of data written to it in the same format as `hexdump -C`, preceded by a line with the time it
+// was written, the reader it was read from and its length. The offsets of each line are the offsets within all of
+// the data read from that reader.
+type Writer struct {
+	Out io.Writer
+	// The total bytes dumped from each reader
+	offsets map[string]int64
+	closer  io.Closer
+}
+
+// NewWriter creates a monitor that renders to the provided writer. The writer is closed when the monitor is closed.
+func NewWriter(out io.WriteCloser) *Writer {
+	return &Writer{Out: out, offsets: make(map[string]int64), closer: out}
+}
+
+// NewStderrWriter creates a monitor that renders to stderr, which is left open when the monitor is closed.
+func NewStderrWriter() *Writer {
+	return &Writer{Out: os.Stderr, offsets: make(map[string]int64)}
+}
+
+// [io.Writer.Write], dumps the data without a reader ID. See [Writer.WriteFrom].
+func (w *Writer) Write(b []byte) (int, error) {
+	return w.WriteFrom("", b)
+}
+
+// Dumps the data as read from the reader with the provided ID.
+func (w *Writer) WriteFrom(readerID string, b []byte) (int, error) {
+	offset := w.offsets[readerID]
+	w.offsets[readerID] = offset + int64(len(b))
+
+	_, err := io.WriteString(w.Out, Dump(time.Now(), readerID, offset, b))
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// [io.Closer.Close]
+func (w *Writer) Close() error {
+	if w.closer == nil {
+		return nil
+	}
+	return w.closer.Close()
+}
+
+// Dump renders a chunk of data read at the provided time from the reader with the provided ID, where offset is the
+// offset of the chunk within all the data read from that reader.
+func Dump(timestamp time.Time, readerID string, offset int64, b []byte) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s [%s] %d bytes\n", timestamp.Format(TimestampFormat), readerID, len(b))
+
+	for start := 0; start < len(b); start += bytesPerLine {
+		line := b[start:min(start+bytesPerLine, len(b))]
+		fmt.Fprintf(&builder, "%08x ", offset+int64(start))
+		for i := range bytesPerLine {
+			// An extra space separates the two halves of the line
+			if i%8 == 0 {
+				builder.WriteByte(' ')
+			}
+			if i < len(line) {
+				fmt.Fprintf(&builder, "%02x ", line[i])
+			} else {
+				builder.WriteString("   ")
+			}
+		}
+
+		builder.WriteString(" |")
+		for _, c := range line {
+			if c < 0x20 || c > 0x7e {
+				c = '.'
+			}
+			builder.WriteByte(c)
+		}
+		builder.WriteString("|\n")
+	}
+	fmt.Fprintf(&builder, "%08x\n", offset+int64(len(b)))
+	return builder.String()
+}
@@ -0,0 +1,64 @@
+package monitor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type bufferCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *bufferCloser) Close() error {
+	b.closed = true
+	return nil
+}
+
+// Ensure the dump matches the format of `hexdump -C`, with the offset of the chunk added to each line.
+func TestDump(t *testing.T) {
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)
+	dump := Dump(timestamp, "Port1", 16, []byte("hello world\n\x00\x01\x02\x03abc"))
+
+	expected := "2024-01-02T03:04:05.000006Z [Port1] 19 bytes\n" +
+		"00000010  68 65 6c 6c 6f 20 77 6f  72 6c 64 0a 00 01 02 03  |hello world.....|\n" +
+		"00000020  61 62 63                                          |abc|\n" +
+		"00000023\n"
+	require.Equal(t, expected, dump)
+}
+
+// Ensure that the offsets are tracked separately for each reader.
+func TestWriter_OffsetsPerReader(t *testing.T) {
+	out := &bufferCloser{}
+	writer := NewWriter(out)
+
+	_, err := writer.WriteFrom("a", []byte("0123456789"))
+	require.NoError(t, err)
+	_, err = writer.WriteFrom("b", []byte("xy"))
+	require.NoError(t, err)
+	n, err := writer.WriteFrom("a", []byte("z"))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 9)
+	require.Contains(t, lines[0], "[a] 10 bytes")
+	require.True(t, strings.HasPrefix(lines[1], "00000000  30 31"))
+	require.Contains(t, lines[3], "[b] 2 bytes")
+	require.True(t, strings.HasPrefix(lines[4], "00000000  78 79"))
+	require.Contains(t, lines[6], "[a] 1 bytes")
+	require.True(t, strings.HasPrefix(lines[7], "0000000a  7a"))
+	require.Equal(t, "0000000b", lines[8])
+
+	require.NoError(t, writer.Close())
+	require.True(t, out.closed)
+}
+
+// Ensure that stderr is not closed with the monitor.
+func TestStderrWriter_Close(t *testing.T) {
+	require.NoError(t, NewStderrWriter().Close())
+}
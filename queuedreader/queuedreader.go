@@ -54,6 +54,13 @@ func (q *QueuedReader[R]) SetPreReadHandlerFunc(handler func(R)) {
 // or on the first non-EOF and non-Timeout error, or [io.EOF] will be returned if all [io.Reader]s timeout or return
 // [io.EOF].
 func (q QueuedReader[R]) Read(b []byte) (int, error) {
+	n, _, err := q.ReadWithIndex(b)
+	return n, err
+}
+
+// ReadWithIndex performs the same as [QueuedReader.Read] but also returns the index of the [io.Reader] that was
+// read from (or that returned the error). The index is -1 when [io.EOF] is returned.
+func (q QueuedReader[R]) ReadWithIndex(b []byte) (int, int, error) {
 	for i, r := range q.readers {
 		if q.preReadFunc != nil {
 			q.preReadFunc(r)
@@ -74,12 +81,12 @@ func (q QueuedReader[R]) Read(b []byte) (int, error) {
 				}
 			} else {
 				// On other errors, make sure we return immediately to the caller
-				return n, err
+				return n, i, err
 			}
 		} else {
 			// If there is no error, return the read number of bytes to the caller
-			return n, err
+			return n, i, err
 		}
 	}
-	return 0, io.EOF
+	return 0, -1, io.EOF
 }
@@ -5,6 +5,7 @@ import (
 	"net"
 	"net/netip"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -75,3 +76,19 @@ func TestRead_OnTimeout(t *testing.T) {
 
 	require.True(t, called)
 }
+
+// Ensure the index of the reader that data was read from is returned.
+func TestReadWithIndex(t *testing.T) {
+	q := NewQueuedReader([]io.Reader{strings.NewReader(""), strings.NewReader("second")})
+
+	b := make([]byte, 10)
+	n, i, err := q.ReadWithIndex(b)
+	require.NoError(t, err)
+	require.Equal(t, 1, i)
+	require.Equal(t, "second", string(b[:n]))
+
+	n, i, err = q.ReadWithIndex(b)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, -1, i)
+	require.Equal(t, 0, n)
+}
@@ -0,0 +1,340 @@
+// Package script runs expect-style scripts that send data to a peer (e.g. a serial device) and wait for its replies.
+package script
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// How long an expect step waits for a match when it has no timeout configured
+const DefaultTimeout = 5 * time.Second
+
+// The amount of received data that is kept to be matched by the next expect step, older data is discarded
+const receiveWindow = 64 * 1024
+
+// A step of a script, each step sets exactly one of [Step.Send], [Step.Expect], [Step.Wait] or [Step.Steps].
+type Step struct {
+	// Sends the data to the peer. This is a text/template of the variables set by the previous steps, e.g.
+	// "AT+CPIN={{.pin}}\r"
+	Send string
+	// Waits until the data received from the peer matches this regular expression. The matched data and the data
+	// received before it is not matched by the following steps
+	Expect string
+	// The milliseconds an expect waits for a match before the script fails, defaults to [DefaultTimeout] when 0
+	Timeout int
+	// Sets the variable with this name to the first capture group of the expect, or the whole match when it has no
+	// capture groups
+	Set string
+	// Waits this many milliseconds
+	Wait int
+	// Runs the steps [Step.Loop] times
+	Steps []Step
+	// The amount of times the steps are run, defaults to 1 when 0
+	Loop int
+}
+
+// A step with its template and regular expression parsed.
+type step struct {
+	Step
+	// The position of the step in the script, e.g. "3.1" for the first step of the third step's loop
+	position string
+	send     *template.Template
+	expect   *regexp.Regexp
+	steps    []step
+}
+
+// Validate checks that each step sets exactly one action, and that its templates and regular expressions are valid.
+func Validate(steps []Step) error {
+	_, err := compile(steps, "")
+	return err
+}
+
+// Parses the templates and regular expressions of the steps, path is the position of the parent loop step used in
+// errors, e.g. "3.1".
+func compile(steps []Step, path string) ([]step, error) {
+	compiled := make([]step, len(steps))
+	for i, s := range steps {
+		position := fmt.Sprintf("%s%d", path, i+1)
+		actions := 0
+		for _, set := range []bool{s.Send != "", s.Expect != "", s.Wait != 0, len(s.Steps) > 0} {
+			if set {
+				actions++
+			}
+		}
+		if actions != 1 {
+			return nil, fmt.Errorf("step [%s] must set exactly one of \"send\", \"expect\", \"wait\" or \"steps\"", position)
+		}
+		if s.Wait < 0 || s.Timeout < 0 || s.Loop < 0 {
+			return nil, fmt.Errorf("step [%s] has a negative wait, timeout or loop", position)
+		}
+		if s.Expect == "" && (s.Timeout != 0 || s.Set != "") {
+			return nil, fmt.Errorf("step [%s] can only set a \"timeout\" or \"set\" with an \"expect\"", position)
+		}
+		if len(s.Steps) == 0 && s.Loop != 0 {
+			return nil, fmt.Errorf("step [%s] can only set a \"loop\" with \"steps\"", position)
+		}
+
+		compiled[i] = step{Step: s, position: position}
+		var err error
+		if s.Send != "" {
+			compiled[i].send, err = template.New(position).Option("missingkey=error").Parse(s.Send)
+			if err != nil {
+				return nil, fmt.Errorf("step [%s] has an invalid send template with error: [%s]", position, err.Error())
+			}
+		}
+		if s.Expect != "" {
+			compiled[i].expect, err = regexp.Compile(s.Expect)
+			if err != nil {
+				return nil, fmt.Errorf("step [%s] has an invalid expect [%s] with error: [%s]", position, s.Expect, err.Error())
+			}
+		}
+		if len(s.Steps) > 0 {
+			compiled[i].steps, err = compile(s.Steps, position+".")
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return compiled, nil
+}
+
+// Runs a script against a peer. The data the script sends is read from the runner, and the data received from the
+// peer is written to it. The script starts once the runner is first read from, and each step is logged to the
+// transcript as it runs.
+type Runner struct {
+	steps      []step
+	transcript io.Writer
+	closer     io.Closer
+	variables  map[string]string
+
+	start   time.Time
+	started sync.Once
+	// Signalled when data is received
+	received chan struct{}
+	closing  chan struct{}
+	closed   sync.Once
+
+	mu       sync.Mutex
+	output   []byte
+	input    []byte
+	finished bool
+	err      error
+}
+
+// NewRunner creates a runner of the steps that logs to the transcript, which is closed when the runner is closed.
+func NewRunner(steps []Step, transcript io.WriteCloser) (*Runner, error) {
+	r, err := newRunner(steps, transcript)
+	if err != nil {
+		return nil, err
+	}
+	r.closer = transcript
+	return r, nil
+}
+
+// NewStderrRunner creates a runner of the steps that logs to stderr, which is left open when the runner is closed.
+func NewStderrRunner(steps []Step) (*Runner, error) {
+	return newRunner(steps, os.Stderr)
+}
+
+func newRunner(steps []Step, transcript io.Writer) (*Runner, error) {
+	compiled, err := compile(steps, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{
+		steps:      compiled,
+		transcript: transcript,
+		variables:  make(map[string]string),
+		start:      time.Now(),
+		received:   make(chan struct{}, 1),
+		closing:    make(chan struct{}),
+	}, nil
+}
+
+// Logs an event of the script with the seconds since the runner was created, the lock must be held.
+func (r *Runner) log(event string, data []byte) {
+	elapsed := time.Since(r.start).Seconds()
+	if data == nil {
+		fmt.Fprintf(r.transcript, "%.3f %s\n", elapsed, event)
+	} else {
+		fmt.Fprintf(r.transcript, "%.3f %s %q\n", elapsed, event, data)
+	}
+}
+
+// Runs the steps and records the result.
+func (r *Runner) run() {
+	err := r.runSteps(r.steps)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished = true
+	r.err = err
+	if err != nil {
+		r.log("fail: "+err.Error(), nil)
+	} else {
+		r.log("pass", nil)
+	}
+}
+
+func (r *Runner) runSteps(steps []step) error {
+	for _, s := range steps {
+		var err error
+		switch {
+		case s.send != nil:
+			err = r.send(s)
+		case s.expect != nil:
+			err = r.expect(s)
+		case s.Wait > 0:
+			err = r.wait(s)
+		default:
+			// The steps of the loop include their position in their own errors
+			for range max(s.Loop, 1) {
+				err = r.runSteps(s.steps)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("step [%s] failed with error: [%s]", s.position, err.Error())
+		}
+	}
+	return nil
+}
+
+// Queues the data of the step to be read from the runner.
+func (r *Runner) send(s step) error {
+	var data bytes.Buffer
+	err := s.send.Execute(&data, r.variables)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.output = append(r.output, data.Bytes()...)
+	r.log("send", data.Bytes())
+	return nil
+}
+
+// Waits for the received data to match the expect of the step, or for the timeout.
+func (r *Runner) expect(s step) error {
+	timeout := DefaultTimeout
+	if s.Timeout > 0 {
+		timeout = time.Duration(s.Timeout) * time.Millisecond
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		r.mu.Lock()
+		match := s.expect.FindSubmatchIndex(r.input)
+		if match != nil {
+			matched := r.input[match[0]:match[1]]
+			if s.Set != "" {
+				value := matched
+				if len(match) > 2 && match[2] >= 0 {
+					value = r.input[match[2]:match[3]]
+				}
+				r.variables[s.Set] = string(value)
+			}
+			r.log("match", matched)
+			r.input = append([]byte(nil), r.input[match[1]:]...)
+			r.mu.Unlock()
+			return nil
+		}
+		r.mu.Unlock()
+
+		select {
+		case <-r.received:
+		case <-timer.C:
+			return fmt.Errorf("expect [%s] timed out after [%d] ms", s.Expect, timeout.Milliseconds())
+		case <-r.closing:
+			return errors.New("the script was closed")
+		}
+	}
+}
+
+// Waits for the duration of the step.
+func (r *Runner) wait(s step) error {
+	r.mu.Lock()
+	r.log(fmt.Sprintf("wait %dms", s.Wait), nil)
+	r.mu.Unlock()
+
+	select {
+	case <-time.After(time.Duration(s.Wait) * time.Millisecond):
+		return nil
+	case <-r.closing:
+		return errors.New("the script was closed")
+	}
+}
+
+// [io.Reader.Read], reads the data sent by the script and starts the script the first time it is called. Returns
+// [io.EOF] when there is no data to send.
+func (r *Runner) Read(b []byte) (int, error) {
+	r.started.Do(func() {
+		go r.run()
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.output) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, r.output)
+	r.output = r.output[n:]
+	return n, nil
+}
+
+// [io.Writer.Write], receives the data from the peer to be matched by the expect steps.
+func (r *Runner) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	r.input = append(r.input, b...)
+	if len(r.input) > receiveWindow {
+		r.input = r.input[len(r.input)-receiveWindow:]
+	}
+	r.log("recv", b)
+	r.mu.Unlock()
+
+	select {
+	case r.received <- struct{}{}:
+	default:
+	}
+	return len(b), nil
+}
+
+// Returns true once the script has finished and all of the data it sent has been read, along with the error that the
+// script failed with.
+func (r *Runner) Finished() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.finished && len(r.output) == 0, r.err
+}
+
+// Returns the variables set by the script so far.
+func (r *Runner) Variables() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return maps.Clone(r.variables)
+}
+
+// [io.Closer.Close], stops the script if it is still running. The runner is both a reader and a writer so this can
+// be called more than once.
+func (r *Runner) Close() error {
+	var err error
+	r.closed.Do(func() {
+		close(r.closing)
+		if r.closer != nil {
+			err = r.closer.Close()
+		}
+	})
+	return err
+}
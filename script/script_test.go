@@ -0,0 +1,130 @@
+package script
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// Polls the runner until it finishes, replying to each command it sends with the reply of the device.
+func runAgainst(t *testing.T, r *Runner, device func(command string) string) error {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		sent, err := io.ReadAll(r)
+		require.NoError(t, err)
+		if len(sent) > 0 {
+			_, err = r.Write([]byte(device(string(sent))))
+			require.NoError(t, err)
+		}
+
+		finished, err := r.Finished()
+		if finished {
+			return err
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	require.Fail(t, "the script did not finish")
+	return nil
+}
+
+// Ensure that the script sends its commands, sets variables from the replies and loops.
+func TestRunner_Pass(t *testing.T) {
+	var transcript bytes.Buffer
+	r, err := NewRunner([]Step{
+		{Send: "AT\r"},
+		{Expect: "OK", Timeout: 1000},
+		{Steps: []Step{
+			{Send: "AT+CSQ\r"},
+			{Expect: `\+CSQ: (\d+)`, Set: "rssi"},
+		}, Loop: 2},
+		{Wait: 10},
+		{Send: "AT+LOG={{.rssi}}\r"},
+		{Expect: "LOGGED", Set: "result"},
+	}, nopCloser{&transcript})
+	require.NoError(t, err)
+
+	signal := 0
+	commands := []string{}
+	err = runAgainst(t, r, func(command string) string {
+		commands = append(commands, command)
+		switch command {
+		case "AT+CSQ\r":
+			signal++
+			return fmt.Sprintf("+CSQ: %d\r\nOK\r\n", signal)
+		case "AT+LOG=2\r":
+			return "LOGGED\r\n"
+		default:
+			return "OK\r\n"
+		}
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"AT\r", "AT+CSQ\r", "AT+CSQ\r", "AT+LOG=2\r"}, commands)
+	require.Equal(t, map[string]string{"rssi": "2", "result": "LOGGED"}, r.Variables())
+
+	require.Contains(t, transcript.String(), ` send "AT\r"`)
+	require.Contains(t, transcript.String(), ` recv "OK\r\n"`)
+	require.Contains(t, transcript.String(), ` match "+CSQ: 2"`)
+	require.Contains(t, transcript.String(), " wait 10ms\n")
+	require.True(t, strings.HasSuffix(transcript.String(), " pass\n"))
+	require.NoError(t, r.Close())
+	require.NoError(t, r.Close())
+}
+
+// Ensure that the script fails once an expect times out.
+func TestRunner_ExpectTimeout(t *testing.T) {
+	var transcript bytes.Buffer
+	r, err := NewRunner([]Step{
+		{Steps: []Step{{Send: "AT\r"}, {Expect: "OK", Timeout: 50}}},
+		{Send: "never sent"},
+	}, nopCloser{&transcript})
+	require.NoError(t, err)
+
+	err = runAgainst(t, r, func(command string) string {
+		return "ERROR\r\n"
+	})
+	require.ErrorContains(t, err, "step [1.2] failed")
+	require.ErrorContains(t, err, "timed out after [50] ms")
+	require.Contains(t, transcript.String(), " fail: step [1.2] failed")
+	require.NotContains(t, transcript.String(), "never sent")
+}
+
+// Ensure that closing the runner stops the script.
+func TestRunner_Close(t *testing.T) {
+	r, err := NewRunner([]Step{{Expect: "never"}}, nopCloser{io.Discard})
+	require.NoError(t, err)
+	_, err = r.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+	require.NoError(t, r.Close())
+
+	require.Eventually(t, func() bool {
+		finished, _ := r.Finished()
+		return finished
+	}, time.Second, 5*time.Millisecond)
+	_, err = r.Finished()
+	require.ErrorContains(t, err, "closed")
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate([]Step{{Send: "AT"}, {Expect: "OK", Timeout: 10, Set: "ok"}, {Wait: 10}, {Steps: []Step{{Send: "a"}}, Loop: 3}}))
+	require.Error(t, Validate([]Step{{}}))
+	require.Error(t, Validate([]Step{{Send: "AT", Expect: "OK"}}))
+	require.Error(t, Validate([]Step{{Wait: -1}}))
+	require.Error(t, Validate([]Step{{Send: "AT", Timeout: 10}}))
+	require.Error(t, Validate([]Step{{Send: "AT", Loop: 2}}))
+	require.Error(t, Validate([]Step{{Send: "{{.missing"}}))
+	require.Error(t, Validate([]Step{{Expect: "("}}))
+	require.ErrorContains(t, Validate([]Step{{Steps: []Step{{Send: "a"}, {}}}}), "step [1.2]")
+}
@@ -0,0 +1,468 @@
+package serial
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	goSerial "go.bug.st/serial"
+)
+
+const (
+	// The read and accept deadline used when polling the RFC 2217 client
+	RFC2217ReadDeadline = 10 * time.Millisecond
+	// The deadline of each write to the RFC 2217 client, a client that stops reading for this long is disconnected
+	RFC2217WriteDeadline = time.Second
+	// The duration of the break sent when a client turns the break state on
+	RFC2217BreakDuration = 250 * time.Millisecond
+	// The signature reported to clients that request it
+	RFC2217Signature = "flow"
+)
+
+// Telnet commands and options, see RFC 854 and RFC 2217.
+const (
+	telnetSE   byte = 240
+	telnetSB   byte = 250
+	telnetWILL byte = 251
+	telnetWONT byte = 252
+	telnetDO   byte = 253
+	telnetDONT byte = 254
+	telnetIAC  byte = 255
+
+	telnetOptionBinary          byte = 0
+	telnetOptionSuppressGoAhead byte = 3
+	telnetOptionComPort         byte = 44
+)
+
+// RFC 2217 client to server commands, the server responds with the command + [rfc2217ResponseOffset].
+const (
+	rfc2217Signature          byte = 0
+	rfc2217SetBaudRate        byte = 1
+	rfc2217SetDataSize        byte = 2
+	rfc2217SetParity          byte = 3
+	rfc2217SetStopSize        byte = 4
+	rfc2217SetControl         byte = 5
+	rfc2217FlowControlSuspend byte = 8
+	rfc2217FlowControlResume  byte = 9
+	rfc2217SetLineStateMask   byte = 10
+	rfc2217SetModemStateMask  byte = 11
+	rfc2217PurgeData          byte = 12
+
+	rfc2217ResponseOffset byte = 100
+)
+
+// RFC 2217 SET-CONTROL values.
+const (
+	controlRequestFlow  byte = 0
+	controlNoFlow       byte = 1
+	controlRequestBreak byte = 4
+	controlBreakOn      byte = 5
+	controlBreakOff     byte = 6
+	controlRequestDTR   byte = 7
+	controlDTROn        byte = 8
+	controlDTROff       byte = 9
+	controlRequestRTS   byte = 10
+	controlRTSOn        byte = 11
+	controlRTSOff       byte = 12
+)
+
+// The states of the telnet stream parser.
+const (
+	telnetStateData = iota
+	telnetStateIAC
+	telnetStateOption
+	telnetStateSB
+	telnetStateSBIAC
+)
+
+// RFC2217Server exposes a serial port to a TCP client speaking RFC 2217 (Telnet COM-Port-Control). Data read from
+// the client is returned by [RFC2217Server.Read] to be written to the port, and data written with
+// [RFC2217Server.Write] is sent to the client. Port settings requested by the client (baud rate, data size, parity,
+// stop size and the DTR, RTS and break control lines) are applied directly to the port.
+// Only a single client is served at a time, further clients are accepted once the current client disconnects.
+type RFC2217Server struct {
+	Port     goSerial.Port
+	Mode     goSerial.Mode
+	Listener *net.TCPListener
+	conn     net.Conn
+	dtr      bool
+	rts      bool
+	breakOn  bool
+	// Set while a break is being sent to the port
+	breaking atomic.Bool
+
+	// Telnet parser state, this is kept between reads since commands can be split across multiple reads
+	state      int
+	command    byte
+	subnegData []byte
+	// The telnet options that are enabled locally (WILL) and remotely (DO)
+	local  map[byte]bool
+	remote map[byte]bool
+	// Set once [RFC2217Server.StopAccepting] has closed the listener
+	stopped bool
+}
+
+// NewRFC2217Server listens on the provided address, serving the provided port which is opened with the provided mode.
+func NewRFC2217Server(port goSerial.Port, mode goSerial.Mode, address string) (*RFC2217Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	dtr, rts := true, true
+	if mode.InitialStatusBits != nil {
+		dtr, rts = mode.InitialStatusBits.DTR, mode.InitialStatusBits.RTS
+	}
+	return &RFC2217Server{
+		Port:     port,
+		Mode:     mode,
+		Listener: listener.(*net.TCPListener),
+		dtr:      dtr,
+		rts:      rts,
+	}, nil
+}
+
+// Accepts a waiting client, if there is no current client. The accept has a deadline of [RFC2217ReadDeadline].
+func (s *RFC2217Server) acceptWaitingConnection() {
+	if s.conn != nil || s.stopped {
+		return
+	}
+
+	s.Listener.SetDeadline(time.Now().Add(RFC2217ReadDeadline))
+	conn, err := s.Listener.Accept()
+	if err != nil {
+		return
+	}
+
+	s.conn = conn
+	s.state = telnetStateData
+	s.subnegData = nil
+	s.local = make(map[byte]bool)
+	s.remote = make(map[byte]bool)
+}
+
+func (s *RFC2217Server) closeConnection() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// [io.Reader.Read], reads data from the current client with a deadline of [RFC2217ReadDeadline], handling any telnet
+// and RFC 2217 commands it contains. [io.EOF] is returned when there is no client or no data to be read.
+func (s *RFC2217Server) Read(b []byte) (int, error) {
+	s.acceptWaitingConnection()
+	if s.conn == nil {
+		return 0, io.EOF
+	}
+
+	raw := make([]byte, len(b))
+	for {
+		s.conn.SetReadDeadline(time.Now().Add(RFC2217ReadDeadline))
+		n, err := s.conn.Read(raw)
+		if err != nil {
+			if !errors.Is(err, os.ErrDeadlineExceeded) {
+				s.closeConnection()
+			}
+			return 0, io.EOF
+		}
+
+		// The data is never larger than the raw bytes it was parsed from
+		data := s.parse(raw[:n])
+		if len(data) > 0 {
+			return copy(b, data), nil
+		}
+	}
+}
+
+// [io.Writer.Write], sends the data to the current client, escaping any telnet IAC bytes. If there is no client the
+// data is discarded.
+func (s *RFC2217Server) Write(b []byte) (int, error) {
+	if s.conn == nil {
+		return len(b), nil
+	}
+
+	s.send(escapeIAC(b)...)
+	return len(b), nil
+}
+
+// [io.Closer.Close], closes the current client and the listener. The port itself is not closed.
+func (s *RFC2217Server) Close() error {
+	s.closeConnection()
+	if s.stopped {
+		return nil
+	}
+	return s.Listener.Close()
+}
+
+// Closes the listener so no new client is accepted, while the current client is still served.
+func (s *RFC2217Server) StopAccepting() error {
+	if s.stopped {
+		return nil
+	}
+	s.stopped = true
+	return s.Listener.Close()
+}
+
+// Parses the telnet stream, handling any commands and returning the remaining data.
+func (s *RFC2217Server) parse(raw []byte) []byte {
+	data := make([]byte, 0, len(raw))
+	for _, c := range raw {
+		switch s.state {
+		case telnetStateData:
+			if c == telnetIAC {
+				s.state = telnetStateIAC
+			} else {
+				data = append(data, c)
+			}
+		case telnetStateIAC:
+			switch c {
+			case telnetIAC:
+				data = append(data, c)
+				s.state = telnetStateData
+			case telnetWILL, telnetWONT, telnetDO, telnetDONT:
+				s.command = c
+				s.state = telnetStateOption
+			case telnetSB:
+				s.subnegData = s.subnegData[:0]
+				s.state = telnetStateSB
+			default:
+				// Other commands (e.g. NOP, GA) are ignored
+				s.state = telnetStateData
+			}
+		case telnetStateOption:
+			s.negotiate(s.command, c)
+			s.state = telnetStateData
+		case telnetStateSB:
+			if c == telnetIAC {
+				s.state = telnetStateSBIAC
+			} else {
+				s.subnegData = append(s.subnegData, c)
+			}
+		case telnetStateSBIAC:
+			if c == telnetSE {
+				s.subnegotiate(s.subnegData)
+				s.state = telnetStateData
+			} else {
+				// An escaped IAC within the subnegotiation
+				s.subnegData = append(s.subnegData, c)
+				s.state = telnetStateSB
+			}
+		}
+	}
+	return data
+}
+
+// Responds to the option negotiation, only responding when the option state changes to avoid negotiation loops.
+func (s *RFC2217Server) negotiate(command byte, option byte) {
+	switch command {
+	case telnetDO:
+		supported := option == telnetOptionBinary || option == telnetOptionSuppressGoAhead
+		if supported && !s.local[option] {
+			s.local[option] = true
+			s.send(telnetIAC, telnetWILL, option)
+		} else if !supported {
+			s.send(telnetIAC, telnetWONT, option)
+		}
+	case telnetDONT:
+		if s.local[option] {
+			s.local[option] = false
+			s.send(telnetIAC, telnetWONT, option)
+		}
+	case telnetWILL:
+		supported := option == telnetOptionBinary || option == telnetOptionSuppressGoAhead || option == telnetOptionComPort
+		if supported && !s.remote[option] {
+			s.remote[option] = true
+			s.send(telnetIAC, telnetDO, option)
+		} else if !supported {
+			s.send(telnetIAC, telnetDONT, option)
+		}
+	case telnetWONT:
+		if s.remote[option] {
+			s.remote[option] = false
+			s.send(telnetIAC, telnetDONT, option)
+		}
+	}
+}
+
+// Handles the RFC 2217 subnegotiation, applying the requested setting and responding with the resulting value.
+func (s *RFC2217Server) subnegotiate(b []byte) {
+	if len(b) < 2 || b[0] != telnetOptionComPort {
+		return
+	}
+
+	command, value := b[1], b[2:]
+	switch command {
+	case rfc2217Signature:
+		if len(value) == 0 {
+			s.respond(command, []byte(RFC2217Signature))
+		}
+	case rfc2217SetBaudRate:
+		if len(value) == 4 {
+			if baud := int(binary.BigEndian.Uint32(value)); baud > 0 {
+				s.setMode(func(mode *goSerial.Mode) { mode.BaudRate = baud })
+			}
+		}
+		s.respond(command, binary.BigEndian.AppendUint32(nil, uint32(s.Mode.BaudRate)))
+	case rfc2217SetDataSize:
+		if len(value) == 1 && value[0] >= 5 && value[0] <= 8 {
+			s.setMode(func(mode *goSerial.Mode) { mode.DataBits = int(value[0]) })
+		}
+		s.respond(command, []byte{byte(s.Mode.DataBits)})
+	case rfc2217SetParity:
+		// RFC 2217 parity values are offset by one from [goSerial.Parity], with 0 requesting the current value
+		if len(value) == 1 && value[0] >= 1 && value[0] <= 5 {
+			s.setMode(func(mode *goSerial.Mode) { mode.Parity = goSerial.Parity(value[0] - 1) })
+		}
+		s.respond(command, []byte{byte(s.Mode.Parity) + 1})
+	case rfc2217SetStopSize:
+		if len(value) == 1 {
+			if stopBits, ok := rfc2217StopBits[value[0]]; ok {
+				s.setMode(func(mode *goSerial.Mode) { mode.StopBits = stopBits })
+			}
+		}
+		for size, stopBits := range rfc2217StopBits {
+			if stopBits == s.Mode.StopBits {
+				s.respond(command, []byte{size})
+			}
+		}
+	case rfc2217SetControl:
+		if len(value) == 1 {
+			s.respond(command, []byte{s.setControl(value[0])})
+		}
+	case rfc2217FlowControlSuspend, rfc2217FlowControlResume:
+		s.respond(command, nil)
+	case rfc2217SetLineStateMask, rfc2217SetModemStateMask:
+		s.respond(command, value)
+	case rfc2217PurgeData:
+		if len(value) == 1 {
+			if value[0] == 1 || value[0] == 3 {
+				s.logError("purge the receive buffer", s.Port.ResetInputBuffer())
+			}
+			if value[0] == 2 || value[0] == 3 {
+				s.logError("purge the transmit buffer", s.Port.ResetOutputBuffer())
+			}
+		}
+		s.respond(command, value)
+	}
+}
+
+// The RFC 2217 SET-STOPSIZE values and their [goSerial.StopBits]
+var rfc2217StopBits = map[byte]goSerial.StopBits{
+	1: goSerial.OneStopBit,
+	2: goSerial.TwoStopBits,
+	3: goSerial.OnePointFiveStopBits,
+}
+
+// Applies the change to a copy of the current mode, the current mode is only updated if it is successfully set on the
+// port.
+func (s *RFC2217Server) setMode(change func(mode *goSerial.Mode)) {
+	mode := s.Mode
+	change(&mode)
+	err := s.Port.SetMode(&mode)
+	if err != nil {
+		s.logError("set the port mode", err)
+		return
+	}
+	s.Mode = mode
+}
+
+// Applies the SET-CONTROL value and returns the resulting state to respond with.
+func (s *RFC2217Server) setControl(value byte) byte {
+	switch value {
+	case controlRequestFlow, controlNoFlow:
+		// Flow control is not supported
+		return controlNoFlow
+	case controlBreakOn:
+		s.breakOn = true
+		s.sendBreak()
+	case controlBreakOff:
+		s.breakOn = false
+	case controlDTROn, controlDTROff:
+		err := s.Port.SetDTR(value == controlDTROn)
+		if s.logError("set DTR", err) == nil {
+			s.dtr = value == controlDTROn
+		}
+	case controlRTSOn, controlRTSOff:
+		err := s.Port.SetRTS(value == controlRTSOn)
+		if s.logError("set RTS", err) == nil {
+			s.rts = value == controlRTSOn
+		}
+	}
+
+	switch value {
+	case controlRequestBreak, controlBreakOn, controlBreakOff:
+		return choose(s.breakOn, controlBreakOn, controlBreakOff)
+	case controlRequestDTR, controlDTROn, controlDTROff:
+		return choose(s.dtr, controlDTROn, controlDTROff)
+	case controlRequestRTS, controlRTSOn, controlRTSOff:
+		return choose(s.rts, controlRTSOn, controlRTSOff)
+	default:
+		// Unsupported values (e.g. inbound flow control) are acknowledged with the requested value
+		return value
+	}
+}
+
+// Sends a break of [RFC2217BreakDuration] to the port in the background, since [goSerial.Port.Break] waits for the
+// break to end and would otherwise stall the flow. A break that is requested while one is being sent is ignored.
+func (s *RFC2217Server) sendBreak() {
+	if !s.breaking.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer s.breaking.Store(false)
+		s.logError("send a break", s.Port.Break(RFC2217BreakDuration))
+	}()
+}
+
+func choose(condition bool, onTrue byte, onFalse byte) byte {
+	if condition {
+		return onTrue
+	}
+	return onFalse
+}
+
+func (s *RFC2217Server) logError(action string, err error) error {
+	if err != nil {
+		fmt.Printf("RFC 2217 client requested to %s, but it failed. Error: [%s].\n", action, err.Error())
+	}
+	return err
+}
+
+// Sends the RFC 2217 server response for the provided command.
+func (s *RFC2217Server) respond(command byte, value []byte) {
+	b := []byte{telnetIAC, telnetSB, telnetOptionComPort, command + rfc2217ResponseOffset}
+	b = append(b, escapeIAC(value)...)
+	s.send(append(b, telnetIAC, telnetSE)...)
+}
+
+// Sends the data to the current client with a deadline of [RFC2217WriteDeadline], the client is disconnected if the
+// write fails so a client that stops reading does not block the flow.
+func (s *RFC2217Server) send(b ...byte) {
+	if s.conn == nil {
+		return
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(RFC2217WriteDeadline))
+	_, err := s.conn.Write(b)
+	if err != nil {
+		fmt.Printf("Failed to write to RFC 2217 client [%s]. Error: [%s].\n", s.conn.RemoteAddr(), err.Error())
+		s.closeConnection()
+	}
+}
+
+// Doubles any IAC bytes so they are treated as data.
+func escapeIAC(b []byte) []byte {
+	escaped := make([]byte, 0, len(b))
+	for _, c := range b {
+		escaped = append(escaped, c)
+		if c == telnetIAC {
+			escaped = append(escaped, telnetIAC)
+		}
+	}
+	return escaped
+}
@@ -0,0 +1,228 @@
+//go:build linux
+
+package serial
+
+import (
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	goSerial "go.bug.st/serial"
+	"golang.org/x/sys/unix"
+)
+
+// Opens a PTY, returning the serial port opened on its slave and another handle of the slave that can be used to
+// inspect its settings.
+func openPTYPort(t *testing.T, mode goSerial.Mode) (goSerial.Port, *os.File) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { master.Close() })
+
+	fd := int(master.Fd())
+	require.NoError(t, unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0))
+	number, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	require.NoError(t, err)
+
+	// The slave is opened before the port, since the port prevents any further opens once it is opened
+	path := "/dev/pts/" + strconv.Itoa(number)
+	slave, err := os.OpenFile(path, os.O_RDWR|unix.O_NOCTTY, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { slave.Close() })
+
+	port, err := goSerial.Open(path, &mode)
+	require.NoError(t, err)
+	t.Cleanup(func() { port.Close() })
+	return port, slave
+}
+
+// Reads from the server until data is returned or the timeout is reached.
+func readServerEventually(t *testing.T, server *RFC2217Server, b []byte) int {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		n, err := server.Read(b)
+		if err == nil {
+			return n
+		}
+		require.Equal(t, io.EOF, err)
+	}
+	require.Fail(t, "no data was read from the RFC 2217 server")
+	return 0
+}
+
+// Reads the exact amount of bytes expected from the client connection.
+func requireReceived(t *testing.T, conn net.Conn, expected []byte) {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	b := make([]byte, len(expected))
+	_, err := io.ReadFull(conn, b)
+	require.NoError(t, err)
+	require.Equal(t, expected, b)
+}
+
+// Ensure that the port settings requested by the client are applied to the port, and that data is unescaped when
+// read from the client and escaped when written to the client.
+func TestRFC2217Server_SetsPortMode(t *testing.T) {
+	mode := goSerial.Mode{BaudRate: 9600, DataBits: 8}
+	port, slave := openPTYPort(t, mode)
+
+	server, err := NewRFC2217Server(port, mode, "127.0.0.1:0")
+	require.NoError(t, err)
+	defer server.Close()
+
+	client, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	request := []byte{telnetIAC, telnetWILL, telnetOptionComPort}
+	request = append(request, telnetIAC, telnetSB, telnetOptionComPort, rfc2217SetBaudRate, 0x00, 0x01, 0xC2, 0x00, telnetIAC, telnetSE)
+	request = append(request, telnetIAC, telnetSB, telnetOptionComPort, rfc2217SetDataSize, 7, telnetIAC, telnetSE)
+	request = append(request, telnetIAC, telnetSB, telnetOptionComPort, rfc2217SetParity, 3, telnetIAC, telnetSE)
+	request = append(request, 'd', 'a', 't', 'a', telnetIAC, telnetIAC)
+	_, err = client.Write(request)
+	require.NoError(t, err)
+
+	b := make([]byte, 64)
+	n := readServerEventually(t, server, b)
+	require.Equal(t, []byte{'d', 'a', 't', 'a', telnetIAC}, b[:n])
+
+	requireReceived(t, client, []byte{telnetIAC, telnetDO, telnetOptionComPort})
+	requireReceived(t, client, []byte{telnetIAC, telnetSB, telnetOptionComPort, 101, 0x00, 0x01, 0xC2, 0x00, telnetIAC, telnetSE})
+	requireReceived(t, client, []byte{telnetIAC, telnetSB, telnetOptionComPort, 102, 7, telnetIAC, telnetSE})
+	requireReceived(t, client, []byte{telnetIAC, telnetSB, telnetOptionComPort, 103, 3, telnetIAC, telnetSE})
+
+	require.Equal(t, goSerial.Mode{BaudRate: 115200, DataBits: 7, Parity: goSerial.EvenParity}, server.Mode)
+	// A PTY always reports 8 data bits and no parity, so only the baud rate applied to the slave can be checked
+	termios, err := unix.IoctlGetTermios(int(slave.Fd()), unix.TCGETS)
+	require.NoError(t, err)
+	require.Equal(t, uint32(unix.B115200), termios.Cflag&unix.CBAUD)
+
+	n, err = server.Write([]byte{'o', 'k', telnetIAC})
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	requireReceived(t, client, []byte{'o', 'k', telnetIAC, telnetIAC})
+}
+
+// Ensure that the current value is returned when the client requests it, and unsupported options are refused.
+func TestRFC2217Server_RequestCurrentValues(t *testing.T) {
+	mode := goSerial.Mode{BaudRate: 9600, DataBits: 8, StopBits: goSerial.TwoStopBits}
+	port, _ := openPTYPort(t, mode)
+
+	server, err := NewRFC2217Server(port, mode, "127.0.0.1:0")
+	require.NoError(t, err)
+	defer server.Close()
+
+	client, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	// Echo (1) is not supported
+	request := []byte{telnetIAC, telnetDO, 1}
+	request = append(request, telnetIAC, telnetSB, telnetOptionComPort, rfc2217SetBaudRate, 0, 0, 0, 0, telnetIAC, telnetSE)
+	request = append(request, telnetIAC, telnetSB, telnetOptionComPort, rfc2217SetStopSize, 0, telnetIAC, telnetSE)
+	request = append(request, telnetIAC, telnetSB, telnetOptionComPort, rfc2217SetControl, controlRequestDTR, telnetIAC, telnetSE)
+	request = append(request, telnetIAC, telnetSB, telnetOptionComPort, rfc2217Signature, telnetIAC, telnetSE)
+	request = append(request, 'x')
+	_, err = client.Write(request)
+	require.NoError(t, err)
+
+	b := make([]byte, 64)
+	n := readServerEventually(t, server, b)
+	require.Equal(t, "x", string(b[:n]))
+
+	requireReceived(t, client, []byte{telnetIAC, telnetWONT, 1})
+	requireReceived(t, client, []byte{telnetIAC, telnetSB, telnetOptionComPort, 101, 0x00, 0x00, 0x25, 0x80, telnetIAC, telnetSE})
+	requireReceived(t, client, []byte{telnetIAC, telnetSB, telnetOptionComPort, 104, 2, telnetIAC, telnetSE})
+	requireReceived(t, client, []byte{telnetIAC, telnetSB, telnetOptionComPort, 105, controlDTROn, telnetIAC, telnetSE})
+	requireReceived(t, client, append(append([]byte{telnetIAC, telnetSB, telnetOptionComPort, 100}, RFC2217Signature...), telnetIAC, telnetSE))
+	require.Equal(t, mode, server.Mode)
+}
+
+// Ensure that data written with no client connected is discarded, and that the next client is served once the
+// current client disconnects.
+func TestRFC2217Server_ClientDisconnects(t *testing.T) {
+	mode := goSerial.Mode{BaudRate: 9600, DataBits: 8}
+	port, _ := openPTYPort(t, mode)
+
+	server, err := NewRFC2217Server(port, mode, "127.0.0.1:0")
+	require.NoError(t, err)
+	defer server.Close()
+
+	n, err := server.Write([]byte("discarded"))
+	require.NoError(t, err)
+	require.Equal(t, len("discarded"), n)
+
+	for _, content := range []string{"first", "second"} {
+		client, err := net.Dial("tcp", server.Listener.Addr().String())
+		require.NoError(t, err)
+		_, err = client.Write([]byte(content))
+		require.NoError(t, err)
+
+		b := make([]byte, 64)
+		n := readServerEventually(t, server, b)
+		require.Equal(t, content, string(b[:n]))
+		require.NoError(t, client.Close())
+
+		// The disconnect is detected on the next read
+		n, err = server.Read(b)
+		require.Equal(t, io.EOF, err)
+		require.Equal(t, 0, n)
+		require.Nil(t, server.conn)
+	}
+}
+
+// Ensure that a break requested by the client does not stall the server while it is sent.
+func TestRFC2217Server_BreakDoesNotBlock(t *testing.T) {
+	mode := goSerial.Mode{BaudRate: 9600, DataBits: 8}
+	port, _ := openPTYPort(t, mode)
+
+	server, err := NewRFC2217Server(port, mode, "127.0.0.1:0")
+	require.NoError(t, err)
+	defer server.Close()
+
+	client, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Write([]byte{telnetIAC, telnetSB, telnetOptionComPort, rfc2217SetControl, controlBreakOn, telnetIAC, telnetSE, 'x'})
+	require.NoError(t, err)
+	start := time.Now()
+	b := make([]byte, 64)
+	n := readServerEventually(t, server, b)
+	require.Equal(t, "x", string(b[:n]))
+	require.Less(t, time.Since(start), RFC2217BreakDuration)
+	requireReceived(t, client, []byte{telnetIAC, telnetSB, telnetOptionComPort, rfc2217SetControl + rfc2217ResponseOffset, controlBreakOn, telnetIAC, telnetSE})
+
+	require.Eventually(t, func() bool {
+		return !server.breaking.Load()
+	}, time.Second, 10*time.Millisecond)
+}
+
+// Ensure that a client that stops reading is disconnected once a write reaches its deadline, rather than blocking.
+func TestRFC2217Server_ClientStopsReading(t *testing.T) {
+	mode := goSerial.Mode{BaudRate: 9600, DataBits: 8}
+	port, _ := openPTYPort(t, mode)
+
+	server, err := NewRFC2217Server(port, mode, "127.0.0.1:0")
+	require.NoError(t, err)
+	defer server.Close()
+
+	client, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	require.Eventually(t, func() bool {
+		server.acceptWaitingConnection()
+		return server.conn != nil
+	}, time.Second, 10*time.Millisecond)
+
+	data := make([]byte, 1024*1024)
+	deadline := time.Now().Add(10 * time.Second)
+	for server.conn != nil && time.Now().Before(deadline) {
+		n, err := server.Write(data)
+		require.NoError(t, err)
+		require.Equal(t, len(data), n)
+	}
+	require.Nil(t, server.conn)
+}
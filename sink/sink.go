@@ -0,0 +1,122 @@
+// Package sink provides writers that discard the data written to them, only counting it, e.g. to benchmark readers
+// without any disk or network I/O.
+package sink
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// Discards the data written to it, the total bytes are reported once it is closed.
+type NullWriter struct {
+	// Where the total is reported
+	Out   io.Writer
+	bytes int64
+}
+
+// NewNullWriter creates a writer that discards its data and reports the total bytes to out once it is closed.
+func NewNullWriter(out io.Writer) *NullWriter {
+	return &NullWriter{Out: out}
+}
+
+// [io.Writer.Write]
+func (w *NullWriter) Write(b []byte) (int, error) {
+	w.bytes += int64(len(b))
+	return len(b), nil
+}
+
+// Returns the total bytes written.
+func (w *NullWriter) Bytes() int64 {
+	return w.bytes
+}
+
+// [io.Closer.Close], reports the total bytes written.
+func (w *NullWriter) Close() error {
+	_, err := fmt.Fprintf(w.Out, "Discarded [%d] bytes.\n", w.bytes)
+	return err
+}
+
+// The data written to a [CounterWriter] from a single reader.
+type Totals struct {
+	Bytes    int64
+	Messages int64
+	// When the first and last data was written
+	First time.Time
+	Last  time.Time
+}
+
+// Returns the bytes written per second between the first and last write, or 0 if there was only a single write.
+func (t Totals) BytesPerSecond() float64 {
+	elapsed := t.Last.Sub(t.First).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(t.Bytes) / elapsed
+}
+
+// Discards the data written to it, counting the bytes and messages (chunks of data or datagrams) of each reader. The
+// totals and throughput of each reader are reported once it is closed.
+type CounterWriter struct {
+	// Where the totals are reported
+	Out    io.Writer
+	totals map[string]*Totals
+	// The reader IDs in the order that they were first written from
+	order []string
+}
+
+// NewCounterWriter creates a writer that counts its data and reports the totals to out once it is closed.
+func NewCounterWriter(out io.Writer) *CounterWriter {
+	return &CounterWriter{Out: out, totals: make(map[string]*Totals)}
+}
+
+// [io.Writer.Write], counts the data without a reader ID. See [CounterWriter.WriteFrom].
+func (w *CounterWriter) Write(b []byte) (int, error) {
+	return w.WriteFrom("", b)
+}
+
+// Counts the data as read from the reader with the provided ID.
+func (w *CounterWriter) WriteFrom(readerID string, b []byte) (int, error) {
+	now := time.Now()
+	totals, exists := w.totals[readerID]
+	if !exists {
+		totals = &Totals{First: now}
+		w.totals[readerID] = totals
+		w.order = append(w.order, readerID)
+	}
+	totals.Bytes += int64(len(b))
+	totals.Messages++
+	totals.Last = now
+	return len(b), nil
+}
+
+// Returns the totals of the reader with the provided ID.
+func (w *CounterWriter) Totals(readerID string) Totals {
+	if totals, exists := w.totals[readerID]; exists {
+		return *totals
+	}
+	return Totals{}
+}
+
+// Returns the IDs of the readers that have been counted, in the order that they were first written from.
+func (w *CounterWriter) ReaderIDs() []string {
+	return slices.Clone(w.order)
+}
+
+// [io.Closer.Close], reports the totals and throughput of each reader.
+func (w *CounterWriter) Close() error {
+	if len(w.order) == 0 {
+		_, err := fmt.Fprintf(w.Out, "Counted [0] bytes.\n")
+		return err
+	}
+	for _, readerID := range w.order {
+		totals := w.totals[readerID]
+		_, err := fmt.Fprintf(w.Out, "Counted [%d] bytes in [%d] messages from reader [%s] over [%.3f] seconds, [%.1f] bytes per second.\n",
+			totals.Bytes, totals.Messages, readerID, totals.Last.Sub(totals.First).Seconds(), totals.BytesPerSecond())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package sink
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNullWriter(t *testing.T) {
+	var report bytes.Buffer
+	w := NewNullWriter(&report)
+	for _, data := range []string{"first", "second"} {
+		n, err := w.Write([]byte(data))
+		require.NoError(t, err)
+		require.Equal(t, len(data), n)
+	}
+	require.Equal(t, int64(11), w.Bytes())
+
+	require.NoError(t, w.Close())
+	require.Equal(t, "Discarded [11] bytes.\n", report.String())
+}
+
+// Ensure that the data of each reader is counted separately, and the throughput is measured between the first and
+// last write.
+func TestCounterWriter(t *testing.T) {
+	var report bytes.Buffer
+	w := NewCounterWriter(&report)
+	_, err := w.WriteFrom("tcp", make([]byte, 100))
+	require.NoError(t, err)
+	_, err = w.WriteFrom("serial", []byte("data"))
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	_, err = w.WriteFrom("tcp", make([]byte, 100))
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"tcp", "serial"}, w.ReaderIDs())
+	tcp := w.Totals("tcp")
+	require.Equal(t, int64(200), tcp.Bytes)
+	require.Equal(t, int64(2), tcp.Messages)
+	require.Greater(t, tcp.BytesPerSecond(), 0.0)
+	require.Less(t, tcp.BytesPerSecond(), 4000.0)
+	require.Equal(t, 0.0, w.Totals("serial").BytesPerSecond())
+	require.Equal(t, Totals{}, w.Totals("missing"))
+
+	require.NoError(t, w.Close())
+	require.Contains(t, report.String(), "Counted [200] bytes in [2] messages from reader [tcp] over [0.0")
+	require.Contains(t, report.String(), "Counted [4] bytes in [1] messages from reader [serial] over [0.000] seconds, [0.0] bytes per second.\n")
+}
+
+func TestCounterWriter_Empty(t *testing.T) {
+	var report bytes.Buffer
+	w := NewCounterWriter(&report)
+	require.NoError(t, w.Close())
+	require.Equal(t, "Counted [0] bytes.\n", report.String())
+}
@@ -0,0 +1,67 @@
+package socket
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// TrimBrackets removes the surrounding brackets of an IPv6 address, e.g. "[::]" to "::".
+func TrimBrackets(addr string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
+
+// Joins the provided address (IPv4, IPv6 with or without brackets, or a hostname) and port into a dialable address.
+func hostPort(addr string, port uint16) string {
+	return net.JoinHostPort(TrimBrackets(addr), strconv.Itoa(int(port)))
+}
+
+// Returns the parsed IP address, or false if the provided address is a hostname.
+func parseIP(addr string) (netip.Addr, bool) {
+	address, err := netip.ParseAddr(TrimBrackets(addr))
+	return address, err == nil
+}
+
+// Returns the [net.ListenConfig] that applies the reuse options to each listener.
+func listenConfig(opts Options) net.ListenConfig {
+	return net.ListenConfig{Control: opts.listenerControl}
+}
+
+// Create a TCP listener on the provided address and port, applying the reuse options. Hostnames are resolved.
+func listenTCP(addr string, port uint16, opts Options) (*net.TCPListener, error) {
+	config := listenConfig(opts)
+	listener, err := config.Listen(context.Background(), "tcp", hostPort(addr, port))
+	if err != nil {
+		return nil, err
+	}
+	return listener.(*net.TCPListener), nil
+}
+
+// Create a UDP connection listening on the provided address and port, applying the reuse options. Hostnames are
+// resolved.
+func listenUDP(addr string, port uint16, opts Options) (*net.UDPConn, error) {
+	config := listenConfig(opts)
+	conn, err := config.ListenPacket(context.Background(), "udp", hostPort(addr, port))
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*net.UDPConn), nil
+}
+
+// Create a dialer for the provided "tcp" or "udp" network, that binds to the [Options.BindAddress] when set.
+func newDialer(network string, opts Options) (*net.Dialer, error) {
+	dialer := &net.Dialer{}
+	if opts.BindAddress == "" {
+		return dialer, nil
+	}
+
+	var err error
+	if network == "udp" {
+		dialer.LocalAddr, err = net.ResolveUDPAddr(network, hostPort(opts.BindAddress, 0))
+	} else {
+		dialer.LocalAddr, err = net.ResolveTCPAddr(network, hostPort(opts.BindAddress, 0))
+	}
+	return dialer, err
+}
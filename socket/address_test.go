@@ -0,0 +1,141 @@
+package socket
+
+import (
+	"io"
+	"net"
+	"net/netip"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Kilemonn/flow/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostPort(t *testing.T) {
+	require.Equal(t, "127.0.0.1:80", hostPort("127.0.0.1", 80))
+	require.Equal(t, "[::]:80", hostPort("::", 80))
+	require.Equal(t, "[::]:80", hostPort("[::]", 80))
+	require.Equal(t, "localhost:80", hostPort("localhost", 80))
+}
+
+// Ensure that hostnames are resolved for both readers and writers.
+func TestTCPReadAndWrite_Hostname(t *testing.T) {
+	reader, err := CreateSocketReader("tcp", "localhost", 0)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	writer, err := CreateSocketWriter("tcp", "localhost", testutil.GetTCPPort(reader.(*TCPTimeoutReader).Listener))
+	require.NoError(t, err)
+	defer writer.Close()
+	require.IsType(t, &ReconnectWriter{}, writer)
+
+	content := "TestTCPReadAndWrite_Hostname"
+	_, err = writer.Write([]byte(content))
+	require.NoError(t, err)
+
+	b := make([]byte, len(content))
+	n := readEventually(t, reader, b)
+	require.Equal(t, content, string(b[:n]))
+}
+
+// Ensure that bracketed IPv6 addresses can be used.
+func TestUDPReadAndWrite_IPv6(t *testing.T) {
+	reader, err := CreateSocketReader("udp", "[::1]", 0)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	writer, err := CreateSocketWriter("udp", "[::1]", testutil.GetUDPPort(reader.(UDPTimeoutReader).Conn))
+	require.NoError(t, err)
+	defer writer.Close()
+
+	content := "TestUDPReadAndWrite_IPv6"
+	_, err = writer.Write([]byte(content))
+	require.NoError(t, err)
+
+	b := make([]byte, len(content))
+	n, err := reader.Read(b)
+	require.NoError(t, err)
+	require.Equal(t, content, string(b[:n]))
+}
+
+// Ensure that the writer's connection originates from the configured bind address.
+func TestTCPWrite_BindAddress(t *testing.T) {
+	// Only Linux routes the whole of 127.0.0.0/8 to the loopback interface by default
+	if runtime.GOOS != "linux" {
+		t.Skip("binding to 127.0.0.2 requires Linux")
+	}
+
+	reader, err := CreateSocketReader("tcp", "0.0.0.0", 0)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	writer, err := CreateSocketWriterWithOptions("tcp", "127.0.0.1", testutil.GetTCPPort(reader.(*TCPTimeoutReader).Listener), Options{BindAddress: "127.0.0.2"})
+	require.NoError(t, err)
+	defer writer.Close()
+
+	require.Equal(t, netip.MustParseAddr("127.0.0.2"), writer.(*net.TCPConn).LocalAddr().(*net.TCPAddr).AddrPort().Addr())
+}
+
+func TestWrite_InvalidBindAddress(t *testing.T) {
+	_, err := CreateSocketWriterWithOptions("udp", "127.0.0.1", 1234, Options{BindAddress: "186753412.123461254.123416254"})
+	require.Error(t, err)
+}
+
+// Ensure that multiple readers can listen on the same port when SO_REUSEPORT is set.
+func TestUDPRead_ReusePort(t *testing.T) {
+	reader, err := CreateSocketReaderWithOptions("udp", "127.0.0.1", 0, Options{ReuseAddr: true, ReusePort: true})
+	require.NoError(t, err)
+	defer reader.Close()
+	port := testutil.GetUDPPort(reader.(UDPTimeoutReader).Conn)
+
+	_, err = CreateSocketReader("udp", "127.0.0.1", port)
+	require.Error(t, err)
+
+	reader2, err := CreateSocketReaderWithOptions("udp", "127.0.0.1", port, Options{ReuseAddr: true, ReusePort: true})
+	require.NoError(t, err)
+	defer reader2.Close()
+}
+
+type closedConn struct {
+	net.Conn
+}
+
+func (c closedConn) Write(b []byte) (int, error) {
+	return 0, net.ErrClosed
+}
+
+func (c closedConn) Close() error {
+	return nil
+}
+
+// Ensure that after a write fails the writer dials a new connection on the next write.
+func TestReconnectWriter_ReconnectsAfterFailure(t *testing.T) {
+	dials := 0
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	writer, err := NewReconnectWriter(func() (net.Conn, error) {
+		dials++
+		if dials == 1 {
+			return closedConn{}, nil
+		}
+		return conn, nil
+	})
+	require.NoError(t, err)
+	defer writer.Close()
+	require.Equal(t, 1, dials)
+
+	_, err = writer.Write([]byte("lost"))
+	require.Error(t, err)
+	require.Equal(t, 1, dials)
+
+	content := "TestReconnectWriter_ReconnectsAfterFailure"
+	go writer.Write([]byte(content))
+
+	b := make([]byte, len(content))
+	peer.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = io.ReadFull(peer, b)
+	require.NoError(t, err)
+	require.Equal(t, content, string(b))
+	require.Equal(t, 2, dials)
+}
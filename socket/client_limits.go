@@ -0,0 +1,72 @@
+package socket
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"time"
+)
+
+// Limits applied to the clients accepted by a [TCPTimeoutReader]. The zero value applies no limits.
+type ClientLimits struct {
+	// The ID of the reader, used when logging rejected and expired clients
+	ID string
+	// The maximum amount of concurrently connected clients, further clients are rejected. No limit when 0.
+	MaxClients int
+	// Clients are disconnected once no data is received from them for this long. No limit when 0.
+	IdleTimeout time.Duration
+	// Clients are disconnected once they have been connected for this long. No limit when 0.
+	Lifetime time.Duration
+	// When not empty, only clients with a remote address within one of these prefixes are accepted
+	Allow []netip.Prefix
+	// Clients with a remote address within one of these prefixes are rejected, this takes priority over
+	// [ClientLimits.Allow]
+	Deny []netip.Prefix
+}
+
+// Returns the reason the client with the provided remote address is rejected by the allow and deny lists, or an
+// empty string if it is allowed. Clients without an IP address (e.g. unix socket clients) are always allowed.
+func (l ClientLimits) rejectReason(remote net.Addr) string {
+	if len(l.Allow) == 0 && len(l.Deny) == 0 {
+		return ""
+	}
+
+	tcpAddr, ok := remote.(*net.TCPAddr)
+	if !ok {
+		return ""
+	}
+	addr := tcpAddr.AddrPort().Addr().Unmap()
+
+	contains := func(prefix netip.Prefix) bool {
+		return prefix.Contains(addr)
+	}
+	if slices.ContainsFunc(l.Deny, contains) {
+		return "address is denied"
+	}
+	if len(l.Allow) > 0 && !slices.ContainsFunc(l.Allow, contains) {
+		return "address is not allowed"
+	}
+	return ""
+}
+
+// Returns the reason the client should be disconnected, or an empty string if it has not expired.
+func (l ClientLimits) expiredReason(now time.Time, accepted time.Time, lastActive time.Time) string {
+	if l.Lifetime > 0 && now.Sub(accepted) >= l.Lifetime {
+		return fmt.Sprintf("connected for longer than %s", l.Lifetime)
+	}
+	if l.IdleTimeout > 0 && now.Sub(lastActive) >= l.IdleTimeout {
+		return fmt.Sprintf("idle for longer than %s", l.IdleTimeout)
+	}
+	return ""
+}
+
+// ParsePrefix parses a CIDR prefix (e.g. "10.0.0.0/8") or a single IP address, which is treated as a prefix that only
+// contains that address.
+func ParsePrefix(s string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(TrimBrackets(s)); err == nil {
+		return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(s)
+	return prefix.Masked(), err
+}
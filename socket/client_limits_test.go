@@ -0,0 +1,143 @@
+package socket
+
+import (
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePrefix(t *testing.T) {
+	for input, expected := range map[string]string{
+		"10.1.2.3/8": "10.0.0.0/8",
+		"127.0.0.1":  "127.0.0.1/32",
+		"::1":        "::1/128",
+		"[::1]":      "::1/128",
+		"fd00::/8":   "fd00::/8",
+	} {
+		prefix, err := ParsePrefix(input)
+		require.NoError(t, err)
+		require.Equal(t, netip.MustParsePrefix(expected), prefix)
+	}
+
+	_, err := ParsePrefix("10.0.0.0/33")
+	require.Error(t, err)
+	_, err = ParsePrefix("not-an-address")
+	require.Error(t, err)
+}
+
+// Creates a TCP reader with the provided limits, and connects the provided amount of clients to it which are then
+// accepted.
+func connectLimitedClients(t *testing.T, limits ClientLimits, count int) (*TCPTimeoutReader, []net.Conn) {
+	reader, err := CreateSocketReaderWithOptions("tcp", "127.0.0.1", 0, Options{ClientLimits: limits})
+	require.NoError(t, err)
+	t.Cleanup(func() { reader.Close() })
+
+	clients := []net.Conn{}
+	for range count {
+		client, err := net.Dial("tcp", reader.(*TCPTimeoutReader).Listener.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+		clients = append(clients, client)
+	}
+	reader.(*TCPTimeoutReader).acceptWaitingConnections()
+	return reader.(*TCPTimeoutReader), clients
+}
+
+// Ensure that the connection of a rejected client is closed, this is either an EOF or a reset if the client had
+// unread data.
+func requireClientClosed(t *testing.T, client net.Conn) {
+	require.NoError(t, client.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err := client.Read(make([]byte, 1))
+	require.Error(t, err)
+	require.NotErrorIs(t, err, os.ErrDeadlineExceeded)
+}
+
+// Ensure that clients are rejected once the maximum amount of clients are connected.
+func TestTCPRead_MaxClients(t *testing.T) {
+	reader, clients := connectLimitedClients(t, ClientLimits{ID: "limited", MaxClients: 1}, 2)
+	require.Equal(t, 1, reader.connectionCount())
+	requireClientClosed(t, clients[1])
+}
+
+// Ensure that clients are rejected by the allow and deny lists.
+func TestTCPRead_AllowAndDeny(t *testing.T) {
+	local := netip.MustParsePrefix("127.0.0.0/8")
+	other := netip.MustParsePrefix("10.0.0.0/8")
+
+	reader, clients := connectLimitedClients(t, ClientLimits{Deny: []netip.Prefix{local}}, 1)
+	require.Equal(t, 0, reader.connectionCount())
+	requireClientClosed(t, clients[0])
+
+	reader, clients = connectLimitedClients(t, ClientLimits{Allow: []netip.Prefix{other}}, 1)
+	require.Equal(t, 0, reader.connectionCount())
+	requireClientClosed(t, clients[0])
+
+	reader, _ = connectLimitedClients(t, ClientLimits{Allow: []netip.Prefix{other, local}}, 1)
+	require.Equal(t, 1, reader.connectionCount())
+
+	// Deny takes priority
+	reader, _ = connectLimitedClients(t, ClientLimits{Allow: []netip.Prefix{local}, Deny: []netip.Prefix{local}}, 1)
+	require.Equal(t, 0, reader.connectionCount())
+}
+
+// Ensure that clients that don't send any data within the idle timeout are disconnected, and the writer is notified.
+func TestTCPRead_IdleTimeout(t *testing.T) {
+	idleTimeout := 100 * time.Millisecond
+	reader, clients := connectLimitedClients(t, ClientLimits{IdleTimeout: idleTimeout}, 2)
+	require.Equal(t, 2, reader.connectionCount())
+
+	time.Sleep(idleTimeout / 2)
+	_, err := clients[1].Write([]byte("active"))
+	require.NoError(t, err)
+	w := &recordingClientWriter{}
+	_, err = reader.WriteTo(w)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("active")}, w.writes)
+
+	time.Sleep(idleTimeout / 2)
+	_, err = reader.WriteTo(w)
+	require.NoError(t, err)
+	require.Equal(t, 1, reader.connectionCount())
+	require.Len(t, w.closed, 1)
+	require.Equal(t, 1, w.closed[0].ID)
+	requireClientClosed(t, clients[0])
+}
+
+// Ensure that clients are disconnected once their lifetime is reached, even if they are sending data.
+func TestTCPRead_Lifetime(t *testing.T) {
+	lifetime := 50 * time.Millisecond
+	reader, clients := connectLimitedClients(t, ClientLimits{Lifetime: lifetime}, 1)
+	require.Equal(t, 1, reader.connectionCount())
+
+	time.Sleep(lifetime)
+	_, err := clients[0].Write([]byte("data"))
+	require.NoError(t, err)
+
+	n, err := reader.Read(make([]byte, 10))
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 0, n)
+	require.Equal(t, 0, reader.connectionCount())
+	requireClientClosed(t, clients[0])
+}
+
+// Ensure that the limits are also applied to unix stream sockets, and that the allow list does not apply to them.
+func TestUnixRead_MaxClients(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flow.sock")
+	reader, err := CreateSocketReaderWithOptions("unix", path, 0, Options{ClientLimits: ClientLimits{MaxClients: 1, Allow: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	for range 2 {
+		client, err := net.Dial("unix", path)
+		require.NoError(t, err)
+		defer client.Close()
+	}
+	reader.(*TCPTimeoutReader).acceptWaitingConnections()
+	require.Equal(t, 1, reader.(*TCPTimeoutReader).connectionCount())
+}
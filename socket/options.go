@@ -0,0 +1,61 @@
+package socket
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Optional settings used when creating socket readers and writers. The zero value is the default behaviour.
+type Options struct {
+	// The name of the network interface used to join a multicast group (reader) or to send multicast packets
+	// from (writer). When empty the system default interface is used.
+	Interface string
+	// The multicast TTL (or hop limit for IPv6) set on multicast writers. When 0 the system default is used.
+	TTL int
+	// Stops multicast writers from looping their sent packets back to the local host.
+	DisableLoopback bool
+	// The file permissions applied to the socket file of listening unix sockets. When 0 the permissions are left
+	// as the system default.
+	Permissions os.FileMode
+	// When set, TCP readers will serve accepted connections over TLS and TCP writers will connect using TLS.
+	TLS *tls.Config
+	// The local address that writers send from, when empty the system chooses the address.
+	BindAddress string
+	// Sets SO_REUSEADDR on listening sockets.
+	ReuseAddr bool
+	// Sets SO_REUSEPORT on listening sockets, allowing multiple processes to listen on the same address and port.
+	// Not supported on windows.
+	ReusePort bool
+	// The limits applied to clients accepted by TCP and unix stream readers.
+	ClientLimits ClientLimits
+	// The deadline of each read and accept attempt of readers, [SocketReadDeadline] is used when 0.
+	ReadDeadline time.Duration
+}
+
+// Resolves the configured [Options.Interface], nil is returned if no interface is configured.
+func (o Options) networkInterface() (*net.Interface, error) {
+	if o.Interface == "" {
+		return nil, nil
+	}
+	return net.InterfaceByName(o.Interface)
+}
+
+// Applies the configured reuse socket options before the socket is bound, see [net.ListenConfig.Control].
+func (o Options) listenerControl(network string, address string, c syscall.RawConn) error {
+	var err error
+	e := c.Control(func(fd uintptr) {
+		if o.ReuseAddr {
+			err = setReuseAddr(fd)
+		}
+		if err == nil && o.ReusePort {
+			err = setReusePort(fd)
+		}
+	})
+	if e != nil {
+		return e
+	}
+	return err
+}
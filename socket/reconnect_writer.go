@@ -0,0 +1,49 @@
+package socket
+
+import (
+	"net"
+)
+
+// A writer that establishes its connection again after a write fails, e.g. when the remote has restarted.
+// Since the connection is dialed again, any hostname is also resolved again.
+type ReconnectWriter struct {
+	dial func() (net.Conn, error)
+	conn net.Conn
+}
+
+// NewReconnectWriter creates a [ReconnectWriter] and immediately establishes its first connection using the
+// provided dial func, an error is returned if this initial connection fails.
+func NewReconnectWriter(dial func() (net.Conn, error)) (*ReconnectWriter, error) {
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	return &ReconnectWriter{dial: dial, conn: conn}, nil
+}
+
+// [io.Writer.Write], if there is no active connection one is established first. If the write fails the connection
+// is closed so that the next Write will reconnect.
+func (w *ReconnectWriter) Write(b []byte) (int, error) {
+	if w.conn == nil {
+		conn, err := w.dial()
+		if err != nil {
+			return 0, err
+		}
+		w.conn = conn
+	}
+
+	n, err := w.conn.Write(b)
+	if err != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	return n, err
+}
+
+// [io.Closer.Close]
+func (w *ReconnectWriter) Close() error {
+	if w.conn == nil {
+		return nil
+	}
+	return w.conn.Close()
+}
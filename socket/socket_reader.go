@@ -10,35 +10,87 @@ import (
 )
 
 const (
+	// The default read and accept deadline of socket readers, see [Options.ReadDeadline]
 	SocketReadDeadline = 10 * time.Millisecond
+	// The deadline of each write to the clients accepted by a [TCPTimeoutReader], so a client that stops reading can't
+	// block the flow
+	SocketWriteDeadline = time.Second
 )
 
+// Returns the provided deadline, or [SocketReadDeadline] if it is not set.
+func readDeadline(deadline time.Duration) time.Duration {
+	if deadline <= 0 {
+		return SocketReadDeadline
+	}
+	return deadline
+}
+
 func CreateSocketReader(protocol string, addr string, port uint16) (io.ReadCloser, error) {
-	if strings.ToLower(protocol) == "tcp" {
-		return NewTCPSocketReader(addr, port)
-	} else if strings.ToLower(protocol) == "udp" {
-		return NewUDPSocketReader(addr, port)
-	} else {
+	return CreateSocketReaderWithOptions(protocol, addr, port, Options{})
+}
+
+// CreateSocketReaderWithOptions creates a socket reader for the provided protocol, applying the provided [Options].
+// If a UDP multicast address is provided, the multicast group will be joined.
+// The address can be an IPv4 or IPv6 address (with or without brackets, e.g. "[::]") or a hostname to be resolved.
+// For the "unix" and "unixgram" protocols the address is the socket file path and the port is ignored.
+func CreateSocketReaderWithOptions(protocol string, addr string, port uint16, opts Options) (io.ReadCloser, error) {
+	switch strings.ToLower(protocol) {
+	case "tcp":
+		return newTCPSocketReader(addr, port, opts)
+	case "udp":
+		if address, ok := parseIP(addr); ok && address.IsMulticast() {
+			return NewUDPMulticastSocketReader(addr, port, opts)
+		}
+		return newUDPSocketReader(addr, port, opts)
+	case "unix":
+		return NewUnixSocketReader(addr, opts)
+	case "unixgram":
+		return NewUnixgramSocketReader(addr, opts)
+	default:
 		return nil, fmt.Errorf("invalid protocol provided [%s]", protocol)
 	}
 }
 
 func NewUDPSocketReader(addr string, port uint16) (io.ReadCloser, error) {
-	address, err := netip.ParseAddr(addr)
+	return newUDPSocketReader(addr, port, Options{})
+}
+
+func newUDPSocketReader(addr string, port uint16, opts Options) (io.ReadCloser, error) {
+	conn, err := listenUDP(addr, port, opts)
+	return newUDPTimeoutReader(conn, opts), err
+}
+
+// NewUDPMulticastSocketReader joins the provided multicast group on the [Options.Interface] (or the system default
+// when not set) and listens on the provided port.
+func NewUDPMulticastSocketReader(addr string, port uint16, opts Options) (io.ReadCloser, error) {
+	address, ok := parseIP(addr)
+	if !ok || !address.IsMulticast() {
+		return nil, fmt.Errorf("address [%s] is not a multicast address", addr)
+	}
+
+	iface, err := opts.networkInterface()
 	if err != nil {
 		return nil, err
 	}
+
 	udpAddr := net.UDPAddrFromAddrPort(netip.AddrPortFrom(address, port))
-	conn, err := net.ListenUDP("udp", udpAddr)
-	return UDPTimeoutReader{Conn: conn}, err
+	conn, err := net.ListenMulticastUDP("udp", iface, udpAddr)
+	return newUDPTimeoutReader(conn, opts), err
 }
 
 func NewTCPSocketReader(addr string, port uint16) (io.ReadCloser, error) {
-	address, err := netip.ParseAddr(addr)
+	return newTCPSocketReader(addr, port, Options{})
+}
+
+// Listens on the provided address, serving accepted connections over TLS if [Options.TLS] is set.
+func newTCPSocketReader(addr string, port uint16, opts Options) (io.ReadCloser, error) {
+	listener, err := listenTCP(addr, port, opts)
 	if err != nil {
 		return nil, err
 	}
-	tcpAddr := net.TCPAddrFromAddrPort(netip.AddrPortFrom(address, port))
-	listener, err := net.ListenTCP("tcp", tcpAddr)
-	return &TCPTimeoutReader{Listener: listener}, err
+
+	if opts.TLS != nil {
+		return &TCPTimeoutReader{Listener: NewTLSListener(listener, opts.TLS, opts.ClientLimits), Limits: opts.ClientLimits, ReadDeadline: opts.ReadDeadline}, nil
+	}
+	return &TCPTimeoutReader{Listener: listener, Limits: opts.ClientLimits, ReadDeadline: opts.ReadDeadline}, nil
 }
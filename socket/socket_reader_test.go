@@ -3,9 +3,11 @@ package socket
 import (
 	"io"
 	"net"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/Kilemonn/flow/clientinfo"
 	"github.com/Kilemonn/flow/testutil"
 	"github.com/stretchr/testify/require"
 )
@@ -162,7 +164,7 @@ func TestTCPRead_DataFromMultipleSockets(t *testing.T) {
 	require.Equal(t, 0, n)
 }
 
-// Ensure that we remove the connection if its remote peer has closed the connection.
+// Ensure that we remove the connection if its remote peer has closed the connection, and the reader has then ended.
 func TestTCPRead_RemoteConnectionCloses(t *testing.T) {
 	reader, err := CreateSocketReader("tcp", "127.0.0.1", 0)
 	require.NoError(t, err)
@@ -175,6 +177,7 @@ func TestTCPRead_RemoteConnectionCloses(t *testing.T) {
 	require.Equal(t, 0, reader.(*TCPTimeoutReader).connectionCount())
 	reader.(*TCPTimeoutReader).acceptWaitingConnections()
 	require.Equal(t, 1, reader.(*TCPTimeoutReader).connectionCount())
+	require.False(t, reader.(*TCPTimeoutReader).Ended())
 
 	err = writer1.(*net.TCPConn).Close()
 	require.NoError(t, err)
@@ -185,6 +188,7 @@ func TestTCPRead_RemoteConnectionCloses(t *testing.T) {
 	require.Equal(t, 0, n)
 
 	require.Equal(t, 0, reader.(*TCPTimeoutReader).connectionCount())
+	require.True(t, reader.(*TCPTimeoutReader).Ended())
 }
 
 func TestCreateSocketReader_invalidProtocol(t *testing.T) {
@@ -216,3 +220,382 @@ func TestNewUDPSocketWriter_invalidAddress(t *testing.T) {
 	_, err := NewUDPSocketWriter("186753412.123461254.123416254", 0)
 	require.Error(t, err)
 }
+
+type recordingWriter struct {
+	writes [][]byte
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.writes = append(w.writes, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+type recordingDatagramWriter struct {
+	recordingWriter
+	datagrams []Datagram
+}
+
+func (w *recordingDatagramWriter) WriteDatagram(d Datagram) (int, error) {
+	w.datagrams = append(w.datagrams, d)
+	return len(d.Data), nil
+}
+
+// Ensure that each datagram is written in its own Write call, even when it is larger than the buffer used by io.Copy.
+func TestUDPWriteTo_PreservesDatagramBoundaries(t *testing.T) {
+	reader, err := NewUDPSocketReader("127.0.0.1", 0)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	writer, err := NewUDPSocketWriter("127.0.0.1", testutil.GetUDPPort(reader.(UDPTimeoutReader).Conn))
+	require.NoError(t, err)
+	defer writer.Close()
+
+	large := make([]byte, 40000)
+	for i := range large {
+		large[i] = byte(i)
+	}
+	small := []byte("TestUDPWriteTo_PreservesDatagramBoundaries")
+
+	_, err = writer.Write(large)
+	require.NoError(t, err)
+	_, err = writer.Write(small)
+	require.NoError(t, err)
+
+	w := &recordingWriter{}
+	n, err := io.Copy(w, reader)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(large)+len(small)), n)
+
+	require.Equal(t, 2, len(w.writes))
+	require.Equal(t, large, w.writes[0])
+	require.Equal(t, small, w.writes[1])
+}
+
+// Ensure that a [DatagramWriter] receives the source address and timestamp of each datagram.
+func TestUDPWriteTo_DatagramWriter(t *testing.T) {
+	reader, err := NewUDPSocketReader("127.0.0.1", 0)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	writer, err := NewUDPSocketWriter("127.0.0.1", testutil.GetUDPPort(reader.(UDPTimeoutReader).Conn))
+	require.NoError(t, err)
+	defer writer.Close()
+
+	content := "TestUDPWriteTo_DatagramWriter"
+	before := time.Now()
+	_, err = writer.Write([]byte(content))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("second"))
+	require.NoError(t, err)
+
+	w := &recordingDatagramWriter{}
+	_, err = reader.(UDPTimeoutReader).WriteTo(w)
+	require.NoError(t, err)
+
+	require.Equal(t, 0, len(w.writes))
+	require.Equal(t, 2, len(w.datagrams))
+	// Each datagram only holds its own data, rather than the whole receive buffer
+	require.Equal(t, content, string(w.datagrams[0].Data))
+	require.Equal(t, len(content), cap(w.datagrams[0].Data))
+	require.Equal(t, "second", string(w.datagrams[1].Data))
+	require.Equal(t, writer.(*net.UDPConn).LocalAddr().(*net.UDPAddr).AddrPort(), w.datagrams[0].Source)
+	require.False(t, w.datagrams[0].Timestamp.Before(before))
+}
+
+// Ensure that a multicast reader that joined a group receives packets sent by a multicast writer to that group.
+func TestUDPMulticastReadAndWrite(t *testing.T) {
+	reader, err := CreateSocketReader("udp", "239.0.0.1", 0)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	writer, err := CreateSocketWriterWithOptions("udp", "239.0.0.1", testutil.GetUDPPort(reader.(UDPTimeoutReader).Conn), Options{TTL: 1})
+	require.NoError(t, err)
+	defer writer.Close()
+
+	content := "TestUDPMulticastReadAndWrite"
+	n, err := writer.Write([]byte(content))
+	require.NoError(t, err)
+	require.Equal(t, len(content), n)
+
+	b := make([]byte, len(content))
+	n, err = reader.Read(b)
+	require.NoError(t, err)
+	require.Equal(t, len(content), n)
+	require.Equal(t, content, string(b))
+}
+
+// Ensure that with loopback disabled the local multicast reader does not receive the sent packet.
+func TestUDPMulticastWrite_DisableLoopback(t *testing.T) {
+	reader, err := CreateSocketReader("udp", "239.0.0.2", 0)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	writer, err := CreateSocketWriterWithOptions("udp", "239.0.0.2", testutil.GetUDPPort(reader.(UDPTimeoutReader).Conn), Options{DisableLoopback: true})
+	require.NoError(t, err)
+	defer writer.Close()
+
+	_, err = writer.Write([]byte("TestUDPMulticastWrite_DisableLoopback"))
+	require.NoError(t, err)
+
+	b := make([]byte, 10)
+	n, err := reader.Read(b)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 0, n)
+}
+
+func TestNewUDPMulticastSocketReader_notMulticast(t *testing.T) {
+	_, err := NewUDPMulticastSocketReader("127.0.0.1", 0, Options{})
+	require.Error(t, err)
+}
+
+func TestNewUDPMulticastSocketWriter_notMulticast(t *testing.T) {
+	_, err := NewUDPMulticastSocketWriter("127.0.0.1", 0, Options{})
+	require.Error(t, err)
+}
+
+func TestNewUDPMulticastSocketReader_invalidInterface(t *testing.T) {
+	_, err := NewUDPMulticastSocketReader("239.0.0.1", 0, Options{Interface: "not-an-interface"})
+	require.Error(t, err)
+}
+
+// Ensure that a writer can send to the broadcast address and a reader listening on all addresses receives it.
+func TestUDPBroadcastReadAndWrite(t *testing.T) {
+	reader, err := NewUDPSocketReader("0.0.0.0", 0)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	writer, err := NewUDPSocketWriter("255.255.255.255", testutil.GetUDPPort(reader.(UDPTimeoutReader).Conn))
+	require.NoError(t, err)
+	defer writer.Close()
+
+	content := "TestUDPBroadcastReadAndWrite"
+	_, err = writer.Write([]byte(content))
+	require.NoError(t, err)
+
+	b := make([]byte, len(content))
+	n, err := reader.Read(b)
+	require.NoError(t, err)
+	require.Equal(t, content, string(b[:n]))
+}
+
+type recordingClientWriter struct {
+	recordingWriter
+	clients []clientinfo.Info
+	closed  []clientinfo.Info
+}
+
+func (w *recordingClientWriter) WriteClient(info clientinfo.Info, b []byte) (int, error) {
+	w.clients = append(w.clients, info)
+	return w.Write(b)
+}
+
+func (w *recordingClientWriter) CloseClient(info clientinfo.Info) error {
+	w.closed = append(w.closed, info)
+	return nil
+}
+
+// Ensure that the data of each TCP client is written with its client information, and the writer is notified once
+// the client disconnects.
+func TestTCPWriteTo_ClientWriter(t *testing.T) {
+	reader, err := CreateSocketReader("tcp", "127.0.0.1", 0)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	port := testutil.GetTCPPort(reader.(*TCPTimeoutReader).Listener)
+	writer1, err := CreateSocketWriter("tcp", "127.0.0.1", port)
+	require.NoError(t, err)
+	defer writer1.Close()
+	writer2, err := CreateSocketWriter("tcp", "127.0.0.1", port)
+	require.NoError(t, err)
+	defer writer2.Close()
+
+	_, err = writer1.Write([]byte("first"))
+	require.NoError(t, err)
+	reader.(*TCPTimeoutReader).acceptWaitingConnections()
+	_, err = writer2.Write([]byte("second"))
+	require.NoError(t, err)
+	require.NoError(t, writer1.Close())
+
+	w := &recordingClientWriter{}
+	_, err = reader.(io.WriterTo).WriteTo(w)
+	require.NoError(t, err)
+
+	require.Len(t, w.clients, 2)
+	contents := map[int]string{}
+	addresses := map[int]string{}
+	for i, info := range w.clients {
+		contents[info.ID] = string(w.writes[i])
+		addresses[info.ID] = info.RemoteAddr
+	}
+	require.Equal(t, "first", contents[1])
+	require.Equal(t, "second", contents[2])
+	require.Equal(t, writer1.(*net.TCPConn).LocalAddr().String(), addresses[1])
+	require.Equal(t, writer2.(*net.TCPConn).LocalAddr().String(), addresses[2])
+
+	require.Len(t, w.closed, 1)
+	require.Equal(t, 1, w.closed[0].ID)
+	require.Equal(t, 1, reader.(*TCPTimeoutReader).connectionCount())
+}
+
+// Ensure that a TCP client that resets its connection is removed and the writer notified, without failing the read
+// of the other clients.
+func TestTCPWriteTo_ClientResets(t *testing.T) {
+	reader, err := CreateSocketReader("tcp", "127.0.0.1", 0)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	port := testutil.GetTCPPort(reader.(*TCPTimeoutReader).Listener)
+	writer1, err := CreateSocketWriter("tcp", "127.0.0.1", port)
+	require.NoError(t, err)
+	defer writer1.Close()
+	writer2, err := CreateSocketWriter("tcp", "127.0.0.1", port)
+	require.NoError(t, err)
+	defer writer2.Close()
+
+	reader.(*TCPTimeoutReader).acceptWaitingConnections()
+	require.Equal(t, 2, reader.(*TCPTimeoutReader).connectionCount())
+
+	// Closing with no linger sends a reset instead of a graceful close
+	require.NoError(t, writer1.(*net.TCPConn).SetLinger(0))
+	require.NoError(t, writer1.Close())
+	time.Sleep(50 * time.Millisecond)
+
+	w := &recordingClientWriter{}
+	_, err = reader.(io.WriterTo).WriteTo(w)
+	require.NoError(t, err)
+	require.Len(t, w.closed, 1)
+	require.Equal(t, 1, w.closed[0].ID)
+	require.Equal(t, 1, reader.(*TCPTimeoutReader).connectionCount())
+
+	_, err = writer2.Write([]byte("second"))
+	require.NoError(t, err)
+	_, err = reader.(io.WriterTo).WriteTo(w)
+	require.NoError(t, err)
+	require.Len(t, w.clients, 1)
+	require.Equal(t, 2, w.clients[0].ID)
+	require.Equal(t, "second", string(w.writes[0]))
+}
+
+// Ensure that data written to the TCP reader is sent back to all of its accepted connections.
+func TestTCPWrite_RepliesToClients(t *testing.T) {
+	reader, err := CreateSocketReader("tcp", "127.0.0.1", 0)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	content := "TestTCPWrite_RepliesToClients"
+	// No clients are connected, so the data is discarded
+	n, err := reader.(io.Writer).Write([]byte(content))
+	require.NoError(t, err)
+	require.Equal(t, len(content), n)
+
+	port := testutil.GetTCPPort(reader.(*TCPTimeoutReader).Listener)
+	clients := []net.Conn{}
+	for range 2 {
+		client, err := net.Dial("tcp", hostPort("127.0.0.1", port))
+		require.NoError(t, err)
+		defer client.Close()
+		clients = append(clients, client)
+	}
+	reader.(*TCPTimeoutReader).acceptWaitingConnections()
+	require.Equal(t, 2, reader.(*TCPTimeoutReader).connectionCount())
+
+	n, err = reader.(io.Writer).Write([]byte(content))
+	require.NoError(t, err)
+	require.Equal(t, len(content), n)
+
+	for _, client := range clients {
+		require.NoError(t, client.SetReadDeadline(time.Now().Add(time.Second)))
+		b := make([]byte, len(content))
+		_, err = io.ReadFull(client, b)
+		require.NoError(t, err)
+		require.Equal(t, content, string(b))
+	}
+}
+
+// Ensure that data written for a client is only sent to that client's connection.
+func TestTCPWriteClient_RepliesToOneClient(t *testing.T) {
+	reader, err := CreateSocketReader("tcp", "127.0.0.1", 0)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	port := testutil.GetTCPPort(reader.(*TCPTimeoutReader).Listener)
+	clients := []net.Conn{}
+	for range 2 {
+		client, err := net.Dial("tcp", hostPort("127.0.0.1", port))
+		require.NoError(t, err)
+		defer client.Close()
+		clients = append(clients, client)
+	}
+	reader.(*TCPTimeoutReader).acceptWaitingConnections()
+	require.Equal(t, 2, reader.(*TCPTimeoutReader).connectionCount())
+
+	content := "TestTCPWriteClient_RepliesToOneClient"
+	second := reader.(*TCPTimeoutReader).clients[1]
+	n, err := reader.(clientinfo.Writer).WriteClient(second, []byte(content))
+	require.NoError(t, err)
+	require.Equal(t, len(content), n)
+
+	require.NoError(t, clients[1].SetReadDeadline(time.Now().Add(time.Second)))
+	b := make([]byte, len(content))
+	_, err = io.ReadFull(clients[1], b)
+	require.NoError(t, err)
+	require.Equal(t, content, string(b))
+
+	require.NoError(t, clients[0].SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	_, err = clients[0].Read(b)
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	// A client that has disconnected is closed and its data is discarded
+	require.NoError(t, reader.(clientinfo.Writer).CloseClient(second))
+	_, err = reader.Read(b)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 1, reader.(*TCPTimeoutReader).connectionCount())
+	n, err = reader.(clientinfo.Writer).WriteClient(second, []byte(content))
+	require.NoError(t, err)
+	require.Equal(t, len(content), n)
+}
+
+// Ensure that the configured read deadline is used instead of the default when there is no data.
+func TestReadDeadline(t *testing.T) {
+	deadline := 5 * SocketReadDeadline
+	for _, protocol := range []string{"tcp", "udp"} {
+		reader, err := CreateSocketReaderWithOptions(protocol, "127.0.0.1", 0, Options{ReadDeadline: deadline})
+		require.NoError(t, err)
+		defer reader.Close()
+
+		testutil.TakesAtleast(t, deadline, func() {
+			n, err := reader.Read(make([]byte, 10))
+			require.Equal(t, io.EOF, err)
+			require.Equal(t, 0, n)
+		})
+	}
+}
+
+// Ensure that once the reader stops accepting, new connections are refused while the accepted connections can still be
+// read from.
+func TestTCPStopAccepting(t *testing.T) {
+	reader, err := CreateSocketReader("tcp", "127.0.0.1", 0)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	port := testutil.GetTCPPort(reader.(*TCPTimeoutReader).Listener)
+	client, err := net.Dial("tcp", hostPort("127.0.0.1", port))
+	require.NoError(t, err)
+	defer client.Close()
+	reader.(*TCPTimeoutReader).acceptWaitingConnections()
+	require.Equal(t, 1, reader.(*TCPTimeoutReader).connectionCount())
+
+	require.NoError(t, reader.(*TCPTimeoutReader).StopAccepting())
+	require.NoError(t, reader.(*TCPTimeoutReader).StopAccepting())
+	_, err = net.Dial("tcp", hostPort("127.0.0.1", port))
+	require.Error(t, err)
+
+	content := "TestTCPStopAccepting"
+	_, err = client.Write([]byte(content))
+	require.NoError(t, err)
+	b := make([]byte, len(content))
+	n, err := reader.Read(b)
+	require.NoError(t, err)
+	require.Equal(t, content, string(b[:n]))
+	require.NoError(t, reader.Close())
+}
@@ -1,37 +1,162 @@
 package socket
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
 	"net/netip"
 	"strings"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 )
 
 func CreateSocketWriter(protocol string, addr string, port uint16) (io.WriteCloser, error) {
-	if strings.ToLower(protocol) == "tcp" {
-		return NewTCPSocketWriter(addr, port)
-	} else if strings.ToLower(protocol) == "udp" {
-		return NewUDPSocketWriter(addr, port)
-	} else {
+	return CreateSocketWriterWithOptions(protocol, addr, port, Options{})
+}
+
+// CreateSocketWriterWithOptions creates a socket writer for the provided protocol, applying the provided [Options].
+// If a UDP multicast address is provided, the multicast options will be applied to the writer.
+// The address can be an IPv4 or IPv6 address (with or without brackets, e.g. "[::1]") or a hostname to be resolved.
+// For the "unix" and "unixgram" protocols the address is the socket file path and the port is ignored.
+func CreateSocketWriterWithOptions(protocol string, addr string, port uint16, opts Options) (io.WriteCloser, error) {
+	switch strings.ToLower(protocol) {
+	case "tcp":
+		return newTCPSocketWriter(addr, port, opts)
+	case "udp":
+		if address, ok := parseIP(addr); ok && address.IsMulticast() {
+			return NewUDPMulticastSocketWriter(addr, port, opts)
+		}
+		return newUDPSocketWriter(addr, port, opts)
+	case "unix":
+		return NewUnixSocketWriter(addr)
+	case "unixgram":
+		return NewUnixgramSocketWriter(addr)
+	default:
 		return nil, fmt.Errorf("invalid protocol provided [%s]", protocol)
 	}
 }
 
+// NewUDPSocketWriter creates a UDP writer for the provided address. Broadcast addresses (e.g. 255.255.255.255 or a
+// subnet's broadcast address) are also supported.
 func NewUDPSocketWriter(addr string, port uint16) (io.WriteCloser, error) {
-	address, err := netip.ParseAddr(addr)
+	return newUDPSocketWriter(addr, port, Options{})
+}
+
+func newUDPSocketWriter(addr string, port uint16, opts Options) (io.WriteCloser, error) {
+	dialer, err := newDialer("udp", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := dialer.Dial("udp", hostPort(addr, port))
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// NewUDPMulticastSocketWriter creates a writer that sends to the provided multicast group, from the
+// [Options.Interface] with the configured [Options.TTL] and loopback settings.
+func NewUDPMulticastSocketWriter(addr string, port uint16, opts Options) (io.WriteCloser, error) {
+	address, ok := parseIP(addr)
+	if !ok || !address.IsMulticast() {
+		return nil, fmt.Errorf("address [%s] is not a multicast address", addr)
+	}
+
+	iface, err := opts.networkInterface()
+	if err != nil {
+		return nil, err
+	}
+
+	network := "udp4"
+	if address.Is6() {
+		network = "udp6"
+	}
+	var bindAddr *net.UDPAddr
+	if opts.BindAddress != "" {
+		bindAddr, err = net.ResolveUDPAddr(network, hostPort(opts.BindAddress, 0))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The socket is left unconnected, so the multicast interface is respected on each send
+	conn, err := net.ListenUDP(network, bindAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setMulticastOptions(conn, address.Is6(), iface, opts)
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
-	udpAddr := net.UDPAddrFromAddrPort(netip.AddrPortFrom(address, port))
-	return net.DialUDP("udp", nil, udpAddr)
+
+	return UDPMulticastWriter{
+		Conn: conn,
+		Addr: net.UDPAddrFromAddrPort(netip.AddrPortFrom(address, port)),
+	}, nil
+}
+
+func setMulticastOptions(conn *net.UDPConn, isIPv6 bool, iface *net.Interface, opts Options) error {
+	if isIPv6 {
+		p := ipv6.NewPacketConn(conn)
+		if iface != nil {
+			if err := p.SetMulticastInterface(iface); err != nil {
+				return err
+			}
+		}
+		if opts.TTL > 0 {
+			if err := p.SetMulticastHopLimit(opts.TTL); err != nil {
+				return err
+			}
+		}
+		return p.SetMulticastLoopback(!opts.DisableLoopback)
+	}
+
+	p := ipv4.NewPacketConn(conn)
+	if iface != nil {
+		if err := p.SetMulticastInterface(iface); err != nil {
+			return err
+		}
+	}
+	if opts.TTL > 0 {
+		if err := p.SetMulticastTTL(opts.TTL); err != nil {
+			return err
+		}
+	}
+	return p.SetMulticastLoopback(!opts.DisableLoopback)
 }
 
 func NewTCPSocketWriter(addr string, port uint16) (io.WriteCloser, error) {
-	address, err := netip.ParseAddr(addr)
+	return newTCPSocketWriter(addr, port, Options{})
+}
+
+// Connects to the provided address, using TLS if [Options.TLS] is set. When a hostname is provided a [ReconnectWriter]
+// is returned so that the hostname is resolved again if the connection needs to be re-established.
+func newTCPSocketWriter(addr string, port uint16, opts Options) (io.WriteCloser, error) {
+	dialer, err := newDialer("tcp", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	dial := func() (net.Conn, error) {
+		if opts.TLS != nil {
+			dialer.Timeout = TLSHandshakeTimeout
+			return tls.DialWithDialer(dialer, "tcp", hostPort(addr, port), opts.TLS)
+		}
+		return dialer.Dial("tcp", hostPort(addr, port))
+	}
+
+	if _, ok := parseIP(addr); !ok {
+		return NewReconnectWriter(dial)
+	}
+
+	conn, err := dial()
 	if err != nil {
 		return nil, err
 	}
-	tcpAddr := net.TCPAddrFromAddrPort(netip.AddrPortFrom(address, port))
-	return net.DialTCP("tcp", nil, tcpAddr)
+	return conn, nil
 }
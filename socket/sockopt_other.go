@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd || windows)
+
+package socket
+
+import (
+	"errors"
+)
+
+func setReuseAddr(fd uintptr) error {
+	return errors.New("SO_REUSEADDR is not supported on this platform")
+}
+
+func setReusePort(fd uintptr) error {
+	return errors.New("SO_REUSEPORT is not supported on this platform")
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package socket
+
+import (
+	"errors"
+	"syscall"
+)
+
+func setReuseAddr(fd uintptr) error {
+	return syscall.SetsockoptInt(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+}
+
+func setReusePort(fd uintptr) error {
+	return errors.New("SO_REUSEPORT is not supported on windows")
+}
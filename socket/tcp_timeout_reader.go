@@ -1,17 +1,40 @@
 package socket
 
 import (
+	"fmt"
+	"io"
 	"net"
 	"slices"
 	"time"
 
+	"github.com/Kilemonn/flow/clientinfo"
 	"github.com/Kilemonn/flow/queuedreader"
 )
 
+// A [net.Listener] that supports an accept deadline, e.g. [net.TCPListener] and [net.UnixListener].
+type DeadlineListener interface {
+	net.Listener
+	SetDeadline(t time.Time) error
+}
+
+// Accepts and reads from all incoming connections of a stream listener. This is used for both TCP and unix stream
+// sockets. Data can also be written back to the accepted connections, see [TCPTimeoutReader.Write].
 type TCPTimeoutReader struct {
-	Listener *net.TCPListener
-	Conns    []*net.TCPConn
-	indicies []int
+	Listener DeadlineListener
+	Conns    []net.Conn
+	Limits   ClientLimits
+	// The deadline of each accept and read attempt, [SocketReadDeadline] is used when 0
+	ReadDeadline time.Duration
+	// The client information and the time data was last received of each connection in [TCPTimeoutReader.Conns],
+	// at the same index
+	clients      []clientinfo.Info
+	lastActive   []time.Time
+	nextClientID int
+	indicies     []int
+	// Clients that have been removed but not yet returned by [TCPTimeoutReader.removeClosedConnections]
+	removed []clientinfo.Info
+	// Set once [TCPTimeoutReader.StopAccepting] has closed the listener
+	stopped bool
 }
 
 // Close all connections then the listener. Only the first occurring error will be returned.
@@ -24,68 +47,239 @@ func (r TCPTimeoutReader) Close() error {
 		}
 	}
 
-	e := r.Listener.Close()
-	if e != nil && err == nil {
-		err = e
+	if !r.stopped {
+		e := r.Listener.Close()
+		if e != nil && err == nil {
+			err = e
+		}
 	}
 
 	return err
 }
 
+// Closes the listener so no new connections are accepted, while data can still be read from and written to the
+// connections that are already accepted.
+func (r *TCPTimeoutReader) StopAccepting() error {
+	if r.stopped {
+		return nil
+	}
+	r.stopped = true
+	return r.Listener.Close()
+}
+
 // Get the amount of active connections
 func (r *TCPTimeoutReader) connectionCount() int {
 	return len(r.Conns)
 }
 
+// Returns true once at least one client has connected and every client has since disconnected.
+func (r *TCPTimeoutReader) Ended() bool {
+	return r.nextClientID > 0 && len(r.Conns) == 0
+}
+
 // Check if any incoming connections are pending to be accepted.
-// This is naturally blocking, so there is a deadline set for [ScoketReadDeadline]
+// This is naturally blocking, so there is a deadline set for [TCPTimeoutReader.ReadDeadline]
 // before this function returns with no accepted connections.
+// Connections that are not allowed by the [TCPTimeoutReader.Limits] are closed immediately.
 func (r *TCPTimeoutReader) acceptWaitingConnections() {
-	for {
-		r.Listener.SetDeadline(time.Now().Add(SocketReadDeadline))
-		conn, err := r.Listener.AcceptTCP()
+	for !r.stopped {
+		r.Listener.SetDeadline(time.Now().Add(readDeadline(r.ReadDeadline)))
+		conn, err := r.Listener.Accept()
 		if err != nil {
-			// We got an error and it IS a timeout so leave without error
-			if e, ok := err.(net.Error); ok && e.Timeout() {
-				return
-			}
+			// We got an error, if it is a timeout there are no more pending connections,
+			// otherwise the listener can't accept anything right now
+			return
 		}
 
+		reason := r.Limits.rejectReason(conn.RemoteAddr())
+		if reason == "" && r.Limits.MaxClients > 0 && len(r.Conns)-len(r.indicies) >= r.Limits.MaxClients {
+			reason = fmt.Sprintf("maximum of %d clients reached", r.Limits.MaxClients)
+		}
+		if reason != "" {
+			fmt.Printf("Rejected connection from [%s] on reader [%s]. Reason: [%s].\n", conn.RemoteAddr(), r.Limits.ID, reason)
+			conn.Close()
+			continue
+		}
+
+		now := time.Now()
+		r.nextClientID++
 		r.Conns = append(r.Conns, conn)
+		r.lastActive = append(r.lastActive, now)
+		r.clients = append(r.clients, clientinfo.Info{
+			ID:         r.nextClientID,
+			RemoteAddr: conn.RemoteAddr().String(),
+			AcceptTime: now,
+		})
 	}
 }
 
-// Removes connections from the connections list that have been marked for removal.
-func (r *TCPTimeoutReader) removeClosedConnections() {
-	if len(r.indicies) == 0 {
+// Marks the connections that have exceeded the idle timeout or lifetime of the [TCPTimeoutReader.Limits] for removal.
+func (r *TCPTimeoutReader) expireConnections() {
+	if r.Limits.IdleTimeout <= 0 && r.Limits.Lifetime <= 0 {
 		return
 	}
 
+	now := time.Now()
+	for i, conn := range r.Conns {
+		if slices.Contains(r.indicies, i) {
+			continue
+		}
+		reason := r.Limits.expiredReason(now, r.clients[i].AcceptTime, r.lastActive[i])
+		if reason != "" {
+			fmt.Printf("Closed connection from [%s] on reader [%s]. Reason: [%s].\n", conn.RemoteAddr(), r.Limits.ID, reason)
+			r.markClosed(i)
+		}
+	}
+	// Remove them now so they are not read from, they are returned by the next call to removeClosedConnections
+	r.removed = r.removeClosedConnections()
+}
+
+// Marks the connection at the provided index to be removed, if it is not already marked.
+func (r *TCPTimeoutReader) markClosed(i int) {
+	if !slices.Contains(r.indicies, i) {
+		r.indicies = append(r.indicies, i)
+	}
+}
+
+// Closes and removes connections from the connections list that have been marked for removal.
+// The client information of the removed connections is returned, including any expired connections that were already
+// removed.
+func (r *TCPTimeoutReader) removeClosedConnections() []clientinfo.Info {
+	removed := r.removed
+	r.removed = nil
+	if len(r.indicies) == 0 {
+		return removed
+	}
+
 	slices.Sort(r.indicies)
 	// Sort and then reverse iterate so we don't change any of the indicies of further forward elements when we remove them
 	for _, i := range slices.Backward(r.indicies) {
+		r.Conns[i].Close()
+		removed = append(removed, r.clients[i])
 		r.Conns = append(r.Conns[:i], r.Conns[i+1:]...)
+		r.clients = append(r.clients[:i], r.clients[i+1:]...)
+		r.lastActive = append(r.lastActive[:i], r.lastActive[i+1:]...)
 	}
 	r.indicies = []int(nil)
+	return removed
 }
 
 // Firstly calls [acceptWaitingConnections].
 // Then wraps the read with a deadline to timeout the Read attempt if there is no incoming data.
-// Timeout used is [SocketReadDeadline].
+// Timeout used is [TCPTimeoutReader.ReadDeadline].
 // Removes any connections that have been closed.
 func (r *TCPTimeoutReader) Read(b []byte) (n int, err error) {
-	// Firstly we need to accept any connections and add them to our connection list
+	n, _, err = r.readClient(b)
+	r.removeClosedConnections()
+	return n, err
+}
+
+// [io.Writer.Write], writes the data to all currently accepted connections. This allows replying to the clients
+// that have connected to this reader. If there are no accepted connections the data is discarded.
+// Connections that fail to be written to are logged and removed on the next read.
+func (r *TCPTimeoutReader) Write(b []byte) (int, error) {
+	for i := range r.Conns {
+		if !slices.Contains(r.indicies, i) {
+			r.writeConn(i, b)
+		}
+	}
+	return len(b), nil
+}
+
+// [clientinfo.Writer.WriteClient], writes the data to the connection of the provided client only. If the client is no
+// longer connected the data is discarded.
+func (r *TCPTimeoutReader) WriteClient(info clientinfo.Info, b []byte) (int, error) {
+	i := r.clientIndex(info)
+	if i >= 0 && !slices.Contains(r.indicies, i) {
+		r.writeConn(i, b)
+	}
+	return len(b), nil
+}
+
+// [clientinfo.Writer.CloseClient], closes the connection of the provided client on the next read.
+func (r *TCPTimeoutReader) CloseClient(info clientinfo.Info) error {
+	if i := r.clientIndex(info); i >= 0 {
+		r.markClosed(i)
+	}
+	return nil
+}
+
+// Returns the index of the connection of the provided client, or -1 if it is no longer connected.
+func (r *TCPTimeoutReader) clientIndex(info clientinfo.Info) int {
+	return slices.IndexFunc(r.clients, func(client clientinfo.Info) bool {
+		return client.ID == info.ID
+	})
+}
+
+// Writes the data to the connection at the provided index within the [SocketWriteDeadline]. If the write fails the
+// connection is marked for removal.
+func (r *TCPTimeoutReader) writeConn(i int, b []byte) {
+	conn := r.Conns[i]
+	conn.SetWriteDeadline(time.Now().Add(SocketWriteDeadline))
+	_, err := conn.Write(b)
+	if err != nil {
+		fmt.Printf("Failed to write to connection from [%s] on reader [%s]. Error: [%s].\n", conn.RemoteAddr(), r.Limits.ID, err.Error())
+		r.markClosed(i)
+	}
+}
+
+// Performs the same as [TCPTimeoutReader.Read] but also returns the information of the client that was read from.
+// Closed connections, and connections that fail to be read from, are marked for removal but not removed.
+func (r *TCPTimeoutReader) readClient(b []byte) (int, clientinfo.Info, error) {
+	// Firstly we need to expire and accept any connections and add them to our connection list
+	r.expireConnections()
 	r.acceptWaitingConnections()
-	defer r.removeClosedConnections()
 
 	q := queuedreader.NewQueuedReader(r.Conns)
-	q.SetPreReadHandlerFunc(func(conn *net.TCPConn) {
-		conn.SetReadDeadline(time.Now().Add(SocketReadDeadline))
+	q.SetPreReadHandlerFunc(func(conn net.Conn) {
+		conn.SetReadDeadline(time.Now().Add(readDeadline(r.ReadDeadline)))
 	})
 	// EOF occurs when the remote closes the connection OR when there is no data to be read (depending on the reader)
-	q.SetEOFHandlerFunc(func(i int, conn *net.TCPConn) {
-		r.indicies = append(r.indicies, i)
+	q.SetEOFHandlerFunc(func(i int, conn net.Conn) {
+		r.markClosed(i)
 	})
 
-	return q.Read(b)
+	n, i, err := q.ReadWithIndex(b)
+	if i < 0 {
+		return n, clientinfo.Info{}, err
+	}
+	if err != nil {
+		// A failed read (e.g. the connection was reset) only ends this connection, not the whole reader
+		fmt.Printf("Failed to read from connection from [%s] on reader [%s]. Error: [%s].\n", r.Conns[i].RemoteAddr(), r.Limits.ID, err.Error())
+		r.markClosed(i)
+		err = nil
+		if n == 0 {
+			err = io.EOF
+		}
+	}
+	if n > 0 {
+		r.lastActive[i] = time.Now()
+	}
+	return n, r.clients[i], err
+}
+
+// [io.WriterTo], this is preferred by [io.Copy] over [TCPTimeoutReader.Read].
+// If the provided [io.Writer] is a [clientinfo.Writer], the data of each client is written along with its
+// [clientinfo.Info] and the writer is notified once a client disconnects.
+// Returns once no client has any data to be read.
+func (r *TCPTimeoutReader) WriteTo(w io.Writer) (n int64, err error) {
+	b := make([]byte, 32*1024)
+	for {
+		read, info, err := r.readClient(b)
+		if closeErr := clientinfo.Close(w, r.removeClosedConnections()); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			if err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+
+		written, err := clientinfo.Write(w, info, b[:read])
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+	}
 }
@@ -0,0 +1,138 @@
+package socket
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// The maximum time allowed for an accepted client to complete its TLS handshake
+	TLSHandshakeTimeout = 10 * time.Second
+)
+
+// A [DeadlineListener] that accepts and performs the TLS handshake of each connection in the background, so that a
+// TLS writer can complete its handshake before the reader is next polled and a slow client can't block the reader.
+// Connections are only returned by [TLSListener.Accept] once their handshake succeeds, and connections from addresses
+// that are not allowed by the [ClientLimits] are closed before their handshake.
+type TLSListener struct {
+	Listener  net.Listener
+	config    *tls.Config
+	limits    ClientLimits
+	deadline  time.Time
+	ready     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewTLSListener wraps the provided listener and starts accepting connections in the background.
+func NewTLSListener(listener net.Listener, config *tls.Config, limits ClientLimits) *TLSListener {
+	l := &TLSListener{
+		Listener: listener,
+		config:   config,
+		limits:   limits,
+		ready:    make(chan net.Conn),
+		closed:   make(chan struct{}),
+	}
+	go l.acceptConnections()
+	return l
+}
+
+func (l *TLSListener) acceptConnections() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			select {
+			case <-l.closed:
+				return
+			default:
+				fmt.Printf("Failed to accept TLS connection on [%s]. Error: [%s].\n", l.Addr(), err.Error())
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				time.Sleep(SocketReadDeadline)
+				continue
+			}
+		}
+		reason := l.limits.rejectReason(conn.RemoteAddr())
+		if reason != "" {
+			fmt.Printf("Rejected connection from [%s] on reader [%s]. Reason: [%s].\n", conn.RemoteAddr(), l.limits.ID, reason)
+			conn.Close()
+			continue
+		}
+		go l.handshake(tls.Server(conn, l.config))
+	}
+}
+
+func (l *TLSListener) handshake(conn *tls.Conn) {
+	conn.SetDeadline(time.Now().Add(TLSHandshakeTimeout))
+	err := conn.Handshake()
+	if err != nil {
+		fmt.Printf("TLS handshake failed with client [%s]. Error: [%s].\n", conn.RemoteAddr(), err.Error())
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	select {
+	case l.ready <- conn:
+	case <-l.closed:
+		conn.Close()
+	}
+}
+
+// [net.Listener.Accept], returns the next connection that has completed its handshake. Blocks until one is ready or
+// the deadline set by [TLSListener.SetDeadline] is reached.
+func (l *TLSListener) Accept() (net.Conn, error) {
+	var timeout <-chan time.Time
+	if !l.deadline.IsZero() {
+		timer := time.NewTimer(time.Until(l.deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case conn := <-l.ready:
+		return conn, nil
+	case <-timeout:
+		return nil, os.ErrDeadlineExceeded
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// [net.Listener.Close], can be called more than once.
+func (l *TLSListener) Close() error {
+	err := net.ErrClosed
+	l.closeOnce.Do(func() {
+		close(l.closed)
+		err = l.Listener.Close()
+	})
+	return err
+}
+
+// [net.Listener.Addr]
+func (l *TLSListener) Addr() net.Addr {
+	return l.Listener.Addr()
+}
+
+// [DeadlineListener.SetDeadline]
+func (l *TLSListener) SetDeadline(t time.Time) error {
+	l.deadline = t
+	return nil
+}
+
+// NewTLSSocketReader listens for TCP connections that will be served over TLS with the provided [tls.Config].
+func NewTLSSocketReader(addr string, port uint16, config *tls.Config) (io.ReadCloser, error) {
+	return newTCPSocketReader(addr, port, Options{TLS: config})
+}
+
+// NewTLSSocketWriter connects to the provided TCP address and completes the TLS handshake using the provided [tls.Config].
+func NewTLSSocketWriter(addr string, port uint16, config *tls.Config) (io.WriteCloser, error) {
+	return newTCPSocketWriter(addr, port, Options{TLS: config})
+}
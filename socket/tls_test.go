@@ -0,0 +1,131 @@
+package socket
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Kilemonn/flow/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTLSConfigs(t *testing.T, certs testutil.TestCertificates, withClientCert bool) (*tls.Config, *tls.Config) {
+	serverCert, err := tls.LoadX509KeyPair(certs.ServerCertFile, certs.ServerKeyFile)
+	require.NoError(t, err)
+	caData, err := os.ReadFile(certs.CAFile)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(caData))
+
+	server := &tls.Config{Certificates: []tls.Certificate{serverCert}, ClientCAs: pool}
+	client := &tls.Config{RootCAs: pool, ServerName: "localhost"}
+	if withClientCert {
+		clientCert, err := tls.LoadX509KeyPair(certs.ClientCertFile, certs.ClientKeyFile)
+		require.NoError(t, err)
+		client.Certificates = []tls.Certificate{clientCert}
+	}
+	return server, client
+}
+
+// Read from the reader until data is returned, since the TLS handshake of accepted connections happens in the background.
+func readEventually(t *testing.T, reader io.Reader, b []byte) int {
+	var n int
+	require.Eventually(t, func() bool {
+		var err error
+		n, err = reader.Read(b)
+		return err == nil && n > 0
+	}, 2*time.Second, SocketReadDeadline)
+	return n
+}
+
+// Ensure that a TLS writer can send data to a TLS reader.
+func TestTLSReadAndWrite(t *testing.T) {
+	server, client := loadTLSConfigs(t, testutil.CreateTestCertificates(t), false)
+
+	reader, err := CreateSocketReaderWithOptions("tcp", "127.0.0.1", 0, Options{TLS: server})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	writer, err := CreateSocketWriterWithOptions("tcp", "127.0.0.1", testutil.GetTCPPort(reader.(*TCPTimeoutReader).Listener), Options{TLS: client})
+	require.NoError(t, err)
+	defer writer.Close()
+	require.IsType(t, &tls.Conn{}, writer)
+
+	content := "TestTLSReadAndWrite"
+	_, err = writer.Write([]byte(content))
+	require.NoError(t, err)
+
+	b := make([]byte, len(content))
+	n := readEventually(t, reader, b)
+	require.Equal(t, content, string(b[:n]))
+}
+
+// Ensure that when client verification is required, a client without a certificate is rejected
+// and a client with a certificate signed by the CA is accepted.
+func TestTLSRead_MutualTLS(t *testing.T) {
+	certs := testutil.CreateTestCertificates(t)
+	server, clientWithoutCert := loadTLSConfigs(t, certs, false)
+	server.ClientAuth = tls.RequireAndVerifyClientCert
+	_, clientWithCert := loadTLSConfigs(t, certs, true)
+
+	reader, err := NewTLSSocketReader("127.0.0.1", 0, server)
+	require.NoError(t, err)
+	defer reader.Close()
+	port := testutil.GetTCPPort(reader.(*TCPTimeoutReader).Listener)
+
+	// With TLS 1.3 the client handshake completes before the server verifies the client certificate,
+	// so the rejection is only seen by the server
+	rejected, err := NewTLSSocketWriter("127.0.0.1", port, clientWithoutCert)
+	if err == nil {
+		defer rejected.Close()
+		rejected.Write([]byte("rejected"))
+	}
+
+	accepted, err := NewTLSSocketWriter("127.0.0.1", port, clientWithCert)
+	require.NoError(t, err)
+	defer accepted.Close()
+
+	content := "TestTLSRead_MutualTLS"
+	_, err = accepted.Write([]byte(content))
+	require.NoError(t, err)
+
+	b := make([]byte, len(content))
+	n := readEventually(t, reader, b)
+	require.Equal(t, content, string(b[:n]))
+	require.Equal(t, 1, reader.(*TCPTimeoutReader).connectionCount())
+}
+
+// Ensure that a writer does not connect to a server whose certificate is not trusted.
+func TestTLSWrite_UntrustedServer(t *testing.T) {
+	server, _ := loadTLSConfigs(t, testutil.CreateTestCertificates(t), false)
+
+	reader, err := NewTLSSocketReader("127.0.0.1", 0, server)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	_, err = NewTLSSocketWriter("127.0.0.1", testutil.GetTCPPort(reader.(*TCPTimeoutReader).Listener), &tls.Config{ServerName: "localhost"})
+	require.Error(t, err)
+}
+
+// Ensure that a denied client is closed without a handshake, and that the listener can be closed more than once.
+func TestTLSRead_DeniedBeforeHandshake(t *testing.T) {
+	server, _ := loadTLSConfigs(t, testutil.CreateTestCertificates(t), false)
+
+	reader, err := CreateSocketReaderWithOptions("tcp", "127.0.0.1", 0, Options{TLS: server, ClientLimits: ClientLimits{Deny: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}}})
+	require.NoError(t, err)
+	listener := reader.(*TCPTimeoutReader).Listener
+
+	// A plain client that never starts the handshake is still closed
+	client, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	requireClientClosed(t, client)
+
+	require.NoError(t, reader.Close())
+	require.ErrorIs(t, listener.Close(), net.ErrClosed)
+}
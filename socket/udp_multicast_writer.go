@@ -0,0 +1,22 @@
+package socket
+
+import (
+	"net"
+)
+
+// A writer that sends each Write as a single datagram to the multicast group [UDPMulticastWriter.Addr].
+// An unconnected [net.UDPConn] is used so that the configured multicast interface is used for every send.
+type UDPMulticastWriter struct {
+	Conn *net.UDPConn
+	Addr *net.UDPAddr
+}
+
+// [io.Writer.Write]
+func (w UDPMulticastWriter) Write(b []byte) (int, error) {
+	return w.Conn.WriteToUDP(b, w.Addr)
+}
+
+// [io.Closer.Close]
+func (w UDPMulticastWriter) Close() error {
+	return w.Conn.Close()
+}
@@ -3,11 +3,47 @@ package socket
 import (
 	"io"
 	"net"
+	"net/netip"
 	"time"
 )
 
+const (
+	// The largest possible UDP payload, used as the read buffer size so that no datagram is ever truncated.
+	MaxDatagramSize = 65535
+)
+
+// A single received datagram along with its metadata.
+type Datagram struct {
+	Data      []byte
+	Source    netip.AddrPort
+	Timestamp time.Time
+}
+
+// A writer that wants to receive each datagram (and its metadata) as a single unit rather than as plain bytes.
+// When the destination of [UDPTimeoutReader.WriteTo] implements this, [DatagramWriter.WriteDatagram] is called
+// instead of [io.Writer.Write].
+type DatagramWriter interface {
+	WriteDatagram(d Datagram) (int, error)
+}
+
+// Write the provided datagram to the [io.Writer], using [DatagramWriter.WriteDatagram] if it is supported.
+func WriteDatagram(w io.Writer, d Datagram) (int, error) {
+	if dw, ok := w.(DatagramWriter); ok {
+		return dw.WriteDatagram(d)
+	}
+	return w.Write(d.Data)
+}
+
 type UDPTimeoutReader struct {
 	Conn *net.UDPConn
+	// The deadline of each read, [SocketReadDeadline] is used when 0
+	ReadDeadline time.Duration
+	// The buffer that each datagram is received into before it is copied
+	buffer []byte
+}
+
+func newUDPTimeoutReader(conn *net.UDPConn, opts Options) UDPTimeoutReader {
+	return UDPTimeoutReader{Conn: conn, ReadDeadline: opts.ReadDeadline, buffer: make([]byte, MaxDatagramSize)}
 }
 
 func (r UDPTimeoutReader) Close() error {
@@ -15,9 +51,11 @@ func (r UDPTimeoutReader) Close() error {
 }
 
 // Wraps the read with a deadline to timeout the Read attempt if there is no incoming data.
-// Timeout used is [SocketReadDeadline].
+// Timeout used is [UDPTimeoutReader.ReadDeadline].
+// Note that if the provided buffer is smaller than the incoming datagram, the remaining bytes are discarded.
+// Prefer [UDPTimeoutReader.WriteTo] or [UDPTimeoutReader.ReadDatagram] where datagram boundaries matter.
 func (r UDPTimeoutReader) Read(b []byte) (n int, err error) {
-	r.Conn.SetReadDeadline(time.Now().Add(SocketReadDeadline))
+	r.Conn.SetReadDeadline(time.Now().Add(readDeadline(r.ReadDeadline)))
 	n, err = r.Conn.Read(b)
 	if err != nil {
 		// We got an error and it IS a timeout so leave without error
@@ -28,3 +66,50 @@ func (r UDPTimeoutReader) Read(b []byte) (n int, err error) {
 	}
 	return
 }
+
+// ReadDatagram reads a single whole datagram, recording its source address and the time it was received.
+// Timeout used is [UDPTimeoutReader.ReadDeadline], [io.EOF] is returned if no datagram arrives before the deadline.
+// The datagram is received into the reader's buffer and its data is copied, so a queued datagram only keeps its own
+// data in memory.
+func (r UDPTimeoutReader) ReadDatagram() (Datagram, error) {
+	b := r.buffer
+	if b == nil {
+		b = make([]byte, MaxDatagramSize)
+	}
+	r.Conn.SetReadDeadline(time.Now().Add(readDeadline(r.ReadDeadline)))
+	n, source, err := r.Conn.ReadFromUDPAddrPort(b)
+	if err != nil {
+		if e, ok := err.(net.Error); ok && e.Timeout() {
+			return Datagram{}, io.EOF
+		}
+		return Datagram{}, err
+	}
+
+	return Datagram{
+		Data:      append(make([]byte, 0, n), b[:n]...),
+		Source:    source,
+		Timestamp: time.Now(),
+	}, nil
+}
+
+// [io.WriterTo], this is preferred by [io.Copy] over [UDPTimeoutReader.Read].
+// Each received datagram is handed to the provided [io.Writer] in its own Write call (or [DatagramWriter.WriteDatagram]
+// if implemented) so that packet boundaries are preserved, e.g. a UDP to UDP relay will send the same packet sizes.
+// Returns once no datagram arrives within [UDPTimeoutReader.ReadDeadline].
+func (r UDPTimeoutReader) WriteTo(w io.Writer) (n int64, err error) {
+	for {
+		d, err := r.ReadDatagram()
+		if err != nil {
+			if err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+
+		written, err := WriteDatagram(w, d)
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package socket
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+)
+
+// NewUnixSocketReader listens on the provided unix stream socket path, accepting and reading from all
+// connecting clients the same as [NewTCPSocketReader].
+// Any stale socket file left at the path is removed first, and the socket file is removed when the reader is closed.
+func NewUnixSocketReader(path string, opts Options) (io.ReadCloser, error) {
+	err := removeStaleSocket(path)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+
+	err = applyPermissions(path, opts)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &TCPTimeoutReader{Listener: listener, Limits: opts.ClientLimits, ReadDeadline: opts.ReadDeadline}, nil
+}
+
+// NewUnixgramSocketReader listens on the provided unix datagram socket path.
+// Any stale socket file left at the path is removed first, and the socket file is removed when the reader is closed.
+func NewUnixgramSocketReader(path string, opts Options) (io.ReadCloser, error) {
+	err := removeStaleSocket(path)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+
+	reader := UnixgramTimeoutReader{Conn: conn, Path: path, ReadDeadline: opts.ReadDeadline}
+	err = applyPermissions(path, opts)
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+	return reader, nil
+}
+
+// NewUnixSocketWriter connects to the unix stream socket listening at the provided path.
+func NewUnixSocketWriter(path string) (io.WriteCloser, error) {
+	return net.DialUnix("unix", nil, &net.UnixAddr{Name: path, Net: "unix"})
+}
+
+// NewUnixgramSocketWriter sends datagrams to the unix datagram socket listening at the provided path.
+func NewUnixgramSocketWriter(path string) (io.WriteCloser, error) {
+	return net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+}
+
+// Remove a socket file left behind at the provided path (e.g. by a process that did not exit cleanly).
+// An error is returned if the path exists and is not a socket, so regular files are never removed.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("path [%s] already exists and is not a socket", path)
+	}
+	return os.Remove(path)
+}
+
+func applyPermissions(path string, opts Options) error {
+	if opts.Permissions == 0 {
+		return nil
+	}
+	return os.Chmod(path, opts.Permissions)
+}
@@ -0,0 +1,118 @@
+package socket
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure that data from multiple unix stream clients is read and that the socket file is removed on close.
+func TestUnixReadAndWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flow.sock")
+	reader, err := CreateSocketReader("unix", path, 0)
+	require.NoError(t, err)
+
+	content := "TestUnixReadAndWrite"
+	writer1, err := CreateSocketWriter("unix", path, 0)
+	require.NoError(t, err)
+	defer writer1.Close()
+	writer2, err := CreateSocketWriter("unix", path, 0)
+	require.NoError(t, err)
+	defer writer2.Close()
+
+	for _, w := range []io.Writer{writer1, writer2} {
+		n, err := w.Write([]byte(content))
+		require.NoError(t, err)
+		require.Equal(t, len(content), n)
+	}
+
+	for range 2 {
+		b := make([]byte, len(content))
+		n, err := reader.Read(b)
+		require.NoError(t, err)
+		require.Equal(t, len(content), n)
+		require.Equal(t, content, string(b))
+	}
+	require.Equal(t, 2, reader.(*TCPTimeoutReader).connectionCount())
+
+	b := make([]byte, len(content))
+	n, err := reader.Read(b)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 0, n)
+
+	require.NoError(t, reader.Close())
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+}
+
+// Ensure that each unix datagram is written as its own message and that the socket file is removed on close.
+func TestUnixgramWriteTo_PreservesDatagramBoundaries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flow.sock")
+	reader, err := CreateSocketReader("unixgram", path, 0)
+	require.NoError(t, err)
+
+	writer, err := CreateSocketWriter("unixgram", path, 0)
+	require.NoError(t, err)
+	defer writer.Close()
+
+	first := "TestUnixgramWriteTo"
+	second := "_PreservesDatagramBoundaries"
+	_, err = writer.Write([]byte(first))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte(second))
+	require.NoError(t, err)
+
+	w := &recordingWriter{}
+	n, err := io.Copy(w, reader)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(first)+len(second)), n)
+	require.Equal(t, [][]byte{[]byte(first), []byte(second)}, w.writes)
+
+	require.NoError(t, reader.Close())
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+}
+
+// Ensure a socket file left behind by another listener is replaced.
+func TestUnixReader_RemovesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flow.sock")
+	stale, err := NewUnixgramSocketReader(path, Options{})
+	require.NoError(t, err)
+	// Close only the connection so the socket file is left behind
+	require.NoError(t, stale.(UnixgramTimeoutReader).Conn.Close())
+
+	reader, err := NewUnixSocketReader(path, Options{})
+	require.NoError(t, err)
+	defer reader.Close()
+}
+
+// Ensure that a regular file at the socket path is never removed.
+func TestUnixReader_PathIsNotSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flow.sock")
+	require.NoError(t, os.WriteFile(path, []byte("TestUnixReader_PathIsNotSocket"), 0666))
+
+	_, err := NewUnixSocketReader(path, Options{})
+	require.Error(t, err)
+	_, err = NewUnixgramSocketReader(path, Options{})
+	require.Error(t, err)
+
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+}
+
+// Ensure the configured permissions are applied to the socket file.
+func TestUnixReader_Permissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flow.sock")
+	reader, err := NewUnixSocketReader(path, Options{Permissions: 0600})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, fs.ModeSocket, info.Mode().Type())
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
@@ -0,0 +1,61 @@
+package socket
+
+import (
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// A reader for a listening unix datagram socket. Similarly to [UDPTimeoutReader] each datagram is
+// forwarded as a single whole message by [UnixgramTimeoutReader.WriteTo].
+type UnixgramTimeoutReader struct {
+	Conn *net.UnixConn
+	Path string
+	// The deadline of each read, [SocketReadDeadline] is used when 0
+	ReadDeadline time.Duration
+}
+
+// Close the connection and remove its socket file.
+func (r UnixgramTimeoutReader) Close() error {
+	err := r.Conn.Close()
+	if e := os.Remove(r.Path); e != nil && !os.IsNotExist(e) && err == nil {
+		err = e
+	}
+	return err
+}
+
+// Wraps the read with a deadline to timeout the Read attempt if there is no incoming data.
+// Timeout used is [UnixgramTimeoutReader.ReadDeadline].
+func (r UnixgramTimeoutReader) Read(b []byte) (n int, err error) {
+	r.Conn.SetReadDeadline(time.Now().Add(readDeadline(r.ReadDeadline)))
+	n, err = r.Conn.Read(b)
+	if err != nil {
+		if e, ok := err.(net.Error); ok && e.Timeout() {
+			// Return EOF here so the call from io.Copy doesn't permanently loop
+			return n, io.EOF
+		}
+	}
+	return
+}
+
+// [io.WriterTo], writes each received datagram in its own Write call so that message boundaries are preserved.
+// Returns once no datagram arrives within [UnixgramTimeoutReader.ReadDeadline].
+func (r UnixgramTimeoutReader) WriteTo(w io.Writer) (n int64, err error) {
+	b := make([]byte, MaxDatagramSize)
+	for {
+		read, err := r.Read(b)
+		if err != nil {
+			if err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+
+		written, err := w.Write(b[:read])
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+	}
+}
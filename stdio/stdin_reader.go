@@ -1,10 +1,40 @@
 package stdio
 
 import (
+	"errors"
 	"io"
 	"os"
 )
 
+// Reads from stdin and records once the end of stdin has been reached, see [StdInReader.Ended].
+type StdInReader struct {
+	file *os.File
+	eof  bool
+}
+
 func CreateStdInReader() (io.ReadCloser, error) {
-	return os.Stdin, nil
+	return &StdInReader{file: os.Stdin}, nil
+}
+
+// [io.Reader]
+func (r *StdInReader) Read(b []byte) (int, error) {
+	n, err := r.file.Read(b)
+	if err == io.EOF {
+		r.eof = true
+	}
+	return n, err
+}
+
+// Returns true once stdin has been closed, e.g. the process piping into it has exited.
+func (r *StdInReader) Ended() bool {
+	return r.eof
+}
+
+// [io.Closer], stdin already being closed (e.g. by an earlier flow in the same process) is not an error.
+func (r *StdInReader) Close() error {
+	err := r.file.Close()
+	if errors.Is(err, os.ErrClosed) {
+		return nil
+	}
+	return err
 }
@@ -27,6 +27,7 @@ func TestCreateStdInReader_WithNameLine(t *testing.T) {
 		bytes, err := io.ReadAll(reader)
 		require.NoError(t, err)
 		require.Equal(t, expected, string(bytes))
+		require.True(t, reader.(*StdInReader).Ended())
 	})
 }
 
@@ -42,3 +43,16 @@ func TestCreateStdInReader_WithoutNewLine(t *testing.T) {
 		require.Equal(t, expected, string(bytes))
 	})
 }
+
+// Ensure that closing stdin again, e.g. by a later flow in the same process, is not an error.
+func TestStdInReader_CloseTwice(t *testing.T) {
+	testutil.WithBytesInStdIn(t, []byte("TestStdInReader_CloseTwice"), func() {
+		reader, err := CreateStdInReader()
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+
+		reader, err = CreateStdInReader()
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+	})
+}
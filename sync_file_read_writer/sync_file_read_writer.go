@@ -10,6 +10,8 @@ import (
 type SyncFileReadWriter struct {
 	file  *os.File
 	mutex sync.Mutex
+	// Whether the last read reached the end of the file
+	eof bool
 }
 
 // NewSynchronisedFileReadWriter create a new SyncFileReadWriter.
@@ -36,7 +38,18 @@ func (rw *SyncFileReadWriter) Read(b []byte) (int, error) {
 	rw.mutex.Lock()
 	defer rw.mutex.Unlock()
 
-	return rw.file.Read(b)
+	n, err := rw.file.Read(b)
+	rw.eof = n == 0 && err == io.EOF
+	return n, err
+}
+
+// Returns true if the last read reached the end of the file. This is reset once more data is written to the file and
+// read.
+func (rw *SyncFileReadWriter) Ended() bool {
+	rw.mutex.Lock()
+	defer rw.mutex.Unlock()
+
+	return rw.eof
 }
 
 // [io.Writer]
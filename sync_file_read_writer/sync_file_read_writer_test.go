@@ -47,10 +47,18 @@ func TestSyncFileReadWriter(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, 3, len(all))
 		require.Equal(t, string(content[1])+content, string(all))
+		require.True(t, rw.Ended())
 
 		// Check position is at position 4
 		pos, err = rw.file.Seek(0, io.SeekCurrent)
 		require.NoError(t, err)
 		require.Equal(t, int64(4), pos)
+
+		// Data written after the end was reached is read, so the end is no longer reached
+		_, err = rw.Write([]byte(content))
+		require.NoError(t, err)
+		_, err = rw.Read(read)
+		require.NoError(t, err)
+		require.False(t, rw.Ended())
 	})
 }